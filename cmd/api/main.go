@@ -1,36 +1,160 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/config"
+	domainAPIKey "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/migrations"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/postgres"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/sqlite"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/sqltx"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	apiKeyUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/apikey"
+	auditUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/audit"
+	privacyUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/privacy"
 	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
 	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// "api migrate" applies pending database migrations and exits
+	if len(os.Args) > 1 {
+		if os.Args[1] != "migrate" {
+			log.Fatalf("Unknown command %q, expected migrate", os.Args[1])
+		}
+		if err := runMigrations(cfg); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	// Initialize JWT service
-	jwtService := jwt.NewService(cfg.JWTSecret, cfg.JWTTokenDuration)
+	signer, err := newSigner(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing key: %v", err)
+	}
+	revocationStore := jwt.NewInMemoryRevocationStore()
+	jwtService := jwt.NewService(signer, cfg.JWTTokenDuration,
+		jwt.WithRevocationStore(revocationStore),
+	)
 
-	// Initialize repository (using in-memory for now)
-	userRepo := user.NewInMemoryRepository()
+	// Rotate the signing key on SIGHUP without restarting
+	go reloadSigningKeyOnSIGHUP(jwtService)
+
+	// Initialize repositories. Users are stored by DATABASE_DRIVER; the
+	// other repositories are in memory for now.
+	userRepo, txManager, err := newUserRepository(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open the user database: %v", err)
+	}
+	refreshTokenRepo := user.NewInMemoryRefreshTokenRepository()
+	passwordResetRepo := user.NewInMemoryPasswordResetTokenRepository()
+	apiKeyRepo := apikey.NewInMemoryRepository()
+	sessionRepo := user.NewInMemorySessionRepository()
+	auditRepo := audit.NewInMemoryRepository()
+	// Magic link login stays off unless MAGIC_LINK_LOGIN is set
+	var magicLinkRepo domainUser.MagicLinkRepository
+	if cfg.MagicLinkLogin {
+		magicLinkRepo = user.NewInMemoryMagicLinkRepository()
+	}
+	// Passkeys stay off unless WEBAUTHN_ENABLED is set
+	var relyingParty *webauthn.RelyingParty
+	if cfg.WebAuthnEnabled {
+		relyingParty, err = newRelyingParty(cfg)
+		if err != nil {
+			log.Fatalf("Invalid WebAuthn configuration: %v", err)
+		}
+	}
+	webAuthnRepo := user.NewInMemoryWebAuthnCredentialRepository()
+	accountThrottle, ipThrottle := newLoginThrottles(cfg)
+	passwordParams, err := newPasswordParams(cfg)
+	if err != nil {
+		log.Fatalf("Invalid password hashing parameters: %v", err)
+	}
+	passwordPolicy, err := newPasswordPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load password breach corpus: %v", err)
+	}
+	emailBlocklist, err := newEmailBlocklist(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load disposable email domains: %v", err)
+	}
 
 	// Initialize use case/service
-	userService := userUseCase.NewService(userRepo, jwtService)
+	auditService := auditUseCase.NewService(auditRepo)
+	apiKeyService := apiKeyUseCase.NewService(apiKeyRepo, apiKeyUseCase.WithOwnerRoles(userRepo))
+	userService := userUseCase.NewService(userRepo, jwtService,
+		userUseCase.WithRefreshTokens(refreshTokenRepo, cfg.RefreshTokenDuration),
+		userUseCase.WithTOTPIssuer(cfg.TOTPIssuer),
+		userUseCase.WithMailer(newMailer(cfg), cfg.AppBaseURL),
+		userUseCase.WithEmailVerification(cfg.EmailVerificationTTL, cfg.EmailVerificationPolicy == config.EmailVerificationLogin),
+		userUseCase.WithPasswordReset(passwordResetRepo, cfg.PasswordResetTTL),
+		userUseCase.WithMagicLinks(magicLinkRepo, cfg.MagicLinkTTL),
+		userUseCase.WithWebAuthn(relyingParty, webAuthnRepo),
+		userUseCase.WithLoginThrottle(accountThrottle, ipThrottle),
+		userUseCase.WithAdminEmails(cfg.AdminEmails),
+		userUseCase.WithImpersonationTTL(cfg.ImpersonationTTL),
+		userUseCase.WithPasswordParams(passwordParams),
+		userUseCase.WithPasswordPolicy(passwordPolicy),
+		userUseCase.WithEmailBlocklist(emailBlocklist),
+		userUseCase.WithSessions(sessionRepo, cfg.SessionIdleTimeout),
+		userUseCase.WithAuditLog(auditService),
+		userUseCase.WithAccountDeletion(cfg.AccountDeletionGracePeriod, apiKeyService),
+		userUseCase.WithTransactions(txManager),
+	)
+	privacyService := privacyUseCase.NewService(userRepo, sessionRepo, apiKeyRepo, auditRepo,
+		privacyUseCase.WithAuditLog(auditService),
+	)
+
+	// Delete stale sessions and anonymize deleted accounts in the background
+	go purgeStaleData(userService, purgeInterval)
 
 	// Initialize handlers
 	userHandler := handler.NewUserHandler(userService)
+	jwksHandler := handler.NewJWKSHandler(jwtService)
+	adminHandler := handler.NewAdminHandler(userService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	privacyHandler := handler.NewPrivacyHandler(privacyService)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuth(jwtService)
+	authMiddleware := middleware.NewAuth(jwtService,
+		middleware.WithAPIKeys(apiKeyService),
+		middleware.WithSessions(userService),
+		middleware.WithAuditLog(auditService),
+		// Wrap payment routes with RequireVerifiedEmail to enforce this policy
+		middleware.WithVerifiedEmailRequired(cfg.EmailVerificationPolicy == config.EmailVerificationPayments),
+	)
+	// Routes declare the permission they need; compose it inside Authenticate
+	requirePermission := authMiddleware.RequirePermission
+	// Sensitive actions are off limits while impersonating a user. Wrap
+	// payout routes with noImpersonation() as well.
+	noImpersonation := authMiddleware.RejectImpersonation
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -38,14 +162,51 @@ func main() {
 	// Public routes
 	mux.HandleFunc("/api/register", userHandler.Register)
 	mux.HandleFunc("/api/login", userHandler.Login)
+	mux.HandleFunc("/api/login/mfa", userHandler.VerifyMFA)
+	mux.HandleFunc("/api/login/magic", userHandler.RequestMagicLink)
+	mux.HandleFunc("/api/login/magic/confirm", userHandler.ConfirmMagicLink)
+	mux.HandleFunc("/api/login/webauthn/begin", userHandler.BeginWebAuthnLogin)
+	mux.HandleFunc("/api/login/webauthn/finish", userHandler.FinishWebAuthnLogin)
+	mux.HandleFunc("/api/verify-email", userHandler.VerifyEmail)
+	mux.HandleFunc("/api/verify-email/resend", userHandler.ResendVerification)
+	mux.HandleFunc("/api/password/forgot", userHandler.ForgotPassword)
+	mux.HandleFunc("/api/password/reset", userHandler.ResetPassword)
+	mux.HandleFunc("/api/token/refresh", userHandler.Refresh)
+	mux.HandleFunc("/.well-known/jwks.json", jwksHandler.JWKS)
+
+	// Authenticated routes
+	mux.HandleFunc("/api/logout", authMiddleware.Authenticate(userHandler.Logout))
+	mux.HandleFunc("/api/me", authMiddleware.Authenticate(noImpersonation(http.MethodPatch, http.MethodDelete)(userHandler.Profile)))
+	mux.HandleFunc("/api/me/password", authMiddleware.Authenticate(noImpersonation()(userHandler.ChangePassword)))
+	mux.HandleFunc("/api/me/export", authMiddleware.Authenticate(privacyHandler.Export))
+	mux.HandleFunc("/api/me/sessions", authMiddleware.Authenticate(userHandler.Sessions))
+	mux.HandleFunc("/api/me/sessions/{id}", authMiddleware.Authenticate(noImpersonation()(userHandler.Session)))
+	mux.HandleFunc("/api/keys", authMiddleware.Authenticate(noImpersonation(http.MethodPost)(requirePermission(domainUser.PermAPIKeysManage)(apiKeyHandler.Keys))))
+	mux.HandleFunc("/api/keys/{id}", authMiddleware.Authenticate(noImpersonation()(requirePermission(domainUser.PermAPIKeysManage)(apiKeyHandler.Key))))
+	mux.HandleFunc("/api/logout-all", authMiddleware.Authenticate(noImpersonation()(userHandler.LogoutAll)))
+	mux.HandleFunc("/api/mfa/totp/enroll", authMiddleware.Authenticate(noImpersonation()(userHandler.EnrollTOTP)))
+	mux.HandleFunc("/api/mfa/totp/confirm", authMiddleware.Authenticate(noImpersonation()(userHandler.ConfirmTOTP)))
+	mux.HandleFunc("/api/mfa/totp/disable", authMiddleware.Authenticate(noImpersonation()(userHandler.DisableTOTP)))
+	mux.HandleFunc("/api/webauthn/register/begin", authMiddleware.Authenticate(noImpersonation()(userHandler.BeginWebAuthnRegistration)))
+	mux.HandleFunc("/api/webauthn/register/finish", authMiddleware.Authenticate(noImpersonation()(userHandler.FinishWebAuthnRegistration)))
+	mux.HandleFunc("/api/webauthn/credentials", authMiddleware.Authenticate(userHandler.WebAuthnCredentials))
+	mux.HandleFunc("/api/webauthn/credentials/{id}", authMiddleware.Authenticate(noImpersonation()(userHandler.WebAuthnCredential)))
+
+	// Admin routes
+	mux.HandleFunc("/api/admin/users/{id}/mfa", authMiddleware.Authenticate(requirePermission(domainUser.PermUsersManage)(adminHandler.SetMFARequirement)))
+	mux.HandleFunc("/api/admin/users/{id}/role", authMiddleware.Authenticate(requirePermission(domainUser.PermUsersManage)(adminHandler.SetRole)))
+	mux.HandleFunc("/api/admin/users/{id}/impersonate", authMiddleware.Authenticate(noImpersonation()(requirePermission(domainUser.PermUsersImpersonate)(adminHandler.Impersonate))))
+	mux.HandleFunc("/api/admin/audit", authMiddleware.Authenticate(requirePermission(domainUser.PermAuditRead)(auditHandler.Events)))
+	mux.HandleFunc("/api/admin/audit/export", authMiddleware.Authenticate(requirePermission(domainUser.PermAuditRead)(auditHandler.Export)))
+	mux.HandleFunc("/api/admin/audit/verify", authMiddleware.Authenticate(requirePermission(domainUser.PermAuditRead)(auditHandler.Verify)))
 
 	// Protected route example
-	mux.HandleFunc("/api/protected", authMiddleware.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/protected", authMiddleware.AuthenticateWithScope(domainAPIKey.ScopeProfileRead, requirePermission(domainUser.PermProfileRead)(func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Context().Value(middleware.UserIDKey)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintf(w, `{"message": "You are authenticated", "user_id": "%v"}`, userID)
-	}))
+	})))
 
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -59,11 +220,291 @@ func main() {
 	log.Printf("Server starting on port %s", cfg.ServerPort)
 	log.Printf("Available endpoints:")
 	log.Printf("  POST /api/register - Register a new user")
-	log.Printf("  POST /api/login - Login and get JWT token")
-	log.Printf("  GET  /api/protected - Protected endpoint (requires JWT)")
+	log.Printf("  POST /api/login - Login and get JWT and refresh tokens")
+	log.Printf("  POST /api/login/mfa - Complete a two-factor login")
+	log.Printf("  POST /api/login/magic - Email a passwordless login link (MAGIC_LINK_LOGIN)")
+	log.Printf("  GET  /api/login/magic/confirm?token= - Log in with a magic link")
+	log.Printf("  POST /api/login/webauthn/begin - Start a passkey login (WEBAUTHN_ENABLED)")
+	log.Printf("  POST /api/login/webauthn/finish - Complete a passkey login")
+	log.Printf("  GET  /api/verify-email?token= - Verify an email address")
+	log.Printf("  POST /api/verify-email/resend - Resend the verification email")
+	log.Printf("  POST /api/password/forgot - Request a password reset email")
+	log.Printf("  POST /api/password/reset - Set a new password with a reset token")
+	log.Printf("  POST /api/token/refresh - Rotate refresh token and get a new JWT")
+	log.Printf("  POST /api/logout - Revoke the current token (requires JWT)")
+	log.Printf("  POST /api/logout-all - Revoke every token of the user (requires JWT)")
+	log.Printf("  POST /api/mfa/totp/enroll - Start TOTP enrollment (requires JWT)")
+	log.Printf("  POST /api/mfa/totp/confirm - Confirm TOTP and get recovery codes (requires JWT)")
+	log.Printf("  POST /api/mfa/totp/disable - Disable TOTP (requires JWT)")
+	log.Printf("  POST /api/webauthn/register/begin - Start registering a passkey (requires JWT)")
+	log.Printf("  POST /api/webauthn/register/finish - Store a new passkey (requires JWT)")
+	log.Printf("  GET  /api/webauthn/credentials - List passkeys (requires JWT)")
+	log.Printf("  DELETE /api/webauthn/credentials/{id} - Remove a passkey (requires JWT)")
+	log.Printf("  PUT  /api/admin/users/{id}/mfa - Require MFA for a user (users:manage)")
+	log.Printf("  PUT  /api/admin/users/{id}/role - Change the role of a user (users:manage)")
+	log.Printf("  POST /api/admin/users/{id}/impersonate - Get a short-lived token to act as a user (users:impersonate)")
+	log.Printf("  GET  /api/admin/audit - Query the security audit log (audit:read)")
+	log.Printf("  GET  /api/admin/audit/export - Export the audit log as JSON Lines (audit:read)")
+	log.Printf("  GET  /api/admin/audit/verify - Check the audit log hash chain (audit:read)")
+	log.Printf("  GET  /api/me - Current user profile (requires JWT)")
+	log.Printf("  PATCH /api/me - Update username or email (requires JWT)")
+	log.Printf("  DELETE /api/me - Delete the account after a grace period (requires JWT)")
+	log.Printf("  POST /api/me/password - Change password (requires JWT)")
+	log.Printf("  GET  /api/me/export - Download all data held about the user (requires JWT)")
+	log.Printf("  GET  /api/me/sessions - List signed-in sessions (requires JWT)")
+	log.Printf("  DELETE /api/me/sessions/{id} - Sign out a session (requires JWT)")
+	log.Printf("  GET  /api/keys - List API keys (requires JWT, api_keys:manage)")
+	log.Printf("  POST /api/keys - Create an API key (requires JWT, api_keys:manage)")
+	log.Printf("  PATCH /api/keys/{id} - Rename an API key (requires JWT, api_keys:manage)")
+	log.Printf("  DELETE /api/keys/{id} - Revoke an API key (requires JWT, api_keys:manage)")
+	log.Printf("  GET  /api/protected - Protected endpoint (requires JWT or an API key, profile:read)")
+	log.Printf("  GET  /.well-known/jwks.json - Public keys for verifying JWTs")
 	log.Printf("  GET  /health - Health check")
 
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	if err := http.ListenAndServe(addr, middleware.RequestMeta(cfg.TrustProxyHeaders)(mux)); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// newUserRepository opens the user store selected by DATABASE_DRIVER,
+// applying pending migrations first unless DATABASE_AUTO_MIGRATE is off. It
+// also returns the transaction manager for the store, which the in-memory
+// repositories join.
+func newUserRepository(cfg *config.Config) (domainUser.Repository, transaction.Manager, error) {
+	if cfg.DatabaseDriver == config.DatabaseMemory {
+		return user.NewInMemoryRepository(), memtx.NewManager(), nil
+	}
+
+	db, dialect, err := openDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.DatabaseAutoMigrate {
+		if err := migrate(db, dialect); err != nil {
+			return nil, nil, err
+		}
+	}
+	txManager := memtx.NewManager(memtx.Within(sqltx.NewManager(db)))
+	if dialect == migrations.SQLite {
+		return sqlite.NewUserRepository(db), txManager, nil
+	}
+	return postgres.NewUserRepository(db), txManager, nil
+}
+
+// openDatabase connects to DATABASE_URL, or opens the SQLite file at
+// DATABASE_PATH
+func openDatabase(cfg *config.Config) (*sql.DB, migrations.Dialect, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch cfg.DatabaseDriver {
+	case config.DatabasePostgres:
+		if cfg.DatabaseURL == "" {
+			return nil, "", fmt.Errorf("DATABASE_URL is required for DATABASE_DRIVER=%s", cfg.DatabaseDriver)
+		}
+		db, err := postgres.Open(ctx, cfg.DatabaseURL)
+		return db, migrations.Postgres, err
+	case config.DatabaseSQLite:
+		db, err := sqlite.Open(ctx, cfg.DatabasePath, cfg.DatabaseBusyTimeout)
+		return db, migrations.SQLite, err
+	case config.DatabaseMemory:
+		return nil, "", fmt.Errorf("DATABASE_DRIVER=%s has no migrations", cfg.DatabaseDriver)
+	default:
+		return nil, "", fmt.Errorf("unknown DATABASE_DRIVER %q", cfg.DatabaseDriver)
+	}
+}
+
+// runMigrations implements the migrate command
+func runMigrations(cfg *config.Config) error {
+	db, dialect, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return migrate(db, dialect)
+}
+
+func migrate(db *sql.DB, dialect migrations.Dialect) error {
+	applied, err := migrations.Run(context.Background(), db, dialect)
+	if err != nil {
+		return err
+	}
+	log.Printf("Database schema up to date, applied %d migrations", applied)
+	return nil
+}
+
+// newSigner builds the JWT signer selected by the configuration. HS256 uses
+// the shared secret, read from JWT_SECRET_FILE when set so it can be rotated
+// at runtime; asymmetric algorithms load a PEM private key.
+func newSigner(cfg *config.Config) (jwt.Signer, error) {
+	if cfg.JWTAlgorithm != jwt.AlgHS256 {
+		return jwt.LoadSignerFromPEM(cfg.JWTAlgorithm, cfg.JWTPrivateKeyFile)
+	}
+
+	if cfg.JWTSecretFile == "" {
+		return jwt.NewHMACSigner(cfg.JWTSecret), nil
+	}
+	secret, err := os.ReadFile(cfg.JWTSecretFile)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.NewHMACSigner(strings.TrimSpace(string(secret))), nil
+}
+
+// reloadSigningKeyOnSIGHUP re-reads the key configuration whenever the process
+// receives SIGHUP and makes the loaded key the active one. Tokens signed with
+// the previous key keep validating for JWT_KEY_GRACE_PERIOD.
+func reloadSigningKeyOnSIGHUP(jwtService *jwt.Service) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		cfg := config.Load()
+		signer, err := newSigner(cfg)
+		if err != nil {
+			log.Printf("Signing key reload failed, keeping current key: %v", err)
+			continue
+		}
+		jwtService.RotateKey(signer, cfg.JWTKeyGracePeriod)
+		log.Printf("Signing key reloaded, active kid %s", signer.KeyID())
+	}
+}
+
+// purgeInterval is how often stale sessions are deleted and deleted
+// accounts past their grace period are anonymized
+const purgeInterval = time.Hour
+
+// purgeStaleData periodically deletes sessions that are no longer usable
+// and anonymizes accounts whose deletion became final
+func purgeStaleData(service *userUseCase.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := service.PurgeSessions(context.Background())
+		if err != nil {
+			log.Printf("Failed to purge sessions: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d stale sessions", purged)
+		}
+
+		anonymized, err := service.PurgeDeletedAccounts(context.Background())
+		if err != nil {
+			log.Printf("Failed to anonymize deleted accounts: %v", err)
+		} else if anonymized > 0 {
+			log.Printf("Anonymized %d deleted accounts", anonymized)
+		}
+	}
+}
+
+// newLoginThrottles builds the failed login trackers. Accounts back off
+// exponentially before being locked; client IPs, which may be shared behind
+// NAT, get a higher limit without backoff.
+func newLoginThrottles(cfg *config.Config) (*lockout.Tracker, *lockout.Tracker) {
+	store := lockout.NewMemoryStore()
+	accounts := lockout.NewTracker(store, lockout.Policy{
+		MaxAttempts:     cfg.LoginMaxAttempts,
+		BaseDelay:       cfg.LoginBackoffBase,
+		MaxDelay:        cfg.LoginBackoffMax,
+		LockoutDuration: cfg.LoginLockout,
+		Window:          cfg.LoginLockout,
+	})
+	ips := lockout.NewTracker(store, lockout.Policy{
+		MaxAttempts:     cfg.LoginIPMaxAttempts,
+		LockoutDuration: cfg.LoginLockout,
+		Window:          cfg.LoginLockout,
+	})
+	return accounts, ips
+}
+
+// newPasswordParams builds the Argon2id parameters for new password hashes.
+// Raising them upgrades existing hashes as users log in.
+func newPasswordParams(cfg *config.Config) (password.Params, error) {
+	params := password.DefaultParams()
+	params.Memory = uint32(cfg.PasswordArgon2Memory)
+	params.Iterations = uint32(cfg.PasswordArgon2Iterations)
+	params.Parallelism = uint8(cfg.PasswordArgon2Parallelism)
+	if cfg.PasswordArgon2Memory <= 0 || cfg.PasswordArgon2Iterations <= 0 ||
+		cfg.PasswordArgon2Parallelism <= 0 || cfg.PasswordArgon2Parallelism > 255 {
+		return params, password.ErrInvalidParams
+	}
+	return params, params.Validate()
+}
+
+// newPasswordPolicy builds the policy for new passwords, loading the breach
+// corpus from PASSWORD_BREACH_FILE when set
+func newPasswordPolicy(cfg *config.Config) (password.Policy, error) {
+	policy := password.DefaultPolicy()
+	policy.MinLength = cfg.PasswordMinLength
+	policy.MaxLength = cfg.PasswordMaxLength
+	policy.RequireUpper = cfg.PasswordRequireUpper
+	policy.RequireLower = cfg.PasswordRequireLower
+	policy.RequireDigit = cfg.PasswordRequireDigit
+	policy.RequireSymbol = cfg.PasswordRequireSymbol
+
+	if cfg.PasswordBreachFile != "" {
+		corpus, err := password.LoadBreachCorpus(cfg.PasswordBreachFile)
+		if err != nil {
+			return policy, err
+		}
+		log.Printf("Loaded %d breached password hashes", corpus.Len())
+		policy.Breaches = corpus
+	}
+	return policy, nil
+}
+
+// newEmailBlocklist builds the disposable email blocklist from
+// DISPOSABLE_EMAIL_DOMAINS and DISPOSABLE_EMAIL_DOMAIN_FILE, which lists one
+// domain per line
+func newEmailBlocklist(cfg *config.Config) (*domainUser.DomainBlocklist, error) {
+	domains := cfg.DisposableEmailDomains
+	if cfg.DisposableEmailDomainFile != "" {
+		data, err := os.ReadFile(cfg.DisposableEmailDomainFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				domains = append(domains, line)
+			}
+		}
+	}
+	if len(domains) == 0 {
+		return nil, nil
+	}
+
+	blocklist := domainUser.NewDomainBlocklist(domains)
+	log.Printf("Blocking %d disposable email domains", blocklist.Len())
+	return blocklist, nil
+}
+
+// newRelyingParty builds the WebAuthn relying party. The RP ID defaults to
+// the host of APP_BASE_URL and the allowed origins to APP_BASE_URL itself.
+func newRelyingParty(cfg *config.Config) (*webauthn.RelyingParty, error) {
+	base, err := url.Parse(cfg.AppBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	rpID := cfg.WebAuthnRPID
+	if rpID == "" {
+		rpID = base.Hostname()
+	}
+	if rpID == "" {
+		return nil, fmt.Errorf("WEBAUTHN_RP_ID is required when APP_BASE_URL has no host")
+	}
+	origins := cfg.WebAuthnOrigins
+	if len(origins) == 0 {
+		origins = []string{base.Scheme + "://" + base.Host}
+	}
+
+	return webauthn.NewRelyingParty(rpID, cfg.WebAuthnRPName, origins, 5*time.Minute), nil
+}
+
+// newMailer builds the mailer selected by MAIL_DRIVER
+func newMailer(cfg *config.Config) mail.Mailer {
+	if cfg.MailDriver == "file" {
+		return mail.NewFileMailer(cfg.MailFile, cfg.MailFrom)
+	}
+	return mail.NewLogMailer(log.Default(), cfg.MailFrom)
+}
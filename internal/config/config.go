@@ -3,26 +3,191 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds application configuration
 type Config struct {
-	ServerPort       string
-	JWTSecret        string
-	JWTTokenDuration time.Duration
+	ServerPort           string
+	DatabaseDriver       string
+	DatabaseURL          string
+	DatabasePath         string
+	DatabaseBusyTimeout  time.Duration
+	DatabaseAutoMigrate  bool
+	JWTSecret            string
+	JWTSecretFile        string
+	JWTAlgorithm         string
+	JWTPrivateKeyFile    string
+	JWTTokenDuration     time.Duration
+	JWTKeyGracePeriod    time.Duration
+	RefreshTokenDuration time.Duration
+	SessionIdleTimeout   time.Duration
+	TOTPIssuer           string
+	AdminEmails          []string
+	ImpersonationTTL     time.Duration
+
+	AppBaseURL              string
+	MailDriver              string
+	MailFile                string
+	MailFrom                string
+	EmailVerificationTTL    time.Duration
+	EmailVerificationPolicy string
+	PasswordResetTTL        time.Duration
+	MagicLinkLogin          bool
+	MagicLinkTTL            time.Duration
+
+	WebAuthnEnabled bool
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+
+	LoginMaxAttempts   int
+	LoginIPMaxAttempts int
+	LoginBackoffBase   time.Duration
+	LoginBackoffMax    time.Duration
+	LoginLockout       time.Duration
+	TrustProxyHeaders  bool
+
+	PasswordArgon2Memory      int
+	PasswordArgon2Iterations  int
+	PasswordArgon2Parallelism int
+	PasswordMinLength         int
+	PasswordMaxLength         int
+	PasswordRequireUpper      bool
+	PasswordRequireLower      bool
+	PasswordRequireDigit      bool
+	PasswordRequireSymbol     bool
+	PasswordBreachFile        string
+
+	DisposableEmailDomains    []string
+	DisposableEmailDomainFile string
+
+	AccountDeletionGracePeriod time.Duration
 }
 
+// Storage backends for DATABASE_DRIVER
+const (
+	DatabaseMemory   = "memory"
+	DatabasePostgres = "postgres"
+	DatabaseSQLite   = "sqlite"
+)
+
+// Email verification policies
+const (
+	EmailVerificationOff      = "off"
+	EmailVerificationLogin    = "login"
+	EmailVerificationPayments = "payments"
+)
+
 // Load loads configuration from environment variables with defaults
 func Load() *Config {
 	port := getEnv("PORT", "8080")
+	databaseDriver := getEnv("DATABASE_DRIVER", DatabaseMemory)
+	databaseURL := getEnv("DATABASE_URL", "")
+	databasePath := getEnv("DATABASE_PATH", "gateway.db")
+	databaseBusyTimeout := getEnvAsDuration("DATABASE_BUSY_TIMEOUT", 5*time.Second)
+	databaseAutoMigrate := getEnvAsBool("DATABASE_AUTO_MIGRATE", true)
 	jwtSecret := getEnv("JWT_SECRET", "your-secret-key-change-in-production")
-	jwtDuration := getEnvAsDuration("JWT_DURATION", 24*time.Hour)
+	jwtSecretFile := getEnv("JWT_SECRET_FILE", "")
+	jwtAlgorithm := getEnv("JWT_ALGORITHM", "HS256")
+	jwtPrivateKeyFile := getEnv("JWT_PRIVATE_KEY_FILE", "")
+	jwtDuration := getEnvAsDuration("JWT_DURATION", 15*time.Minute)
+	jwtKeyGracePeriod := getEnvAsDuration("JWT_KEY_GRACE_PERIOD", jwtDuration)
+	refreshDuration := getEnvAsDuration("REFRESH_TOKEN_DURATION", 30*24*time.Hour)
+	sessionIdleTimeout := getEnvAsDuration("SESSION_IDLE_TIMEOUT", 7*24*time.Hour)
+	totpIssuer := getEnv("TOTP_ISSUER", "Crypto Payment Gateway")
+	adminEmails := getEnvAsList("ADMIN_EMAILS")
+	impersonationTTL := getEnvAsDuration("IMPERSONATION_TTL", 15*time.Minute)
+	appBaseURL := getEnv("APP_BASE_URL", "http://localhost:"+port)
+	mailDriver := getEnv("MAIL_DRIVER", "log")
+	mailFile := getEnv("MAIL_FILE", "mail.log")
+	mailFrom := getEnv("MAIL_FROM", "no-reply@localhost")
+	verificationTTL := getEnvAsDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+	verificationPolicy := getEnv("EMAIL_VERIFICATION_POLICY", EmailVerificationOff)
+	passwordResetTTL := getEnvAsDuration("PASSWORD_RESET_TTL", 30*time.Minute)
+	magicLinkLogin := getEnvAsBool("MAGIC_LINK_LOGIN", false)
+	magicLinkTTL := getEnvAsDuration("MAGIC_LINK_TTL", 15*time.Minute)
+	webAuthnEnabled := getEnvAsBool("WEBAUTHN_ENABLED", false)
+	webAuthnRPID := getEnv("WEBAUTHN_RP_ID", "")
+	webAuthnRPName := getEnv("WEBAUTHN_RP_NAME", "Crypto Payment Gateway")
+	webAuthnOrigins := getEnvAsList("WEBAUTHN_ORIGINS")
+	loginMaxAttempts := getEnvAsInt("LOGIN_MAX_ATTEMPTS", 5)
+	loginIPMaxAttempts := getEnvAsInt("LOGIN_IP_MAX_ATTEMPTS", 20)
+	loginBackoffBase := getEnvAsDuration("LOGIN_BACKOFF_BASE", time.Second)
+	loginBackoffMax := getEnvAsDuration("LOGIN_BACKOFF_MAX", 30*time.Second)
+	loginLockout := getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	trustProxyHeaders := getEnvAsBool("TRUST_PROXY_HEADERS", false)
+	argon2Memory := getEnvAsInt("PASSWORD_ARGON2_MEMORY", 19*1024)
+	argon2Iterations := getEnvAsInt("PASSWORD_ARGON2_ITERATIONS", 2)
+	argon2Parallelism := getEnvAsInt("PASSWORD_ARGON2_PARALLELISM", 1)
+	passwordMinLength := getEnvAsInt("PASSWORD_MIN_LENGTH", 8)
+	passwordMaxLength := getEnvAsInt("PASSWORD_MAX_LENGTH", 128)
+	passwordRequireUpper := getEnvAsBool("PASSWORD_REQUIRE_UPPER", false)
+	passwordRequireLower := getEnvAsBool("PASSWORD_REQUIRE_LOWER", false)
+	passwordRequireDigit := getEnvAsBool("PASSWORD_REQUIRE_DIGIT", false)
+	passwordRequireSymbol := getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false)
+	passwordBreachFile := getEnv("PASSWORD_BREACH_FILE", "")
+	disposableEmailDomains := getEnvAsList("DISPOSABLE_EMAIL_DOMAINS")
+	disposableEmailDomainFile := getEnv("DISPOSABLE_EMAIL_DOMAIN_FILE", "")
+	accountDeletionGracePeriod := getEnvAsDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
 
 	return &Config{
-		ServerPort:       port,
-		JWTSecret:        jwtSecret,
-		JWTTokenDuration: jwtDuration,
+		ServerPort:           port,
+		DatabaseDriver:       databaseDriver,
+		DatabaseURL:          databaseURL,
+		DatabasePath:         databasePath,
+		DatabaseBusyTimeout:  databaseBusyTimeout,
+		DatabaseAutoMigrate:  databaseAutoMigrate,
+		JWTSecret:            jwtSecret,
+		JWTSecretFile:        jwtSecretFile,
+		JWTAlgorithm:         jwtAlgorithm,
+		JWTPrivateKeyFile:    jwtPrivateKeyFile,
+		JWTTokenDuration:     jwtDuration,
+		JWTKeyGracePeriod:    jwtKeyGracePeriod,
+		RefreshTokenDuration: refreshDuration,
+		SessionIdleTimeout:   sessionIdleTimeout,
+		TOTPIssuer:           totpIssuer,
+		AdminEmails:          adminEmails,
+		ImpersonationTTL:     impersonationTTL,
+
+		AppBaseURL:              appBaseURL,
+		MailDriver:              mailDriver,
+		MailFile:                mailFile,
+		MailFrom:                mailFrom,
+		EmailVerificationTTL:    verificationTTL,
+		EmailVerificationPolicy: verificationPolicy,
+		PasswordResetTTL:        passwordResetTTL,
+		MagicLinkLogin:          magicLinkLogin,
+		MagicLinkTTL:            magicLinkTTL,
+
+		WebAuthnEnabled: webAuthnEnabled,
+		WebAuthnRPID:    webAuthnRPID,
+		WebAuthnRPName:  webAuthnRPName,
+		WebAuthnOrigins: webAuthnOrigins,
+
+		LoginMaxAttempts:   loginMaxAttempts,
+		LoginIPMaxAttempts: loginIPMaxAttempts,
+		LoginBackoffBase:   loginBackoffBase,
+		LoginBackoffMax:    loginBackoffMax,
+		LoginLockout:       loginLockout,
+		TrustProxyHeaders:  trustProxyHeaders,
+
+		PasswordArgon2Memory:      argon2Memory,
+		PasswordArgon2Iterations:  argon2Iterations,
+		PasswordArgon2Parallelism: argon2Parallelism,
+		PasswordMinLength:         passwordMinLength,
+		PasswordMaxLength:         passwordMaxLength,
+		PasswordRequireUpper:      passwordRequireUpper,
+		PasswordRequireLower:      passwordRequireLower,
+		PasswordRequireDigit:      passwordRequireDigit,
+		PasswordRequireSymbol:     passwordRequireSymbol,
+		PasswordBreachFile:        passwordBreachFile,
+
+		DisposableEmailDomains:    disposableEmailDomains,
+		DisposableEmailDomainFile: disposableEmailDomainFile,
+
+		AccountDeletionGracePeriod: accountDeletionGracePeriod,
 	}
 }
 
@@ -34,14 +199,48 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvAsDuration accepts Go duration strings ("15m") as well as a bare
+// number, which is interpreted as hours for backwards compatibility
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	value := os.Getenv(key)
 	if value == "" {
 		return defaultValue
 	}
-	hours, err := strconv.Atoi(value)
+	if hours, err := strconv.Atoi(value); err == nil {
+		return time.Duration(hours) * time.Hour
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvAsInt reads an integer variable, falling back on a missing or invalid value
+func getEnvAsInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsBool reads a boolean variable, falling back on a missing or invalid value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
 	if err != nil {
 		return defaultValue
 	}
-	return time.Duration(hours) * time.Hour
+	return value
+}
+
+// getEnvAsList splits a comma separated variable, dropping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
 }
@@ -0,0 +1,111 @@
+package apikey
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+var (
+	ErrEmptyUserID     = errors.New("user id cannot be empty")
+	ErrEmptyPublicKey  = errors.New("public key cannot be empty")
+	ErrEmptySecretHash = errors.New("secret hash cannot be empty")
+	ErrNoScopes        = errors.New("at least one scope is required")
+	ErrInvalidScope    = errors.New("invalid scope")
+	ErrExpiryInPast    = errors.New("expiry must be in the future")
+)
+
+// Scopes an API key can be granted. They are the user permissions that
+// make sense for server-to-server calls.
+const (
+	ScopeProfileRead    = string(user.PermProfileRead)
+	ScopePaymentsRead   = string(user.PermPaymentsRead)
+	ScopePaymentsWrite  = string(user.PermPaymentsWrite)
+	ScopePaymentsRefund = string(user.PermPaymentsRefund)
+)
+
+// Scopes lists every valid scope
+var Scopes = []string{ScopeProfileRead, ScopePaymentsRead, ScopePaymentsWrite, ScopePaymentsRefund}
+
+// APIKey represents a merchant credential for server-to-server calls. The
+// public key identifies it in listings; only a hash of the secret is kept.
+type APIKey struct {
+	ID         string
+	UserID     string
+	Label      string
+	PublicKey  string
+	SecretHash string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// NewAPIKey creates a new API key entity with validation. A nil expiresAt
+// creates a key that does not expire.
+func NewAPIKey(userID, label, publicKey, secretHash string, scopes []string, expiresAt *time.Time) (*APIKey, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	if publicKey == "" {
+		return nil, ErrEmptyPublicKey
+	}
+	if secretHash == "" {
+		return nil, ErrEmptySecretHash
+	}
+	if len(scopes) == 0 {
+		return nil, ErrNoScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, ErrExpiryInPast
+	}
+
+	return &APIKey{
+		UserID:     userID,
+		Label:      label,
+		PublicKey:  publicKey,
+		SecretHash: secretHash,
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(scopes))),
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}, nil
+}
+
+// IsExpired reports whether the key is past its expiry at the given time
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// IsActive reports whether the key can be used at the given time
+func (k *APIKey) IsActive(now time.Time) bool {
+	return !k.IsRevoked() && !k.IsExpired(now)
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Revoke disables the key permanently
+func (k *APIKey) Revoke() {
+	if k.RevokedAt != nil {
+		return
+	}
+	now := time.Now()
+	k.RevokedAt = &now
+}
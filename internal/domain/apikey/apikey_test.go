@@ -0,0 +1,66 @@
+package apikey_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		userID      string
+		publicKey   string
+		secretHash  string
+		scopes      []string
+		expiresAt   *time.Time
+		expectedErr error
+	}{
+		{name: "Valid key", userID: "user123", publicKey: "pk", secretHash: "hash", scopes: []string{apikey.ScopePaymentsRead}},
+		{name: "Valid key with expiry", userID: "user123", publicKey: "pk", secretHash: "hash", scopes: []string{apikey.ScopePaymentsRead}, expiresAt: &future},
+		{name: "Empty user ID", publicKey: "pk", secretHash: "hash", scopes: []string{apikey.ScopePaymentsRead}, expectedErr: apikey.ErrEmptyUserID},
+		{name: "Empty public key", userID: "user123", secretHash: "hash", scopes: []string{apikey.ScopePaymentsRead}, expectedErr: apikey.ErrEmptyPublicKey},
+		{name: "Empty secret hash", userID: "user123", publicKey: "pk", scopes: []string{apikey.ScopePaymentsRead}, expectedErr: apikey.ErrEmptySecretHash},
+		{name: "No scopes", userID: "user123", publicKey: "pk", secretHash: "hash", expectedErr: apikey.ErrNoScopes},
+		{name: "Unknown scope", userID: "user123", publicKey: "pk", secretHash: "hash", scopes: []string{"admin"}, expectedErr: apikey.ErrInvalidScope},
+		{name: "Expiry in the past", userID: "user123", publicKey: "pk", secretHash: "hash", scopes: []string{apikey.ScopePaymentsRead}, expiresAt: &past, expectedErr: apikey.ErrExpiryInPast},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := apikey.NewAPIKey(tt.userID, "label", tt.publicKey, tt.secretHash, tt.scopes, tt.expiresAt)
+
+			if err != tt.expectedErr {
+				t.Fatalf("NewAPIKey() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !key.IsActive(time.Now()) {
+				t.Error("NewAPIKey() key should be active")
+			}
+			if !key.HasScope(apikey.ScopePaymentsRead) || key.HasScope(apikey.ScopePaymentsWrite) {
+				t.Errorf("NewAPIKey() scopes = %v", key.Scopes)
+			}
+		})
+	}
+}
+
+func TestAPIKey_IsActive(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	key, _ := apikey.NewAPIKey("user123", "label", "pk", "hash", []string{apikey.ScopePaymentsRead}, &expiresAt)
+
+	if key.IsActive(expiresAt) {
+		t.Error("key should be inactive once expired")
+	}
+
+	key.Revoke()
+	if !key.IsRevoked() || key.IsActive(time.Now()) {
+		t.Error("revoked key should be inactive")
+	}
+}
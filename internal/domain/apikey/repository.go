@@ -0,0 +1,17 @@
+package apikey
+
+import (
+	"context"
+	"time"
+)
+
+// Repository defines the abstract interface for API key persistence
+type Repository interface {
+	Create(ctx context.Context, key *APIKey) error
+	FindByID(ctx context.Context, id string) (*APIKey, error)
+	FindBySecretHash(ctx context.Context, secretHash string) (*APIKey, error)
+	ListByUser(ctx context.Context, userID string) ([]*APIKey, error)
+	Update(ctx context.Context, key *APIKey) error
+	// TouchLastUsed records when the key was last used to authenticate
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrEmptyEventType = errors.New("audit event type cannot be empty")
+	ErrChainBroken    = errors.New("audit chain is broken")
+)
+
+// EventType names what happened
+type EventType string
+
+const (
+	EventUserRegistered    EventType = "user.registered"
+	EventLoginSucceeded    EventType = "login.succeeded"
+	EventLoginFailed       EventType = "login.failed"
+	EventLoginLocked       EventType = "login.locked"
+	EventPasswordChanged   EventType = "password.changed"
+	EventPasswordReset     EventType = "password.reset"
+	EventTokenRevoked      EventType = "token.revoked"
+	EventAllTokensRevoked  EventType = "token.revoked_all"
+	EventRefreshReuse      EventType = "token.reuse_detected"
+	EventSessionRevoked    EventType = "session.revoked"
+	EventRoleChanged       EventType = "user.role_changed"
+	EventMFARequirementSet EventType = "user.mfa_requirement_changed"
+	EventPasskeyRegistered EventType = "passkey.registered"
+	EventPasskeyRemoved    EventType = "passkey.removed"
+	EventDeletionRequested EventType = "user.deletion_requested"
+	EventDeletionCancelled EventType = "user.deletion_cancelled"
+	EventUserAnonymized    EventType = "user.anonymized"
+	EventDataExported      EventType = "user.data_exported"
+	EventAccessDenied      EventType = "access.denied"
+	// Impersonation events have the impersonating user as actor and the
+	// impersonated user as subject
+	EventImpersonationStarted EventType = "impersonation.started"
+	EventImpersonatedRequest  EventType = "impersonation.request"
+)
+
+// Reasons for EventLoginFailed
+const (
+	ReasonUnknownAccount   = "unknown_account"
+	ReasonInvalidPassword  = "invalid_password"
+	ReasonInvalidMFACode   = "invalid_mfa_code"
+	ReasonEmailNotVerified = "email_not_verified"
+	ReasonMFANotEnrolled   = "mfa_not_enrolled"
+	ReasonLockedOut        = "locked_out"
+	ReasonOtherBrowser     = "magic_link_other_browser"
+	ReasonInvalidPasskey   = "invalid_passkey"
+	// ReasonPasskeyCloned means the signature counter went backwards, a
+	// sign that the credential was copied off its authenticator
+	ReasonPasskeyCloned = "passkey_sign_count"
+)
+
+// Event is a single audit log entry. Entries form a hash chain: each one's
+// Hash covers its content and the Hash of the entry before it, so editing,
+// removing or reordering entries is detectable with VerifyChain.
+type Event struct {
+	Sequence   int64             `json:"sequence"`
+	Type       EventType         `json:"type"`
+	ActorID    string            `json:"actor_id,omitempty"`
+	SubjectID  string            `json:"subject_id,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	PrevHash   string            `json:"prev_hash"`
+	Hash       string            `json:"hash"`
+}
+
+// Recorder appends events to the audit log. Recording must not fail the
+// operation being audited, so errors are handled by the recorder.
+type Recorder interface {
+	Record(ctx context.Context, event Event)
+}
+
+// Validate checks the fields every event needs
+func (e *Event) Validate() error {
+	if e.Type == "" {
+		return ErrEmptyEventType
+	}
+	return nil
+}
+
+// Seal links the event after prev (nil for the first entry) and computes
+// its hash
+func (e *Event) Seal(prev *Event) {
+	e.Sequence = 1
+	e.PrevHash = ""
+	if prev != nil {
+		e.Sequence = prev.Sequence + 1
+		e.PrevHash = prev.Hash
+	}
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash returns the SHA-256 of the event content, excluding Hash
+// itself, as hex
+func (e *Event) ComputeHash() string {
+	content := *e
+	content.Hash = ""
+	content.OccurredAt = e.OccurredAt.UTC()
+
+	// Marshalling a struct of strings, a string map and a time cannot fail
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyChain checks that events, in sequence order starting after prev
+// (nil when they start the log), are unmodified and contiguous
+func VerifyChain(prev *Event, events []*Event) error {
+	for _, e := range events {
+		wantSequence, wantPrevHash := int64(1), ""
+		if prev != nil {
+			wantSequence, wantPrevHash = prev.Sequence+1, prev.Hash
+		}
+
+		switch {
+		case e.Sequence != wantSequence:
+			return fmt.Errorf("%w: expected entry %d, found %d", ErrChainBroken, wantSequence, e.Sequence)
+		case e.PrevHash != wantPrevHash:
+			return fmt.Errorf("%w: entry %d does not follow entry %d", ErrChainBroken, e.Sequence, wantSequence-1)
+		case e.Hash != e.ComputeHash():
+			return fmt.Errorf("%w: entry %d was modified", ErrChainBroken, e.Sequence)
+		}
+		prev = e
+	}
+	return nil
+}
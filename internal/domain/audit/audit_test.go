@@ -0,0 +1,130 @@
+package audit_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+)
+
+func sealedChain(n int) []*audit.Event {
+	var events []*audit.Event
+	var prev *audit.Event
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		e := &audit.Event{
+			Type:       audit.EventLoginSucceeded,
+			SubjectID:  "user-1",
+			Details:    map[string]string{"method": "password"},
+			OccurredAt: start.Add(time.Duration(i) * time.Minute),
+		}
+		e.Seal(prev)
+		events = append(events, e)
+		prev = e
+	}
+	return events
+}
+
+func TestEvent_Seal(t *testing.T) {
+	events := sealedChain(2)
+
+	if events[0].Sequence != 1 || events[0].PrevHash != "" {
+		t.Errorf("first entry = %+v, want sequence 1 with no previous hash", events[0])
+	}
+	if events[1].Sequence != 2 || events[1].PrevHash != events[0].Hash {
+		t.Errorf("second entry = %+v, want it linked to the first", events[1])
+	}
+	if len(events[0].Hash) != 64 || events[0].Hash == events[1].Hash {
+		t.Errorf("hashes should be distinct SHA-256 hex digests: %q, %q", events[0].Hash, events[1].Hash)
+	}
+}
+
+func TestEvent_ComputeHash_IgnoresTimeZone(t *testing.T) {
+	e := sealedChain(1)[0]
+	e.OccurredAt = e.OccurredAt.In(time.FixedZone("UTC+2", 2*60*60))
+
+	if e.ComputeHash() != e.Hash {
+		t.Error("ComputeHash() should not depend on the time zone of OccurredAt")
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func([]*audit.Event) []*audit.Event
+		valid  bool
+	}{
+		{name: "intact", tamper: func(e []*audit.Event) []*audit.Event { return e }, valid: true},
+		{name: "edited field", tamper: func(e []*audit.Event) []*audit.Event {
+			e[1].SubjectID = "user-2"
+			return e
+		}},
+		{name: "edited details", tamper: func(e []*audit.Event) []*audit.Event {
+			e[0].Details["method"] = "mfa"
+			return e
+		}},
+		{name: "removed entry", tamper: func(e []*audit.Event) []*audit.Event {
+			return append(e[:1], e[2:]...)
+		}},
+		{name: "reordered entries", tamper: func(e []*audit.Event) []*audit.Event {
+			e[1], e[2] = e[2], e[1]
+			return e
+		}},
+		{name: "rehashed edit", tamper: func(e []*audit.Event) []*audit.Event {
+			e[1].Reason = "forged"
+			e[1].Hash = e[1].ComputeHash()
+			return e
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := audit.VerifyChain(nil, tt.tamper(sealedChain(3)))
+			if tt.valid && err != nil {
+				t.Errorf("VerifyChain() unexpected error = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, audit.ErrChainBroken) {
+				t.Errorf("VerifyChain() error = %v, want %v", err, audit.ErrChainBroken)
+			}
+		})
+	}
+}
+
+func TestVerifyChain_FromPrevious(t *testing.T) {
+	events := sealedChain(3)
+
+	if err := audit.VerifyChain(events[0], events[1:]); err != nil {
+		t.Errorf("VerifyChain() from a previous entry unexpected error = %v", err)
+	}
+	if err := audit.VerifyChain(nil, events[1:]); !errors.Is(err, audit.ErrChainBroken) {
+		t.Errorf("VerifyChain() of a partial log without its predecessor error = %v, want %v", err, audit.ErrChainBroken)
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := &audit.Event{ActorID: "admin-1", SubjectID: "user-1", OccurredAt: at}
+
+	tests := []struct {
+		name     string
+		filter   audit.Filter
+		expected bool
+	}{
+		{name: "empty filter", filter: audit.Filter{}, expected: true},
+		{name: "subject", filter: audit.Filter{UserID: "user-1"}, expected: true},
+		{name: "actor", filter: audit.Filter{UserID: "admin-1"}, expected: true},
+		{name: "other user", filter: audit.Filter{UserID: "user-2"}, expected: false},
+		{name: "from is inclusive", filter: audit.Filter{From: at}, expected: true},
+		{name: "to is exclusive", filter: audit.Filter{To: at}, expected: false},
+		{name: "inside range", filter: audit.Filter{From: at.Add(-time.Hour), To: at.Add(time.Hour)}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(e); got != tt.expected {
+				t.Errorf("Matches() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Filter selects audit events. Zero fields match everything; a zero Limit
+// returns all matches.
+type Filter struct {
+	// UserID matches events where the user is either the actor or the subject
+	UserID string
+	From   time.Time // inclusive
+	To     time.Time // exclusive
+	Limit  int
+}
+
+// Matches reports whether the event passes the filter, ignoring Limit
+func (f Filter) Matches(e *Event) bool {
+	if f.UserID != "" && e.ActorID != f.UserID && e.SubjectID != f.UserID {
+		return false
+	}
+	if !f.From.IsZero() && e.OccurredAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.OccurredAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Repository defines the abstract interface for the append-only audit log
+type Repository interface {
+	// Append seals the event after the last stored entry and stores it.
+	// Implementations must serialize appends so the chain stays linear.
+	Append(ctx context.Context, event *Event) error
+	// Query returns matching events in sequence order
+	Query(ctx context.Context, filter Filter) ([]*Event, error)
+}
@@ -0,0 +1,21 @@
+// Package transaction defines units of work that span several repositories
+package transaction
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrConflict is returned when a transaction cannot commit because data it
+// used was changed concurrently. The unit of work may be retried.
+var ErrConflict = errors.New("transaction conflicts with a concurrent change")
+
+// Manager runs units of work atomically. The transaction travels in the
+// context given to fn: repositories called with that context take part in
+// it, and see its uncommitted changes.
+type Manager interface {
+	// WithinTx runs fn in a transaction, committing when fn returns nil and
+	// rolling back otherwise, in which case fn's error is returned. A call
+	// with a context that already carries a transaction joins it.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,84 @@
+package user
+
+import "time"
+
+// Anonymized accounts keep a unique, undeliverable address on a reserved
+// top-level domain so the email stays unique across users
+const (
+	anonymizedUsername    = "deleted-user"
+	anonymizedEmailDomain = "anonymized.invalid"
+)
+
+// RequestDeletion schedules the account for deletion. Requesting it again
+// keeps the original request time.
+func (u *User) RequestDeletion() {
+	if u.DeletionRequestedAt != nil {
+		return
+	}
+
+	now := time.Now()
+	u.DeletionRequestedAt = &now
+	u.UpdatedAt = now
+}
+
+// CancelDeletion withdraws a pending deletion request. Anonymized accounts
+// cannot be restored.
+func (u *User) CancelDeletion() {
+	if !u.IsPendingDeletion() {
+		return
+	}
+
+	u.DeletionRequestedAt = nil
+	u.UpdatedAt = time.Now()
+}
+
+// IsPendingDeletion reports whether deletion was requested and the account
+// has not been anonymized yet
+func (u *User) IsPendingDeletion() bool {
+	return u.DeletionRequestedAt != nil && u.AnonymizedAt == nil
+}
+
+// IsDeletionDue reports whether the account is pending a deletion that was
+// requested before requestedBefore
+func (u *User) IsDeletionDue(requestedBefore time.Time) bool {
+	return u.IsPendingDeletion() && u.DeletionRequestedAt.Before(requestedBefore)
+}
+
+// IsAnonymized reports whether the personal data of the account was erased
+func (u *User) IsAnonymized() bool {
+	return u.AnonymizedAt != nil
+}
+
+// DeletionDueAt returns when a pending deletion becomes final
+func (u *User) DeletionDueAt(gracePeriod time.Duration) time.Time {
+	if u.DeletionRequestedAt == nil {
+		return time.Time{}
+	}
+	return u.DeletionRequestedAt.Add(gracePeriod)
+}
+
+// Anonymize irreversibly replaces the personal data of the account and its
+// credentials. The ID is kept so records retained for legal reasons, such
+// as payments and the audit log, still refer to the same account.
+func (u *User) Anonymize() {
+	if u.IsAnonymized() {
+		return
+	}
+
+	now := time.Now()
+	u.Username = anonymizedUsername
+	u.Email = "deleted-" + u.ID + "@" + anonymizedEmailDomain
+	u.PasswordHash = ""
+	u.EmailVerified = false
+	u.EmailVerifiedAt = nil
+	u.MFARequired = false
+	u.TOTPSecret = ""
+	u.TOTPPendingSecret = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodeHashes = nil
+	if u.DeletionRequestedAt == nil {
+		u.DeletionRequestedAt = &now
+	}
+	u.AnonymizedAt = &now
+	u.UpdatedAt = now
+}
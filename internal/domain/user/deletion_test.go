@@ -0,0 +1,70 @@
+package user_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestUser_RequestDeletion(t *testing.T) {
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword")
+
+	u.RequestDeletion()
+	if !u.IsPendingDeletion() || u.DeletionRequestedAt == nil {
+		t.Fatal("RequestDeletion() should mark the account pending deletion")
+	}
+	requestedAt := *u.DeletionRequestedAt
+
+	u.RequestDeletion()
+	if !u.DeletionRequestedAt.Equal(requestedAt) {
+		t.Error("RequestDeletion() again should keep the original request time")
+	}
+	if due := u.DeletionDueAt(24 * time.Hour); !due.Equal(requestedAt.Add(24 * time.Hour)) {
+		t.Errorf("DeletionDueAt() = %v, want %v", due, requestedAt.Add(24*time.Hour))
+	}
+	if u.IsDeletionDue(requestedAt) || !u.IsDeletionDue(requestedAt.Add(time.Second)) {
+		t.Error("IsDeletionDue() should hold only for cutoffs after the request")
+	}
+
+	u.CancelDeletion()
+	if u.IsPendingDeletion() || u.DeletionRequestedAt != nil {
+		t.Error("CancelDeletion() should withdraw the request")
+	}
+	if u.IsDeletionDue(requestedAt.Add(time.Second)) {
+		t.Error("IsDeletionDue() after CancelDeletion() = true, want false")
+	}
+}
+
+func TestUser_Anonymize(t *testing.T) {
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword")
+	u.ID = "user-1"
+	u.MarkEmailVerified()
+	u.TOTPSecret = "JBSWY3DPEHPK3PXP"
+	u.RecoveryCodeHashes = []string{"hash"}
+	u.RequestDeletion()
+
+	u.Anonymize()
+
+	if !u.IsAnonymized() || u.IsPendingDeletion() {
+		t.Fatal("Anonymize() should finish the deletion")
+	}
+	if u.Username == "testuser" || strings.Contains(u.Email, "test@example.com") {
+		t.Errorf("Anonymize() kept personal data: %q, %q", u.Username, u.Email)
+	}
+	if _, err := user.ParseEmail(u.Email); err != nil {
+		t.Errorf("Anonymize() email %q should stay a valid, unique address: %v", u.Email, err)
+	}
+	if u.PasswordHash != "" || u.TOTPSecret != "" || u.RecoveryCodeHashes != nil || u.EmailVerified {
+		t.Errorf("Anonymize() kept credentials: %+v", u)
+	}
+	if u.ID != "user-1" {
+		t.Error("Anonymize() must keep the ID for retained records")
+	}
+
+	u.CancelDeletion()
+	if !u.IsAnonymized() || u.DeletionRequestedAt == nil {
+		t.Error("CancelDeletion() must not restore an anonymized account")
+	}
+}
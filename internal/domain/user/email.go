@@ -0,0 +1,139 @@
+package user
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrDisposableEmail = errors.New("disposable email addresses are not allowed")
+
+// Limits from RFC 5321 section 4.5.3.1
+const (
+	maxEmailLocalLength = 64
+	maxEmailLength      = 254
+)
+
+// Email is a parsed email address in canonical form: lowercased, with
+// internationalized domains converted to punycode. Two addresses that
+// reach the same mailbox compare equal.
+type Email struct {
+	local  string
+	domain string
+}
+
+// ParseEmail parses a bare RFC 5322 address such as "John.Doe@Example.com".
+// Display names ("John <john@example.com>") are rejected, and the domain
+// must be a fully qualified host name.
+func ParseEmail(raw string) (Email, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.ContainsAny(raw, "<>") {
+		return Email{}, ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(raw)
+	if err != nil || addr.Name != "" {
+		return Email{}, ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 1 {
+		return Email{}, ErrInvalidEmail
+	}
+	local := strings.ToLower(addr.Address[:at])
+	domain, err := canonicalDomain(addr.Address[at+1:])
+	if err != nil {
+		return Email{}, err
+	}
+
+	e := Email{local: local, domain: domain}
+	if len(local) > maxEmailLocalLength || len(e.String()) > maxEmailLength {
+		return Email{}, ErrInvalidEmail
+	}
+	// Quoted local parts that only parse while quoted ("john doe") are not
+	// supported, so the canonical form is always a parseable address
+	if _, err := mail.ParseAddress(e.String()); err != nil {
+		return Email{}, ErrInvalidEmail
+	}
+	return e, nil
+}
+
+// canonicalDomain lowercases a domain and converts internationalized names
+// to their ASCII (punycode) form
+func canonicalDomain(domain string) (string, error) {
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !strings.Contains(ascii, ".") {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// CanonicalEmail returns the canonical form of raw for use as a lookup key.
+// Values that do not parse are only trimmed and lowercased, so they still
+// compare consistently without matching any valid address.
+func CanonicalEmail(raw string) string {
+	e, err := ParseEmail(raw)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(raw))
+	}
+	return e.String()
+}
+
+// String returns the canonical address
+func (e Email) String() string {
+	if e.domain == "" {
+		return ""
+	}
+	return e.local + "@" + e.domain
+}
+
+// Local returns the part before the @
+func (e Email) Local() string {
+	return e.local
+}
+
+// Domain returns the ASCII domain
+func (e Email) Domain() string {
+	return e.domain
+}
+
+// DomainBlocklist rejects addresses at listed domains and their subdomains,
+// typically disposable email providers
+type DomainBlocklist struct {
+	domains map[string]bool
+}
+
+// NewDomainBlocklist builds a blocklist. Entries are canonicalized like
+// email domains; invalid entries are skipped.
+func NewDomainBlocklist(domains []string) *DomainBlocklist {
+	b := &DomainBlocklist{domains: make(map[string]bool, len(domains))}
+	for _, domain := range domains {
+		if canonical, err := canonicalDomain(strings.TrimSpace(domain)); err == nil {
+			b.domains[canonical] = true
+		}
+	}
+	return b
+}
+
+// Len returns the number of blocked domains
+func (b *DomainBlocklist) Len() int {
+	return len(b.domains)
+}
+
+// Blocks reports whether the address is at a blocked domain
+func (b *DomainBlocklist) Blocks(e Email) bool {
+	domain := e.domain
+	for domain != "" {
+		if b.domains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
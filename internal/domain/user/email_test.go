@@ -0,0 +1,88 @@
+package user_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestParseEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "lowercased", raw: "John.Doe@Example.COM", want: "john.doe@example.com"},
+		{name: "surrounding space", raw: "  john@example.com ", want: "john@example.com"},
+		{name: "plus addressing", raw: "john+pay@example.com", want: "john+pay@example.com"},
+		{name: "redundant quotes", raw: `"john"@example.com`, want: "john@example.com"},
+		{name: "internationalized domain", raw: "john@Bücher.example", want: "john@xn--bcher-kva.example"},
+		{name: "empty", raw: "", wantErr: true},
+		{name: "no at sign", raw: "john", wantErr: true},
+		{name: "no local part", raw: "@example.com", wantErr: true},
+		{name: "no domain", raw: "john@", wantErr: true},
+		{name: "unqualified domain", raw: "john@localhost", wantErr: true},
+		{name: "two at signs", raw: "a@b@example.com", wantErr: true},
+		{name: "display name", raw: "John <john@example.com>", wantErr: true},
+		{name: "quoted space", raw: `"john doe"@example.com`, wantErr: true},
+		{name: "invalid domain label", raw: "john@-bad.example", wantErr: true},
+		{name: "local part too long", raw: strings.Repeat("a", 65) + "@example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := user.ParseEmail(tt.raw)
+			if tt.wantErr {
+				if err != user.ErrInvalidEmail {
+					t.Errorf("ParseEmail() error = %v, want %v", err, user.ErrInvalidEmail)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEmail() unexpected error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseEmail() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalEmail(t *testing.T) {
+	if got := user.CanonicalEmail("John@Example.com"); got != "john@example.com" {
+		t.Errorf("CanonicalEmail() = %q, want john@example.com", got)
+	}
+	if got := user.CanonicalEmail(" Not An Email "); got != "not an email" {
+		t.Errorf("CanonicalEmail() = %q, want %q", got, "not an email")
+	}
+}
+
+func TestDomainBlocklist(t *testing.T) {
+	blocklist := user.NewDomainBlocklist([]string{"Mailinator.com", " tempmail.example ", "not a domain"})
+
+	if blocklist.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", blocklist.Len())
+	}
+
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{email: "john@mailinator.com", want: true},
+		{email: "john@eu.mailinator.com", want: true},
+		{email: "john@TempMail.example", want: true},
+		{email: "john@example.com", want: false},
+		{email: "john@notmailinator.com", want: false},
+	}
+	for _, tt := range tests {
+		e, err := user.ParseEmail(tt.email)
+		if err != nil {
+			t.Fatalf("ParseEmail(%q) unexpected error = %v", tt.email, err)
+		}
+		if got := blocklist.Blocks(e); got != tt.want {
+			t.Errorf("Blocks(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+}
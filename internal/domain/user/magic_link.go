@@ -0,0 +1,61 @@
+package user
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+)
+
+var ErrEmptyNonceHash = errors.New("nonce hash cannot be empty")
+
+// MagicLink represents a single-use passwordless login link. It is bound to
+// the browser that requested it by a nonce kept in a cookie there, so a
+// forwarded link cannot be used elsewhere. Only hashes of the link token
+// and nonce are stored.
+type MagicLink struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	NonceHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// NewMagicLink creates a new magic link entity with validation
+func NewMagicLink(userID, tokenHash, nonceHash string, ttl time.Duration) (*MagicLink, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	if tokenHash == "" {
+		return nil, ErrEmptyTokenHash
+	}
+	if nonceHash == "" {
+		return nil, ErrEmptyNonceHash
+	}
+
+	now := time.Now()
+	return &MagicLink{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		NonceHash: nonceHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+// IsExpired reports whether the link is past its expiry at the given time
+func (l *MagicLink) IsExpired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// IsUsed reports whether the link has already been consumed
+func (l *MagicLink) IsUsed() bool {
+	return l.UsedAt != nil
+}
+
+// MatchesNonce reports whether nonceHash is the hash of the nonce issued to
+// the requesting browser
+func (l *MagicLink) MatchesNonce(nonceHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(l.NonceHash), []byte(nonceHash)) == 1
+}
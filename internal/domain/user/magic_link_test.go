@@ -0,0 +1,56 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestNewMagicLink(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		tokenHash   string
+		nonceHash   string
+		expectedErr error
+	}{
+		{name: "Valid link", userID: "user123", tokenHash: "hash", nonceHash: "nonce"},
+		{name: "Empty user ID", tokenHash: "hash", nonceHash: "nonce", expectedErr: user.ErrEmptyUserID},
+		{name: "Empty token hash", userID: "user123", nonceHash: "nonce", expectedErr: user.ErrEmptyTokenHash},
+		{name: "Empty nonce hash", userID: "user123", tokenHash: "hash", expectedErr: user.ErrEmptyNonceHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := user.NewMagicLink(tt.userID, tt.tokenHash, tt.nonceHash, time.Minute)
+
+			if err != tt.expectedErr {
+				t.Fatalf("NewMagicLink() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if link.IsExpired(time.Now()) || link.IsUsed() {
+				t.Error("NewMagicLink() link should be usable")
+			}
+			if !link.IsExpired(time.Now().Add(time.Minute)) {
+				t.Error("link should be expired once its TTL has passed")
+			}
+		})
+	}
+}
+
+func TestMagicLink_MatchesNonce(t *testing.T) {
+	link, _ := user.NewMagicLink("user123", "hash", "nonce", time.Minute)
+
+	if !link.MatchesNonce("nonce") {
+		t.Error("MatchesNonce() should accept the issued nonce")
+	}
+	for _, nonce := range []string{"", "other", "nonce2"} {
+		if link.MatchesNonce(nonce) {
+			t.Errorf("MatchesNonce(%q) should be false", nonce)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrNoPendingEnrollment = errors.New("no pending two-factor enrollment")
+)
+
+// MFAEnabled reports whether the user has a confirmed second factor
+func (u *User) MFAEnabled() bool {
+	return u.TOTPSecret != ""
+}
+
+// StartTOTPEnrollment stores a secret that becomes active once confirmed
+// with a valid code. Starting again replaces any unconfirmed secret.
+func (u *User) StartTOTPEnrollment(secret string) error {
+	if u.MFAEnabled() {
+		return ErrMFAAlreadyEnabled
+	}
+
+	u.TOTPPendingSecret = secret
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// ConfirmTOTPEnrollment activates the pending secret. step is the time step
+// of the code used for confirmation, so that code cannot be replayed.
+func (u *User) ConfirmTOTPEnrollment(step int64, recoveryCodeHashes []string) error {
+	if u.TOTPPendingSecret == "" {
+		return ErrNoPendingEnrollment
+	}
+
+	u.TOTPSecret = u.TOTPPendingSecret
+	u.TOTPPendingSecret = ""
+	u.TOTPLastStep = step
+	u.RecoveryCodeHashes = recoveryCodeHashes
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// DisableTOTP removes the second factor and its recovery codes
+func (u *User) DisableTOTP() {
+	u.TOTPSecret = ""
+	u.TOTPPendingSecret = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodeHashes = nil
+	u.UpdatedAt = time.Now()
+}
+
+// ConsumeRecoveryCode removes the recovery code with the given hash,
+// reporting whether it existed. Each code can therefore be used only once.
+func (u *User) ConsumeRecoveryCode(hash string) bool {
+	for i, stored := range u.RecoveryCodeHashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			u.RecoveryCodeHashes = append(u.RecoveryCodeHashes[:i:i], u.RecoveryCodeHashes[i+1:]...)
+			u.UpdatedAt = time.Now()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestUser_TOTPEnrollment(t *testing.T) {
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword123")
+
+	if err := u.ConfirmTOTPEnrollment(1, nil); err != user.ErrNoPendingEnrollment {
+		t.Errorf("ConfirmTOTPEnrollment() without enrollment expected ErrNoPendingEnrollment, got %v", err)
+	}
+
+	_ = u.StartTOTPEnrollment("SECRET")
+	if u.MFAEnabled() {
+		t.Error("MFAEnabled() should be false until enrollment is confirmed")
+	}
+
+	if err := u.ConfirmTOTPEnrollment(42, []string{"hash1", "hash2"}); err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment() unexpected error = %v", err)
+	}
+
+	if !u.MFAEnabled() || u.TOTPSecret != "SECRET" || u.TOTPLastStep != 42 {
+		t.Errorf("ConfirmTOTPEnrollment() did not activate the pending secret: %+v", u)
+	}
+
+	if err := u.StartTOTPEnrollment("OTHER"); err != user.ErrMFAAlreadyEnabled {
+		t.Errorf("StartTOTPEnrollment() when enabled expected ErrMFAAlreadyEnabled, got %v", err)
+	}
+
+	u.DisableTOTP()
+	if u.MFAEnabled() || len(u.RecoveryCodeHashes) != 0 {
+		t.Error("DisableTOTP() should clear the secret and recovery codes")
+	}
+}
+
+func TestUser_ConsumeRecoveryCode(t *testing.T) {
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword123")
+	_ = u.StartTOTPEnrollment("SECRET")
+	_ = u.ConfirmTOTPEnrollment(1, []string{"hash1", "hash2"})
+
+	if !u.ConsumeRecoveryCode("hash1") {
+		t.Fatal("ConsumeRecoveryCode() should accept a stored code")
+	}
+
+	if u.ConsumeRecoveryCode("hash1") {
+		t.Error("ConsumeRecoveryCode() should reject a code that was already used")
+	}
+
+	if len(u.RecoveryCodeHashes) != 1 || u.RecoveryCodeHashes[0] != "hash2" {
+		t.Errorf("RecoveryCodeHashes = %v, want [hash2]", u.RecoveryCodeHashes)
+	}
+}
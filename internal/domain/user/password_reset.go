@@ -0,0 +1,42 @@
+package user
+
+import "time"
+
+// PasswordResetToken represents a single-use token emailed to a user who
+// forgot their password. Only the hash of the token value is stored.
+type PasswordResetToken struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// NewPasswordResetToken creates a new password reset token entity with validation
+func NewPasswordResetToken(userID, tokenHash string, ttl time.Duration) (*PasswordResetToken, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	if tokenHash == "" {
+		return nil, ErrEmptyTokenHash
+	}
+
+	now := time.Now()
+	return &PasswordResetToken{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+// IsExpired reports whether the token is past its expiry at the given time
+func (t *PasswordResetToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// IsUsed reports whether the token has already been consumed
+func (t *PasswordResetToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
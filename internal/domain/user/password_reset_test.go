@@ -0,0 +1,41 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		tokenHash   string
+		expectedErr error
+	}{
+		{name: "Valid token", userID: "user123", tokenHash: "hash"},
+		{name: "Empty user ID", tokenHash: "hash", expectedErr: user.ErrEmptyUserID},
+		{name: "Empty token hash", userID: "user123", expectedErr: user.ErrEmptyTokenHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := user.NewPasswordResetToken(tt.userID, tt.tokenHash, time.Hour)
+
+			if err != tt.expectedErr {
+				t.Fatalf("NewPasswordResetToken() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if token.IsExpired(time.Now()) || token.IsUsed() {
+				t.Error("NewPasswordResetToken() token should be usable")
+			}
+			if !token.IsExpired(time.Now().Add(time.Hour)) {
+				t.Error("token should be expired once its TTL has passed")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package user
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrEmptyUserID    = errors.New("user id cannot be empty")
+	ErrEmptyFamilyID  = errors.New("token family id cannot be empty")
+	ErrEmptyTokenHash = errors.New("token hash cannot be empty")
+)
+
+// RefreshToken represents an opaque, single-use refresh token. Tokens that
+// were rotated from one another share a FamilyID so that replaying an old
+// token can revoke the whole chain.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// NewRefreshToken creates a new refresh token entity with validation
+func NewRefreshToken(userID, familyID, tokenHash string, ttl time.Duration) (*RefreshToken, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	if familyID == "" {
+		return nil, ErrEmptyFamilyID
+	}
+	if tokenHash == "" {
+		return nil, ErrEmptyTokenHash
+	}
+
+	now := time.Now()
+	return &RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
+
+// IsExpired reports whether the token is past its expiry at the given time
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// IsUsed reports whether the token has already been exchanged
+func (t *RefreshToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
+
+// IsRevoked reports whether the token has been revoked
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
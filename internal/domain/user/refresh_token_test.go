@@ -0,0 +1,51 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		familyID    string
+		tokenHash   string
+		expectedErr error
+	}{
+		{name: "Valid token", userID: "user123", familyID: "family123", tokenHash: "hash"},
+		{name: "Empty user ID", familyID: "family123", tokenHash: "hash", expectedErr: user.ErrEmptyUserID},
+		{name: "Empty family ID", userID: "user123", tokenHash: "hash", expectedErr: user.ErrEmptyFamilyID},
+		{name: "Empty token hash", userID: "user123", familyID: "family123", expectedErr: user.ErrEmptyTokenHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := user.NewRefreshToken(tt.userID, tt.familyID, tt.tokenHash, time.Hour)
+
+			if err != tt.expectedErr {
+				t.Fatalf("NewRefreshToken() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if token.IsExpired(time.Now()) {
+				t.Error("NewRefreshToken() token should not be expired")
+			}
+			if token.IsUsed() || token.IsRevoked() {
+				t.Error("NewRefreshToken() token should be neither used nor revoked")
+			}
+		})
+	}
+}
+
+func TestRefreshToken_IsExpired(t *testing.T) {
+	token, _ := user.NewRefreshToken("user123", "family123", "hash", time.Hour)
+
+	if !token.IsExpired(time.Now().Add(2 * time.Hour)) {
+		t.Error("IsExpired() should be true after the expiry")
+	}
+}
@@ -1,11 +1,95 @@
 package user
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
-// Repository defines the abstract interface for user data operations
+// ErrConcurrentModification is returned when an entity is updated from a
+// version that is no longer the stored one, because it was changed since
+// it was read. The change should be retried on a fresh copy, or reported.
+var ErrConcurrentModification = errors.New("modified concurrently, reload and retry")
+
+// Repository defines the abstract interface for user data operations.
+// Users are versioned for optimistic concurrency control: Create stores
+// version 1, and Update only succeeds from the stored version.
 type Repository interface {
+	// Create stores a new user and sets its ID, if empty, and Version
 	Create(ctx context.Context, user *User) error
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByID(ctx context.Context, id string) (*User, error)
+	// Update stores the user if its Version is still the stored one, and
+	// increments it. Otherwise it returns ErrConcurrentModification and
+	// changes nothing.
 	Update(ctx context.Context, user *User) error
+	// ListPendingDeletion returns users whose deletion was requested before
+	// requestedBefore and who have not been anonymized yet
+	ListPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*User, error)
+	// Anonymize erases the personal data of the user (see User.Anonymize)
+	// if its deletion is still due; see User.IsDeletionDue. It checks and
+	// rewrites the stored user atomically and reports whether it did.
+	Anonymize(ctx context.Context, id string, requestedBefore time.Time) (bool, error)
+}
+
+// RefreshTokenRepository defines the abstract interface for refresh token persistence
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// MarkUsed atomically flags the token as used. It returns false when the
+	// token had already been used, which signals a replay.
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID string) error
+}
+
+// PasswordResetTokenRepository defines the abstract interface for password reset token persistence
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *PasswordResetToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	// MarkUsed atomically consumes the token. It returns false when the
+	// token had already been used.
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error)
+	// InvalidateAllForUser consumes every outstanding token of the user
+	InvalidateAllForUser(ctx context.Context, userID string) error
+}
+
+// MagicLinkRepository defines the abstract interface for magic link persistence
+type MagicLinkRepository interface {
+	Create(ctx context.Context, link *MagicLink) error
+	FindByHash(ctx context.Context, tokenHash string) (*MagicLink, error)
+	// MarkUsed atomically consumes the link. It returns false when the link
+	// had already been used.
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error)
+	// InvalidateAllForUser consumes every outstanding link of the user
+	InvalidateAllForUser(ctx context.Context, userID string) error
+}
+
+// WebAuthnCredentialRepository defines the abstract interface for passkey persistence
+type WebAuthnCredentialRepository interface {
+	// Create stores a credential, failing if its credential ID is taken
+	Create(ctx context.Context, credential *WebAuthnCredential) error
+	FindByCredentialID(ctx context.Context, credentialID []byte) (*WebAuthnCredential, error)
+	ListByUser(ctx context.Context, userID string) ([]*WebAuthnCredential, error)
+	// UpdateSignCount stores a new signature counter and the time of use,
+	// but only if the stored counter still equals previous. It reports
+	// whether the update happened.
+	UpdateSignCount(ctx context.Context, id string, previous, next uint32, usedAt time.Time) (bool, error)
+	Delete(ctx context.Context, id string) error
+	DeleteAllForUser(ctx context.Context, userID string) error
+}
+
+// SessionRepository defines the abstract interface for login session persistence
+type SessionRepository interface {
+	Create(ctx context.Context, session *Session) error
+	FindByID(ctx context.Context, id string) (*Session, error)
+	FindByFamily(ctx context.Context, familyID string) (*Session, error)
+	ListByUser(ctx context.Context, userID string) ([]*Session, error)
+	// Touch records activity on the session
+	Touch(ctx context.Context, id string, seenAt time.Time) error
+	Revoke(ctx context.Context, id string, revokedAt time.Time) error
+	RevokeAllForUser(ctx context.Context, userID string, revokedAt time.Time) error
+	// DeleteStale removes sessions last seen, or revoked, before cutoff and
+	// returns how many were removed
+	DeleteStale(ctx context.Context, cutoff time.Time) (int, error)
 }
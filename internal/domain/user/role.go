@@ -0,0 +1,93 @@
+package user
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+)
+
+// Role determines what a user is allowed to do
+type Role string
+
+const (
+	RoleAdmin             Role = "admin"
+	RoleMerchantOwner     Role = "merchant_owner"
+	RoleMerchantDeveloper Role = "merchant_developer"
+	RoleSupport           Role = "support"
+	RoleFinanceReadOnly   Role = "finance_readonly"
+)
+
+// DefaultRole is given to self-registered accounts
+const DefaultRole = RoleMerchantOwner
+
+// Permission is a single capability a route can require. API key scopes use
+// the same vocabulary.
+type Permission string
+
+const (
+	PermProfileRead      Permission = "profile:read"
+	PermPaymentsRead     Permission = "payments:read"
+	PermPaymentsWrite    Permission = "payments:write"
+	PermPaymentsRefund   Permission = "payments:refund"
+	PermAPIKeysManage    Permission = "api_keys:manage"
+	PermUsersRead        Permission = "users:read"
+	PermUsersManage      Permission = "users:manage"
+	PermAuditRead        Permission = "audit:read"
+	PermUsersImpersonate Permission = "users:impersonate"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermProfileRead, PermPaymentsRead, PermPaymentsWrite, PermPaymentsRefund,
+		PermAPIKeysManage, PermUsersRead, PermUsersManage, PermAuditRead, PermUsersImpersonate,
+	},
+	RoleMerchantOwner: {
+		PermProfileRead, PermPaymentsRead, PermPaymentsWrite, PermPaymentsRefund, PermAPIKeysManage,
+	},
+	RoleMerchantDeveloper: {
+		PermProfileRead, PermPaymentsRead, PermPaymentsWrite, PermAPIKeysManage,
+	},
+	RoleSupport: {
+		PermProfileRead, PermPaymentsRead, PermUsersRead,
+	},
+	RoleFinanceReadOnly: {
+		PermProfileRead, PermPaymentsRead,
+	},
+}
+
+// ParseRole converts a role name into a Role
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", ErrInvalidRole
+	}
+	return role, nil
+}
+
+// Permissions returns the permissions granted to the role
+func (r Role) Permissions() []Permission {
+	return slices.Clone(rolePermissions[r])
+}
+
+// Can reports whether the role grants permission. Unknown roles grant nothing.
+func (r Role) Can(permission Permission) bool {
+	return slices.Contains(rolePermissions[r], permission)
+}
+
+// AssignRole changes the role of the user
+func (u *User) AssignRole(role Role) error {
+	if _, err := ParseRole(string(role)); err != nil {
+		return err
+	}
+	if role == u.Role {
+		return nil
+	}
+
+	u.Role = role
+	u.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,72 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    user.Role
+		expectedErr error
+	}{
+		{name: "admin", expected: user.RoleAdmin},
+		{name: "merchant_owner", expected: user.RoleMerchantOwner},
+		{name: "merchant_developer", expected: user.RoleMerchantDeveloper},
+		{name: "support", expected: user.RoleSupport},
+		{name: "finance_readonly", expected: user.RoleFinanceReadOnly},
+		{name: "superuser", expectedErr: user.ErrInvalidRole},
+		{name: "", expectedErr: user.ErrInvalidRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := user.ParseRole(tt.name)
+			if err != tt.expectedErr || role != tt.expected {
+				t.Errorf("ParseRole(%q) = %v, %v; expected %v, %v", tt.name, role, err, tt.expected, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRole_Can(t *testing.T) {
+	tests := []struct {
+		role       user.Role
+		permission user.Permission
+		expected   bool
+	}{
+		{role: user.RoleAdmin, permission: user.PermUsersManage, expected: true},
+		{role: user.RoleMerchantOwner, permission: user.PermPaymentsRefund, expected: true},
+		{role: user.RoleMerchantOwner, permission: user.PermUsersManage, expected: false},
+		{role: user.RoleMerchantDeveloper, permission: user.PermPaymentsRefund, expected: false},
+		{role: user.RoleSupport, permission: user.PermUsersRead, expected: true},
+		{role: user.RoleSupport, permission: user.PermPaymentsWrite, expected: false},
+		{role: user.RoleSupport, permission: user.PermUsersImpersonate, expected: false},
+		{role: user.RoleAdmin, permission: user.PermUsersImpersonate, expected: true},
+		{role: user.RoleFinanceReadOnly, permission: user.PermPaymentsRead, expected: true},
+		{role: user.RoleFinanceReadOnly, permission: user.PermAPIKeysManage, expected: false},
+		{role: user.Role(""), permission: user.PermProfileRead, expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.permission); got != tt.expected {
+			t.Errorf("%q.Can(%q) = %v, expected %v", tt.role, tt.permission, got, tt.expected)
+		}
+	}
+}
+
+func TestUser_AssignRole(t *testing.T) {
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword123")
+
+	if u.Role != user.DefaultRole {
+		t.Errorf("NewUser() role = %v, expected %v", u.Role, user.DefaultRole)
+	}
+	if err := u.AssignRole("superuser"); err != user.ErrInvalidRole {
+		t.Errorf("AssignRole() error = %v, expected %v", err, user.ErrInvalidRole)
+	}
+	if err := u.AssignRole(user.RoleSupport); err != nil || u.Role != user.RoleSupport {
+		t.Errorf("AssignRole() = %v, role %v; expected support", err, u.Role)
+	}
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"time"
+)
+
+// maxUserAgentLength bounds the stored user agent, which is client supplied
+const maxUserAgentLength = 512
+
+// Session is a signed-in device. It is created on login, shares its
+// FamilyID with the refresh tokens rotated from that login, and is named by
+// the sid claim of every access token issued for it.
+type Session struct {
+	ID         string
+	UserID     string
+	FamilyID   string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
+
+// NewSession creates a new session entity with validation
+func NewSession(userID, familyID, ip, userAgent string) (*Session, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	if familyID == "" {
+		return nil, ErrEmptyFamilyID
+	}
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	return &Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}, nil
+}
+
+// IsRevoked reports whether the session was signed out
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsIdle reports whether the session has been unused for longer than
+// timeout. A zero timeout never expires sessions.
+func (s *Session) IsIdle(now time.Time, timeout time.Duration) bool {
+	return timeout > 0 && !now.Before(s.LastSeenAt.Add(timeout))
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive(now time.Time, timeout time.Duration) bool {
+	return !s.IsRevoked() && !s.IsIdle(now, timeout)
+}
@@ -0,0 +1,65 @@
+package user_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestNewSession(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		familyID    string
+		expectedErr error
+	}{
+		{name: "valid session", userID: "user-1", familyID: "family-1"},
+		{name: "missing user", userID: "", familyID: "family-1", expectedErr: user.ErrEmptyUserID},
+		{name: "missing family", userID: "user-1", familyID: "", expectedErr: user.ErrEmptyFamilyID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := user.NewSession(tt.userID, tt.familyID, "192.0.2.1", "curl/8.0")
+			if err != tt.expectedErr {
+				t.Fatalf("NewSession() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+			if !s.LastSeenAt.Equal(s.CreatedAt) || s.IsRevoked() {
+				t.Errorf("NewSession() = %+v, want a fresh active session", s)
+			}
+		})
+	}
+}
+
+func TestNewSession_TruncatesUserAgent(t *testing.T) {
+	s, _ := user.NewSession("user-1", "family-1", "192.0.2.1", strings.Repeat("x", 2000))
+	if len(s.UserAgent) != 512 {
+		t.Errorf("UserAgent length = %d, want 512", len(s.UserAgent))
+	}
+}
+
+func TestSession_IsActive(t *testing.T) {
+	s, _ := user.NewSession("user-1", "family-1", "", "")
+	now := s.LastSeenAt
+
+	if !s.IsActive(now.Add(time.Hour), 2*time.Hour) {
+		t.Error("IsActive() = false within the idle timeout")
+	}
+	if s.IsActive(now.Add(2*time.Hour), 2*time.Hour) {
+		t.Error("IsActive() = true after the idle timeout")
+	}
+	if !s.IsActive(now.Add(1000*time.Hour), 0) {
+		t.Error("IsActive() = false with no idle timeout")
+	}
+
+	revokedAt := now
+	s.RevokedAt = &revokedAt
+	if s.IsActive(now, time.Hour) {
+		t.Error("IsActive() = true for a revoked session")
+	}
+}
@@ -6,9 +6,9 @@ import (
 )
 
 var (
-	ErrInvalidEmail       = errors.New("invalid email address")
-	ErrEmptyPasswordHash  = errors.New("password hash cannot be empty")
-	ErrEmptyUsername      = errors.New("username cannot be empty")
+	ErrInvalidEmail      = errors.New("invalid email address")
+	ErrEmptyPasswordHash = errors.New("password hash cannot be empty")
+	ErrEmptyUsername     = errors.New("username cannot be empty")
 )
 
 // User represents the user domain entity
@@ -17,17 +17,39 @@ type User struct {
 	Username     string
 	Email        string
 	PasswordHash string
+	Role         Role
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+
+	// Email verification
+	EmailVerified   bool
+	EmailVerifiedAt *time.Time
+
+	// Two-factor authentication
+	MFARequired        bool
+	TOTPSecret         string
+	TOTPPendingSecret  string
+	TOTPLastStep       int64
+	RecoveryCodeHashes []string
+
+	// Account deletion; see RequestDeletion and Anonymize
+	DeletionRequestedAt *time.Time
+	AnonymizedAt        *time.Time
+
+	// Version counts the stored revisions of the user; it is set by the
+	// repository. See Repository.Update.
+	Version int64
 }
 
-// NewUser creates a new user entity with validation
+// NewUser creates a new user entity with validation. The email is stored
+// in canonical form; see ParseEmail.
 func NewUser(username, email, passwordHash string) (*User, error) {
 	if username == "" {
 		return nil, ErrEmptyUsername
 	}
-	if email == "" {
-		return nil, ErrInvalidEmail
+	address, err := ParseEmail(email)
+	if err != nil {
+		return nil, err
 	}
 	if passwordHash == "" {
 		return nil, ErrEmptyPasswordHash
@@ -36,9 +58,66 @@ func NewUser(username, email, passwordHash string) (*User, error) {
 	now := time.Now()
 	return &User{
 		Username:     username,
-		Email:        email,
+		Email:        address.String(),
 		PasswordHash: passwordHash,
+		Role:         DefaultRole,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}, nil
 }
+
+// MarkEmailVerified records that the user proved ownership of their email
+func (u *User) MarkEmailVerified() {
+	if u.EmailVerified {
+		return
+	}
+
+	now := time.Now()
+	u.EmailVerified = true
+	u.EmailVerifiedAt = &now
+	u.UpdatedAt = now
+}
+
+// ChangePasswordHash replaces the stored password hash
+func (u *User) ChangePasswordHash(passwordHash string) error {
+	if passwordHash == "" {
+		return ErrEmptyPasswordHash
+	}
+
+	u.PasswordHash = passwordHash
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// ChangeUsername renames the user
+func (u *User) ChangeUsername(username string) error {
+	if username == "" {
+		return ErrEmptyUsername
+	}
+	if username == u.Username {
+		return nil
+	}
+
+	u.Username = username
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// ChangeEmail replaces the email address. The new address has not been
+// verified yet, so the verification state is reset. Changing only the case
+// of the address is a no-op.
+func (u *User) ChangeEmail(email string) error {
+	address, err := ParseEmail(email)
+	if err != nil {
+		return err
+	}
+	if address.String() == CanonicalEmail(u.Email) {
+		return nil
+	}
+
+	u.Email = address.String()
+	u.EmailVerified = false
+	u.EmailVerifiedAt = nil
+	u.UpdatedAt = time.Now()
+	return nil
+}
@@ -79,3 +79,57 @@ func TestNewUser(t *testing.T) {
 		})
 	}
 }
+
+func TestUser_MarkEmailVerified(t *testing.T) {
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword123")
+
+	if u.EmailVerified {
+		t.Fatal("NewUser() should create unverified users")
+	}
+
+	u.MarkEmailVerified()
+	if !u.EmailVerified || u.EmailVerifiedAt == nil {
+		t.Fatal("MarkEmailVerified() should set the verified state")
+	}
+
+	verifiedAt := *u.EmailVerifiedAt
+	u.MarkEmailVerified()
+	if !u.EmailVerifiedAt.Equal(verifiedAt) {
+		t.Error("MarkEmailVerified() should keep the first verification time")
+	}
+}
+
+func TestUser_ChangeEmail(t *testing.T) {
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword123")
+	u.MarkEmailVerified()
+
+	if err := u.ChangeEmail(""); err != user.ErrInvalidEmail {
+		t.Errorf("ChangeEmail() error = %v, expected %v", err, user.ErrInvalidEmail)
+	}
+
+	// Keeping the same address, in any case, keeps it verified
+	if err := u.ChangeEmail("Test@Example.com"); err != nil || !u.EmailVerified {
+		t.Errorf("ChangeEmail() same address: err = %v, verified = %v", err, u.EmailVerified)
+	}
+
+	if err := u.ChangeEmail("new@example.com"); err != nil {
+		t.Fatalf("ChangeEmail() unexpected error = %v", err)
+	}
+	if u.Email != "new@example.com" || u.EmailVerified || u.EmailVerifiedAt != nil {
+		t.Error("ChangeEmail() should set the new address and reset verification")
+	}
+}
+
+func TestNewUser_CanonicalEmail(t *testing.T) {
+	u, err := user.NewUser("testuser", " John.Doe@Example.COM", "hashedpassword123")
+	if err != nil {
+		t.Fatalf("NewUser() unexpected error = %v", err)
+	}
+	if u.Email != "john.doe@example.com" {
+		t.Errorf("Email = %q, want john.doe@example.com", u.Email)
+	}
+
+	if _, err := user.NewUser("testuser", "not-an-email", "hashedpassword123"); err != user.ErrInvalidEmail {
+		t.Errorf("NewUser() error = %v, expected %v", err, user.ErrInvalidEmail)
+	}
+}
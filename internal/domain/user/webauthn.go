@@ -0,0 +1,70 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEmptyCredentialID = errors.New("credential id cannot be empty")
+	ErrEmptyPublicKey    = errors.New("credential public key cannot be empty")
+)
+
+// maxCredentialNameLength bounds the user supplied credential name
+const maxCredentialNameLength = 64
+
+// defaultCredentialName is used when the user does not name a credential
+const defaultCredentialName = "Passkey"
+
+// WebAuthnCredential is a passkey or security key registered to a user. It
+// can replace the password, or serve as a second factor after it.
+type WebAuthnCredential struct {
+	ID     string
+	UserID string
+	// CredentialID is the authenticator's identifier for the credential
+	CredentialID []byte
+	// PublicKey is the COSE encoded credential public key
+	PublicKey []byte
+	// SignCount is the last signature counter seen, used to detect cloned
+	// authenticators
+	SignCount         uint32
+	AAGUID            []byte
+	AttestationFormat string
+	Name              string
+	CreatedAt         time.Time
+	LastUsedAt        *time.Time
+}
+
+// NewWebAuthnCredential creates a new credential entity with validation
+func NewWebAuthnCredential(userID string, credentialID, publicKey []byte, signCount uint32, name string) (*WebAuthnCredential, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	if len(credentialID) == 0 {
+		return nil, ErrEmptyCredentialID
+	}
+	if len(publicKey) == 0 {
+		return nil, ErrEmptyPublicKey
+	}
+
+	return &WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: credentialID,
+		PublicKey:    publicKey,
+		SignCount:    signCount,
+		Name:         normalizeCredentialName(name),
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+func normalizeCredentialName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return defaultCredentialName
+	}
+	if runes := []rune(name); len(runes) > maxCredentialNameLength {
+		name = string(runes[:maxCredentialNameLength])
+	}
+	return name
+}
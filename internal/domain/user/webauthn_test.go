@@ -0,0 +1,47 @@
+package user_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+func TestNewWebAuthnCredential(t *testing.T) {
+	tests := []struct {
+		name         string
+		userID       string
+		credentialID []byte
+		publicKey    []byte
+		credName     string
+		expectedName string
+		expectedErr  error
+	}{
+		{name: "Valid credential", userID: "user123", credentialID: []byte{1}, publicKey: []byte{2}, credName: "  Laptop ", expectedName: "Laptop"},
+		{name: "Default name", userID: "user123", credentialID: []byte{1}, publicKey: []byte{2}, expectedName: "Passkey"},
+		{name: "Long name", userID: "user123", credentialID: []byte{1}, publicKey: []byte{2}, credName: strings.Repeat("é", 70), expectedName: strings.Repeat("é", 64)},
+		{name: "Empty user ID", credentialID: []byte{1}, publicKey: []byte{2}, expectedErr: user.ErrEmptyUserID},
+		{name: "Empty credential ID", userID: "user123", publicKey: []byte{2}, expectedErr: user.ErrEmptyCredentialID},
+		{name: "Empty public key", userID: "user123", credentialID: []byte{1}, expectedErr: user.ErrEmptyPublicKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credential, err := user.NewWebAuthnCredential(tt.userID, tt.credentialID, tt.publicKey, 0, tt.credName)
+
+			if err != tt.expectedErr {
+				t.Fatalf("NewWebAuthnCredential() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if credential.Name != tt.expectedName {
+				t.Errorf("NewWebAuthnCredential() name = %q, expected %q", credential.Name, tt.expectedName)
+			}
+			if credential.CreatedAt.IsZero() {
+				t.Error("NewWebAuthnCredential() should set CreatedAt")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+// AdminHandler handles administrative HTTP requests
+type AdminHandler struct {
+	userUseCase user.UseCase
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(userUseCase user.UseCase) *AdminHandler {
+	return &AdminHandler{
+		userUseCase: userUseCase,
+	}
+}
+
+// MFARequirementRequest represents the payload to require MFA for an account
+type MFARequirementRequest struct {
+	Required bool `json:"required"`
+}
+
+// RoleRequest represents the payload to change the role of an account
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ImpersonateRequest represents the payload to start impersonating a user
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonateResponse carries the token to act as the user with
+type ImpersonateResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"`
+	UserID    string `json:"user_id"`
+}
+
+// SetMFARequirement requires or stops requiring MFA for the user in the path
+func (h *AdminHandler) SetMFARequirement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MFARequirementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.userUseCase.SetMFARequired(r.Context(), r.PathValue("id"), req.Required)
+	if errors.Is(err, user.ErrUserNotFound) {
+		sendError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, domainUser.ErrConcurrentModification) {
+		sendError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, MessageResponse{Message: "MFA requirement updated"}, http.StatusOK)
+}
+
+// SetRole changes the role of the user in the path
+func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := domainUser.ParseRole(req.Role)
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.userUseCase.SetRole(r.Context(), r.PathValue("id"), role)
+	if errors.Is(err, user.ErrUserNotFound) {
+		sendError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, domainUser.ErrConcurrentModification) {
+		sendError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, MessageResponse{Message: "Role updated"}, http.StatusOK)
+}
+
+// Impersonate issues a short-lived token to act as the user in the path
+func (h *AdminHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, _ := r.Context().Value(middleware.ClaimsKey).(*jwt.Claims)
+	if claims == nil {
+		sendError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targetID := r.PathValue("id")
+	impersonation, err := h.userUseCase.Impersonate(r.Context(), claims, targetID, req.Reason)
+	switch {
+	case errors.Is(err, user.ErrImpersonationReasonRequired):
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, user.ErrUserNotFound):
+		sendError(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, user.ErrCannotImpersonate):
+		sendError(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, ImpersonateResponse{
+		Token:     impersonation.AccessToken,
+		ExpiresIn: int64(impersonation.ExpiresIn.Seconds()),
+		UserID:    targetID,
+	}, http.StatusOK)
+}
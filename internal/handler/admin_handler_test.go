@@ -0,0 +1,277 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	domainAudit "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	auditRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	auditUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/audit"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func setupAdminHandler() (*userUseCase.Service, *jwt.Service, http.Handler) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService)
+	h := handler.NewAdminHandler(service)
+	auth := middleware.NewAuth(jwtService)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/users/{id}/mfa", auth.Authenticate(auth.RequirePermission(domainUser.PermUsersManage)(h.SetMFARequirement)))
+	mux.HandleFunc("/api/admin/users/{id}/role", auth.Authenticate(auth.RequirePermission(domainUser.PermUsersManage)(h.SetRole)))
+	return service, jwtService, mux
+}
+
+func adminToken(jwtService *jwt.Service) string {
+	token, _ := jwtService.GenerateAccessToken(jwt.Claims{UserID: "admin-id", Email: "admin@example.com", Role: string(domainUser.RoleAdmin)})
+	return token
+}
+
+func TestAdminHandler_SetMFARequirement(t *testing.T) {
+	service, jwtService, mux := setupAdminHandler()
+
+	target, _ := service.Register(context.Background(), "merchant", "merchant@example.com", "password123")
+	adminToken := adminToken(jwtService)
+
+	body, _ := json.Marshal(handler.MFARequirementRequest{Required: true})
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/users/"+target.ID+"/mfa", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("SetMFARequirement() status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	if _, err := service.Login(context.Background(), "merchant@example.com", "password123"); err != userUseCase.ErrMFARequired {
+		t.Errorf("Login() after requiring MFA expected ErrMFARequired, got %v", err)
+	}
+}
+
+func TestAdminHandler_SetMFARequirement_NotAdmin(t *testing.T) {
+	service, jwtService, mux := setupAdminHandler()
+
+	target, _ := service.Register(context.Background(), "merchant", "merchant@example.com", "password123")
+	token, _ := jwtService.GenerateToken(target.ID, target.Email)
+
+	body, _ := json.Marshal(handler.MFARequirementRequest{Required: true})
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/users/"+target.ID+"/mfa", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("SetMFARequirement() status = %v, want %v", w.Code, http.StatusForbidden)
+	}
+
+	var resp middleware.PermissionDeniedResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != "permission_denied" || resp.RequiredPermission != string(domainUser.PermUsersManage) {
+		t.Errorf("SetMFARequirement() denial = %+v", resp)
+	}
+}
+
+func TestAdminHandler_SetMFARequirement_UnknownUser(t *testing.T) {
+	_, jwtService, mux := setupAdminHandler()
+	adminToken := adminToken(jwtService)
+
+	body, _ := json.Marshal(handler.MFARequirementRequest{Required: true})
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/users/missing/mfa", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("SetMFARequirement() status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminHandler_SetRole(t *testing.T) {
+	service, jwtService, mux := setupAdminHandler()
+	ctx := context.Background()
+
+	target, _ := service.Register(ctx, "merchant", "merchant@example.com", "password123")
+
+	tests := []struct {
+		name       string
+		role       string
+		wantStatus int
+	}{
+		{name: "unknown role", role: "superuser", wantStatus: http.StatusBadRequest},
+		{name: "valid role", role: string(domainUser.RoleSupport), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(handler.RoleRequest{Role: tt.role})
+			req := httptest.NewRequest(http.MethodPut, "/api/admin/users/"+target.ID+"/role", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+adminToken(jwtService))
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SetRole() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	result, err := service.Login(ctx, "merchant@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+	claims, _ := jwtService.ValidateToken(result.Tokens.AccessToken)
+	if claims.Role != string(domainUser.RoleSupport) {
+		t.Errorf("role claim = %v, want %v", claims.Role, domainUser.RoleSupport)
+	}
+}
+
+func setupImpersonation() (*userUseCase.Service, *jwt.Service, *auditUseCase.Service, http.Handler) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour)
+	auditService := auditUseCase.NewService(auditRepo.NewInMemoryRepository())
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithAuditLog(auditService),
+	)
+	adminHandler := handler.NewAdminHandler(service)
+	userHandler := handler.NewUserHandler(service)
+	auth := middleware.NewAuth(jwtService, middleware.WithAuditLog(auditService))
+	noImpersonation := auth.RejectImpersonation
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/users/{id}/impersonate", auth.Authenticate(noImpersonation()(auth.RequirePermission(domainUser.PermUsersImpersonate)(adminHandler.Impersonate))))
+	mux.HandleFunc("/api/me", auth.Authenticate(noImpersonation(http.MethodPatch, http.MethodDelete)(userHandler.Profile)))
+	mux.HandleFunc("/api/me/password", auth.Authenticate(noImpersonation()(userHandler.ChangePassword)))
+	mux.HandleFunc("/whoami", auth.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"user_id":      r.Context().Value(middleware.UserIDKey),
+			"real_user_id": r.Context().Value(middleware.RealUserIDKey),
+		})
+	}))
+	return service, jwtService, auditService, middleware.RequestMeta(false)(mux)
+}
+
+func impersonate(t *testing.T, mux http.Handler, token, targetID, reason string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, _ := json.Marshal(handler.ImpersonateRequest{Reason: reason})
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+targetID+"/impersonate", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminHandler_Impersonate(t *testing.T) {
+	service, jwtService, auditService, mux := setupImpersonation()
+	ctx := context.Background()
+
+	target, _ := service.Register(ctx, "merchant", "merchant@example.com", "password123")
+
+	w := impersonate(t, mux, adminToken(jwtService), target.ID, "ticket #42")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Impersonate() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var resp handler.ImpersonateResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Token == "" || resp.UserID != target.ID || resp.ExpiresIn <= 0 {
+		t.Fatalf("Impersonate() response = %+v", resp)
+	}
+
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// Handlers see the impersonated user, with the admin as the real user
+	var who map[string]string
+	json.NewDecoder(send(http.MethodGet, "/whoami", nil).Body).Decode(&who)
+	if who["user_id"] != target.ID || who["real_user_id"] != "admin-id" {
+		t.Errorf("context users = %v, want effective %s and real admin-id", who, target.ID)
+	}
+	if w := send(http.MethodGet, "/api/me", nil); w.Code != http.StatusOK {
+		t.Errorf("GET /api/me while impersonating status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	// Sensitive actions are refused
+	blocked := []struct{ method, path string }{
+		{http.MethodPatch, "/api/me"},
+		{http.MethodPost, "/api/me/password"},
+		{http.MethodPost, "/api/admin/users/" + target.ID + "/impersonate"},
+	}
+	for _, b := range blocked {
+		if w := send(b.method, b.path, map[string]string{}); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s while impersonating status = %v, want %v", b.method, b.path, w.Code, http.StatusForbidden)
+		}
+	}
+
+	// Starting the impersonation and every impersonated request are audited
+	events, _ := auditService.Query(ctx, domainAudit.Filter{UserID: target.ID})
+	var started, requests, denied int
+	for _, e := range events {
+		if e.ActorID != "admin-id" && e.Type != domainAudit.EventUserRegistered {
+			t.Errorf("event %s actor = %q, want admin-id", e.Type, e.ActorID)
+		}
+		switch e.Type {
+		case domainAudit.EventImpersonationStarted:
+			started++
+			if e.Reason != "ticket #42" {
+				t.Errorf("impersonation reason = %q", e.Reason)
+			}
+		case domainAudit.EventImpersonatedRequest:
+			requests++
+		case domainAudit.EventAccessDenied:
+			denied++
+		}
+	}
+	if started != 1 || requests != 5 || denied != 3 {
+		t.Errorf("audited %d starts, %d requests and %d denials; want 1, 5 and 3", started, requests, denied)
+	}
+}
+
+func TestAdminHandler_Impersonate_Refused(t *testing.T) {
+	service, jwtService, _, mux := setupImpersonation()
+	ctx := context.Background()
+
+	merchant, _ := service.Register(ctx, "merchant", "merchant@example.com", "password123")
+	otherAdmin, _ := service.Register(ctx, "admin2", "admin2@example.com", "password123")
+	service.SetRole(ctx, otherAdmin.ID, domainUser.RoleAdmin)
+	merchantToken, _ := jwtService.GenerateAccessToken(jwt.Claims{UserID: merchant.ID, Role: string(domainUser.RoleMerchantOwner)})
+
+	tests := []struct {
+		name       string
+		token      string
+		targetID   string
+		reason     string
+		wantStatus int
+	}{
+		{name: "missing reason", token: adminToken(jwtService), targetID: merchant.ID, wantStatus: http.StatusBadRequest},
+		{name: "unknown user", token: adminToken(jwtService), targetID: "missing", reason: "debug", wantStatus: http.StatusNotFound},
+		{name: "another admin", token: adminToken(jwtService), targetID: otherAdmin.ID, reason: "debug", wantStatus: http.StatusForbidden},
+		{name: "not an admin", token: merchantToken, targetID: otherAdmin.ID, reason: "debug", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := impersonate(t, mux, tt.token, tt.targetID, tt.reason); w.Code != tt.wantStatus {
+				t.Errorf("Impersonate() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	apiKeyUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/apikey"
+)
+
+// APIKeyHandler handles API key management requests
+type APIKeyHandler struct {
+	apiKeyUseCase apiKeyUseCase.UseCase
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyUseCase apiKeyUseCase.UseCase) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+// CreateAPIKeyRequest represents the API key creation payload
+type CreateAPIKeyRequest struct {
+	Label     string     `json:"label"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// RenameAPIKeyRequest represents the API key label update payload
+type RenameAPIKeyRequest struct {
+	Label string `json:"label"`
+}
+
+// APIKeyResponse describes an API key without its secret
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Label      string     `json:"label"`
+	PublicKey  string     `json:"public_key"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyResponse is returned once, when the key is created
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Secret  string `json:"secret"`
+	Message string `json:"message"`
+}
+
+// Keys lists (GET) or creates (POST) API keys of the authenticated user
+func (h *APIKeyHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.list(w, r)
+	case http.MethodPost:
+		h.create(w, r)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Key renames (PATCH) or revokes (DELETE) the API key in the path
+func (h *APIKeyHandler) Key(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPatch:
+		h.rename(w, r)
+	case http.MethodDelete:
+		h.revoke(w, r)
+	default:
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *APIKeyHandler) list(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	keys, err := h.apiKeyUseCase.List(r.Context(), userID)
+	if err != nil {
+		sendError(w, "Failed to list API keys", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		resp = append(resp, newAPIKeyResponse(key))
+	}
+	sendJSON(w, resp, http.StatusOK)
+}
+
+func (h *APIKeyHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	created, err := h.apiKeyUseCase.Create(r.Context(), userID, req.Label, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		sendError(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	sendJSON(w, CreateAPIKeyResponse{
+		APIKeyResponse: newAPIKeyResponse(created.Key),
+		Secret:         created.Secret,
+		Message:        "Store the secret safely; it will not be shown again.",
+	}, http.StatusCreated)
+}
+
+func (h *APIKeyHandler) rename(w http.ResponseWriter, r *http.Request) {
+	var req RenameAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	key, err := h.apiKeyUseCase.Rename(r.Context(), userID, r.PathValue("id"), req.Label)
+	if err != nil {
+		sendError(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	sendJSON(w, newAPIKeyResponse(key), http.StatusOK)
+}
+
+func (h *APIKeyHandler) revoke(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	if err := h.apiKeyUseCase.Revoke(r.Context(), userID, r.PathValue("id")); err != nil {
+		sendError(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	sendJSON(w, MessageResponse{Message: "API key revoked"}, http.StatusOK)
+}
+
+func newAPIKeyResponse(key *apikey.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Label:      key.Label,
+		PublicKey:  key.PublicKey,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, apikey.ErrNoScopes),
+		errors.Is(err, apikey.ErrInvalidScope),
+		errors.Is(err, apikey.ErrExpiryInPast):
+		return http.StatusBadRequest
+	case errors.Is(err, apiKeyUseCase.ErrScopeNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, apiKeyUseCase.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
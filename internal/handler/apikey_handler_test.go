@@ -0,0 +1,113 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	apiKeyRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	apiKeyUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/apikey"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func setupAPIKeyHandler() (*handler.APIKeyHandler, *handler.UserHandler, *middleware.Auth) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
+	userRepo := user.NewInMemoryRepository()
+	apiKeyService := apiKeyUseCase.NewService(apiKeyRepo.NewInMemoryRepository(), apiKeyUseCase.WithOwnerRoles(userRepo))
+	userHandler := handler.NewUserHandler(userUseCase.NewService(userRepo, jwtService))
+	auth := middleware.NewAuth(jwtService, middleware.WithAPIKeys(apiKeyService))
+	return handler.NewAPIKeyHandler(apiKeyService), userHandler, auth
+}
+
+func TestAPIKeyHandler_CreateAndUse(t *testing.T) {
+	h, userHandler, auth := setupAPIKeyHandler()
+	login := loginTestUser(t, userHandler)
+
+	body, _ := json.Marshal(handler.CreateAPIKeyRequest{Label: "backend", Scopes: []string{apikey.ScopeProfileRead}})
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Keys)(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Keys() POST status = %v, want %v", w.Code, http.StatusCreated)
+	}
+	var created handler.CreateAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Secret == "" || created.PublicKey == "" {
+		t.Fatalf("Keys() POST = %+v, want a secret and public key", created)
+	}
+
+	protected := func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(middleware.APIKeyKey).(*apikey.APIKey); !ok {
+			t.Error("API key should be in the context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+	}{
+		{name: "granted scope", handler: auth.AuthenticateWithScope(apikey.ScopeProfileRead, protected), wantStatus: http.StatusOK},
+		{name: "missing scope", handler: auth.AuthenticateWithScope(apikey.ScopePaymentsWrite, protected), wantStatus: http.StatusForbidden},
+		{name: "JWT only route", handler: auth.Authenticate(protected), wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+created.Secret)
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	// Revoked keys stop working
+	req = httptest.NewRequest(http.MethodDelete, "/api/keys/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	auth.Authenticate(h.Key)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Key() DELETE status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Secret)
+	w = httptest.NewRecorder()
+	auth.AuthenticateWithScope(apikey.ScopeProfileRead, protected)(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAPIKeyHandler_Create_InvalidScope(t *testing.T) {
+	h, userHandler, auth := setupAPIKeyHandler()
+	login := loginTestUser(t, userHandler)
+
+	body, _ := json.Marshal(handler.CreateAPIKeyRequest{Label: "backend", Scopes: []string{"everything"}})
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Keys)(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Keys() POST status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
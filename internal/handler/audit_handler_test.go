@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	domainAudit "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	auditRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	auditUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/audit"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func setupAuditHandler() (*userUseCase.Service, *jwt.Service, http.Handler) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour)
+	auditService := auditUseCase.NewService(auditRepo.NewInMemoryRepository())
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithAuditLog(auditService),
+	)
+	h := handler.NewAuditHandler(auditService)
+	auth := middleware.NewAuth(jwtService, middleware.WithAuditLog(auditService))
+	requireAudit := auth.RequirePermission(domainUser.PermAuditRead)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/audit", auth.Authenticate(requireAudit(h.Events)))
+	mux.HandleFunc("/api/admin/audit/export", auth.Authenticate(requireAudit(h.Export)))
+	mux.HandleFunc("/api/admin/audit/verify", auth.Authenticate(requireAudit(h.Verify)))
+	return service, jwtService, middleware.RequestMeta(false)(mux)
+}
+
+func getAudit(t *testing.T, mux http.Handler, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuditHandler_Events(t *testing.T) {
+	service, jwtService, mux := setupAuditHandler()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "merchant", "merchant@example.com", "password123")
+	service.Login(ctx, "merchant@example.com", "wrong-password")
+	service.Login(ctx, "merchant@example.com", "password123")
+
+	w := getAudit(t, mux, "/api/admin/audit?user_id="+u.ID, adminToken(jwtService))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Events() status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	var events []domainAudit.Event
+	json.NewDecoder(w.Body).Decode(&events)
+	want := []domainAudit.EventType{
+		domainAudit.EventUserRegistered, domainAudit.EventLoginFailed, domainAudit.EventLoginSucceeded,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Events() returned %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Errorf("event %d type = %v, want %v", i, e.Type, want[i])
+		}
+	}
+	if events[1].Reason != domainAudit.ReasonInvalidPassword {
+		t.Errorf("login failure reason = %q, want %q", events[1].Reason, domainAudit.ReasonInvalidPassword)
+	}
+}
+
+func TestAuditHandler_Events_InvalidFilter(t *testing.T) {
+	_, jwtService, mux := setupAuditHandler()
+
+	for _, query := range []string{"from=yesterday", "to=2024-13-01", "limit=0", "limit=abc"} {
+		w := getAudit(t, mux, "/api/admin/audit?"+query, adminToken(jwtService))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Events(%s) status = %v, want %v", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAuditHandler_AccessDeniedIsAudited(t *testing.T) {
+	_, jwtService, mux := setupAuditHandler()
+
+	merchantToken, _ := jwtService.GenerateAccessToken(jwt.Claims{
+		UserID: "merchant-id", Role: string(domainUser.RoleMerchantOwner),
+	})
+	w := getAudit(t, mux, "/api/admin/audit", merchantToken)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Events() as merchant status = %v, want %v", w.Code, http.StatusForbidden)
+	}
+	requestID := w.Header().Get("X-Request-ID")
+
+	w = getAudit(t, mux, "/api/admin/audit?user_id=merchant-id", adminToken(jwtService))
+	var events []domainAudit.Event
+	json.NewDecoder(w.Body).Decode(&events)
+	if len(events) != 1 || events[0].Type != domainAudit.EventAccessDenied {
+		t.Fatalf("expected one access.denied event, got %+v", events)
+	}
+	e := events[0]
+	if e.ActorID != "merchant-id" || e.Details["permission"] != string(domainUser.PermAuditRead) {
+		t.Errorf("access.denied event = %+v", e)
+	}
+	if requestID == "" || e.RequestID != requestID {
+		t.Errorf("event request ID = %q, want response header %q", e.RequestID, requestID)
+	}
+}
+
+func TestAuditHandler_ExportAndVerify(t *testing.T) {
+	service, jwtService, mux := setupAuditHandler()
+	ctx := context.Background()
+
+	service.Register(ctx, "merchant", "merchant@example.com", "password123")
+	service.Login(ctx, "merchant@example.com", "password123")
+
+	w := getAudit(t, mux, "/api/admin/audit/export", adminToken(jwtService))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Export() Content-Type = %q", ct)
+	}
+
+	var events []*domainAudit.Event
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var e domainAudit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Export() line is not JSON: %v", err)
+		}
+		events = append(events, &e)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Export() wrote %d lines, want 2", len(events))
+	}
+	if err := domainAudit.VerifyChain(nil, events); err != nil {
+		t.Errorf("exported log should verify offline: %v", err)
+	}
+
+	w = getAudit(t, mux, "/api/admin/audit/verify", adminToken(jwtService))
+	var resp handler.AuditVerifyResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !resp.Valid || resp.Entries != 2 {
+		t.Errorf("Verify() = %+v, want valid with 2 entries", resp)
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+// JWKSHandler publishes the public keys used to verify issued tokens
+type JWKSHandler struct {
+	jwtService *jwt.Service
+}
+
+// NewJWKSHandler creates a new JWKS handler
+func NewJWKSHandler(jwtService *jwt.Service) *JWKSHandler {
+	return &JWKSHandler{
+		jwtService: jwtService,
+	}
+}
+
+// JWKS serves the JSON Web Key Set
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	sendJSON(w, h.jwtService.JWKS(), http.StatusOK)
+}
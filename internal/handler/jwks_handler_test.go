@@ -0,0 +1,50 @@
+package handler_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func TestJWKSHandler_JWKS(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signer, _ := jwt.NewECDSASigner(key)
+	h := handler.NewJWKSHandler(jwt.NewService(signer, time.Hour))
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	h.JWKS(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("JWKS() status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	var resp jwt.JWKS
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	if len(resp.Keys) != 1 || resp.Keys[0].Kid != signer.KeyID() {
+		t.Errorf("JWKS() keys = %+v, want one key with kid %s", resp.Keys, signer.KeyID())
+	}
+}
+
+func TestJWKSHandler_InvalidMethod(t *testing.T) {
+	h := handler.NewJWKSHandler(jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour))
+
+	req := httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	h.JWKS(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("JWKS() status = %v, want %v", w.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+)
+
+// The nonce cookie binds a magic link to the browser that requested it
+const (
+	magicLinkNonceCookie = "magic_link_nonce"
+	magicLinkCookiePath  = "/api/login/magic"
+)
+
+// MagicLinkRequest represents the magic link login request payload
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// RequestMagicLink emails a single-use login link and sets the cookie the
+// link must be opened with. The response is the same whether or not the
+// address belongs to an account.
+func (h *UserHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		h.sendError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	nonce, err := h.userUseCase.RequestMagicLink(r.Context(), req.Email)
+	if errors.Is(err, user.ErrMagicLinksDisabled) {
+		h.sendError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.sendError(w, "Failed to send login link", http.StatusInternalServerError)
+		return
+	}
+
+	setMagicLinkCookie(w, r, nonce, 0)
+	h.sendJSON(w, MessageResponse{Message: "If the address belongs to an account, a login link has been sent"}, http.StatusAccepted)
+}
+
+// ConfirmMagicLink logs in with the token from a magic link email. It
+// responds like Login, with tokens or an MFA challenge.
+func (h *UserHandler) ConfirmMagicLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.sendError(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+	var nonce string
+	if cookie, err := r.Cookie(magicLinkNonceCookie); err == nil {
+		nonce = cookie.Value
+	}
+
+	result, err := h.userUseCase.ConfirmMagicLink(r.Context(), token, nonce)
+	if h.sendLocked(w, err) {
+		return
+	}
+	if errors.Is(err, user.ErrMFARequired) || errors.Is(err, user.ErrEmailNotVerified) {
+		h.sendError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// The link is spent, so the nonce is no longer needed
+	setMagicLinkCookie(w, r, "", -1)
+	if result.MFAToken != "" {
+		h.sendJSON(w, LoginResponse{MFARequired: true, MFAToken: result.MFAToken}, http.StatusOK)
+		return
+	}
+	h.sendJSON(w, newLoginResponse(result.Tokens), http.StatusOK)
+}
+
+// setMagicLinkCookie sets the nonce cookie, or deletes it when maxAge is
+// negative. SameSite=Lax still sends it when the link is opened from an
+// email client.
+func setMagicLinkCookie(w http.ResponseWriter, r *http.Request, nonce string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkNonceCookie,
+		Value:    nonce,
+		Path:     magicLinkCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,111 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
+)
+
+func setupMagicLinkHandler() (*handler.UserHandler, *userUseCase.Service, *bytes.Buffer) {
+	var sent bytes.Buffer
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+		userUseCase.WithMailer(mail.NewLogMailer(log.New(&sent, "", 0), "no-reply@localhost"), "http://localhost:8080"),
+		userUseCase.WithMagicLinks(user.NewInMemoryMagicLinkRepository(), time.Minute),
+	)
+	return handler.NewUserHandler(service), service, &sent
+}
+
+func requestMagicLink(t *testing.T, h *handler.UserHandler, email string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, _ := json.Marshal(handler.MagicLinkRequest{Email: email})
+	w := httptest.NewRecorder()
+	h.RequestMagicLink(w, httptest.NewRequest(http.MethodPost, "/api/login/magic", bytes.NewReader(body)))
+	return w
+}
+
+// sentLink returns the login link from the mailed message
+func sentLink(t *testing.T, sent *bytes.Buffer) string {
+	t.Helper()
+
+	for _, field := range strings.Fields(sent.String()) {
+		if strings.HasPrefix(field, "http://localhost:8080/api/login/magic/confirm?") {
+			u, _ := url.Parse(field)
+			return u.RequestURI()
+		}
+	}
+	t.Fatalf("no login link was sent: %q", sent.String())
+	return ""
+}
+
+func TestUserHandler_MagicLink(t *testing.T) {
+	h, service, sent := setupMagicLinkHandler()
+	service.Register(context.Background(), "testuser", "test@example.com", "password123")
+
+	w := requestMagicLink(t, h, "test@example.com")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("RequestMagicLink() status = %v, want %v", w.Code, http.StatusAccepted)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("RequestMagicLink() cookies = %+v, want one HttpOnly SameSite=Lax nonce cookie", cookies)
+	}
+	link := sentLink(t, sent)
+
+	// Without the cookie, the link is refused
+	w = httptest.NewRecorder()
+	h.ConfirmMagicLink(w, httptest.NewRequest(http.MethodGet, link, nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("ConfirmMagicLink() without cookie status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, link, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ConfirmMagicLink(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ConfirmMagicLink() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var resp handler.LoginResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Errorf("ConfirmMagicLink() response = %+v, want tokens", resp)
+	}
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("ConfirmMagicLink() should clear the nonce cookie, got %+v", cleared)
+	}
+}
+
+func TestUserHandler_MagicLink_UnknownEmail(t *testing.T) {
+	h, _, sent := setupMagicLinkHandler()
+
+	if w := requestMagicLink(t, h, "unknown@example.com"); w.Code != http.StatusAccepted {
+		t.Errorf("RequestMagicLink() status = %v, want %v", w.Code, http.StatusAccepted)
+	}
+	if sent.Len() != 0 {
+		t.Error("no email should be sent for an unknown address")
+	}
+}
+
+func TestUserHandler_MagicLink_Disabled(t *testing.T) {
+	h := setupHandler()
+
+	if w := requestMagicLink(t, h, "test@example.com"); w.Code != http.StatusNotFound {
+		t.Errorf("RequestMagicLink() status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+)
+
+// MFALoginRequest represents the second step of a two-factor login
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// MFACodeRequest carries a TOTP or recovery code
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TOTPEnrollmentResponse represents a started TOTP enrollment
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse represents the recovery codes issued on confirmation
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Message       string   `json:"message"`
+}
+
+// VerifyMFA completes a two-factor login
+func (h *UserHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if req.MFAToken == "" || req.Code == "" {
+		h.sendError(w, "MFA token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	// Call use case
+	tokens, err := h.userUseCase.VerifyMFA(r.Context(), req.MFAToken, req.Code)
+	if h.sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	h.sendJSON(w, newLoginResponse(tokens), http.StatusOK)
+}
+
+// EnrollTOTP starts TOTP enrollment for the authenticated user
+func (h *UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	enrollment, err := h.userUseCase.EnrollTOTP(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, TOTPEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	}, http.StatusOK)
+}
+
+// ConfirmTOTP activates TOTP with a first code and returns recovery codes
+func (h *UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		h.sendError(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	codes, err := h.userUseCase.ConfirmTOTP(r.Context(), userID, req.Code)
+	if err != nil {
+		h.sendError(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Two-factor authentication enabled. Store these recovery codes safely; they will not be shown again.",
+	}, http.StatusOK)
+}
+
+// DisableTOTP turns off TOTP for the authenticated user
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		h.sendError(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	if err := h.userUseCase.DisableTOTP(r.Context(), userID, req.Code); err != nil {
+		h.sendError(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Two-factor authentication disabled"}, http.StatusOK)
+}
+
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, userUseCase.ErrInvalidMFACode):
+		return http.StatusBadRequest
+	case errors.Is(err, user.ErrMFAAlreadyEnabled),
+		errors.Is(err, user.ErrMFANotEnabled),
+		errors.Is(err, user.ErrNoPendingEnrollment),
+		errors.Is(err, user.ErrConcurrentModification):
+		return http.StatusConflict
+	case errors.Is(err, userUseCase.ErrMFARequired):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,99 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/totp"
+)
+
+func TestUserHandler_MFALoginFlow(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+
+	// Start enrollment
+	req := httptest.NewRequest(http.MethodPost, "/api/mfa/totp/enroll", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.EnrollTOTP)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("EnrollTOTP() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var enrollment handler.TOTPEnrollmentResponse
+	json.NewDecoder(w.Body).Decode(&enrollment)
+
+	// Confirm with a first code
+	code, _ := totp.Code(enrollment.Secret, time.Now())
+	body, _ := json.Marshal(handler.MFACodeRequest{Code: code})
+	req = httptest.NewRequest(http.MethodPost, "/api/mfa/totp/confirm", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	auth.Authenticate(h.ConfirmTOTP)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ConfirmTOTP() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var confirmed handler.RecoveryCodesResponse
+	json.NewDecoder(w.Body).Decode(&confirmed)
+	if len(confirmed.RecoveryCodes) == 0 {
+		t.Error("ConfirmTOTP() should return recovery codes")
+	}
+
+	// Password login now only yields an MFA challenge
+	body, _ = json.Marshal(handler.LoginRequest{Email: "test@example.com", Password: "password123"})
+	w = httptest.NewRecorder()
+	h.Login(w, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+
+	var challenge handler.LoginResponse
+	json.NewDecoder(w.Body).Decode(&challenge)
+	if !challenge.MFARequired || challenge.MFAToken == "" || challenge.Token != "" {
+		t.Fatalf("Login() = %+v, want an MFA challenge only", challenge)
+	}
+
+	// Exchange the challenge and a recovery code for tokens
+	body, _ = json.Marshal(handler.MFALoginRequest{MFAToken: challenge.MFAToken, Code: confirmed.RecoveryCodes[0]})
+	w = httptest.NewRecorder()
+	h.VerifyMFA(w, httptest.NewRequest(http.MethodPost, "/api/login/mfa", bytes.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("VerifyMFA() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var resp handler.LoginResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Token == "" {
+		t.Error("VerifyMFA() should return a token")
+	}
+}
+
+func TestUserHandler_VerifyMFA_InvalidToken(t *testing.T) {
+	h := setupHandler()
+
+	body, _ := json.Marshal(handler.MFALoginRequest{MFAToken: "bogus", Code: "123456"})
+	w := httptest.NewRecorder()
+	h.VerifyMFA(w, httptest.NewRequest(http.MethodPost, "/api/login/mfa", bytes.NewReader(body)))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("VerifyMFA() status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUserHandler_ConfirmTOTP_WithoutEnrollment(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+
+	body, _ := json.Marshal(handler.MFACodeRequest{Code: "123456"})
+	req := httptest.NewRequest(http.MethodPost, "/api/mfa/totp/confirm", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.ConfirmTOTP)(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("ConfirmTOTP() status = %v, want %v", w.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+)
+
+// ForgotPasswordRequest represents the password reset request payload
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the payload that sets a new password
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ForgotPassword emails a password reset token. The response is the same
+// whether or not the address belongs to an account.
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		h.sendError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userUseCase.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		h.sendError(w, "Failed to start password reset", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "If the address belongs to an account, a password reset email has been sent"}, http.StatusAccepted)
+}
+
+// ResetPassword sets a new password using a token from a reset email
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if req.Token == "" || req.NewPassword == "" {
+		h.sendError(w, "Token and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.userUseCase.ResetPassword(r.Context(), req.Token, req.NewPassword)
+	if h.sendPasswordPolicy(w, err) {
+		return
+	}
+	if errors.Is(err, user.ErrInvalidResetToken) {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domainUser.ErrConcurrentModification) || errors.Is(err, transaction.ErrConflict) {
+		h.sendError(w, "Too many concurrent changes, please retry", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.sendError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Password has been reset. Please log in again."}, http.StatusOK)
+}
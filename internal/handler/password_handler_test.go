@@ -0,0 +1,48 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+)
+
+func TestUserHandler_ForgotPassword_UnknownEmail(t *testing.T) {
+	h := setupHandler()
+
+	body, _ := json.Marshal(handler.ForgotPasswordRequest{Email: "unknown@example.com"})
+	w := httptest.NewRecorder()
+	h.ForgotPassword(w, httptest.NewRequest(http.MethodPost, "/api/password/forgot", bytes.NewReader(body)))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("ForgotPassword() status = %v, want %v", w.Code, http.StatusAccepted)
+	}
+}
+
+func TestUserHandler_ResetPassword(t *testing.T) {
+	h := setupHandler()
+
+	tests := []struct {
+		name       string
+		reqBody    handler.ResetPasswordRequest
+		wantStatus int
+	}{
+		{name: "missing fields", reqBody: handler.ResetPasswordRequest{Token: "abc"}, wantStatus: http.StatusBadRequest},
+		{name: "invalid token", reqBody: handler.ResetPasswordRequest{Token: "bogus", NewPassword: "newpassword456"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.reqBody)
+			w := httptest.NewRecorder()
+			h.ResetPassword(w, httptest.NewRequest(http.MethodPost, "/api/password/reset", bytes.NewReader(body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ResetPassword() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/privacy"
+)
+
+// PrivacyHandler handles data subject requests of the authenticated user
+type PrivacyHandler struct {
+	privacyUseCase privacy.UseCase
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(privacyUseCase privacy.UseCase) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyUseCase: privacyUseCase,
+	}
+}
+
+// Export downloads everything stored about the authenticated user, as JSON
+// or, with ?format=zip, as a ZIP archive
+func (h *PrivacyHandler) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		sendError(w, "format must be json or zip", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	archive, err := h.privacyUseCase.Export(r.Context(), userID)
+	if errors.Is(err, privacy.ErrUserNotFound) {
+		sendError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	write := archive.WriteJSON
+	if format == "zip" {
+		write = archive.WriteZIP
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="account-export.zip"`)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="account-export.json"`)
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := write(w); err != nil {
+		log.Printf("Failed to write account export: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	apiKeyRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/apikey"
+	auditRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/privacy"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func setupPrivacyHandler(t *testing.T) (http.HandlerFunc, string) {
+	t.Helper()
+
+	repo := user.NewInMemoryRepository()
+	sessions := user.NewInMemorySessionRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour)
+	service := userUseCase.NewService(repo, jwtService, userUseCase.WithSessions(sessions, time.Hour))
+	privacyService := privacy.NewService(repo, sessions, apiKeyRepo.NewInMemoryRepository(), auditRepo.NewInMemoryRepository())
+
+	ctx := context.Background()
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	result, err := service.Login(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+
+	auth := middleware.NewAuth(jwtService)
+	return auth.Authenticate(handler.NewPrivacyHandler(privacyService).Export), result.Tokens.AccessToken
+}
+
+func TestPrivacyHandler_Export(t *testing.T) {
+	export, token := setupPrivacyHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	export(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var archive privacy.Archive
+	if err := json.NewDecoder(w.Body).Decode(&archive); err != nil {
+		t.Fatalf("Export() body is not JSON: %v", err)
+	}
+	if archive.Profile.Email != "test@example.com" || len(archive.Sessions) != 1 {
+		t.Errorf("Export() = %+v", archive)
+	}
+}
+
+func TestPrivacyHandler_Export_ZIP(t *testing.T) {
+	export, token := setupPrivacyHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me/export?format=zip", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	export(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Export() status = %v, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Export() body is not a ZIP archive: %v", err)
+	}
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, name := range []string{"profile.json", "sessions.json", "api_keys.json", "audit_events.json"} {
+		if !names[name] {
+			t.Errorf("Export() archive is missing %s", name)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/me/export?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	export(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Export() with an unknown format status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
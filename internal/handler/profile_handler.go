@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+)
+
+// ProfileResponse represents the account of the authenticated user
+type ProfileResponse struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	MFAEnabled    bool      `json:"mfa_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// UpdateProfileRequest represents the profile update payload. Omitted
+// fields are left unchanged.
+type UpdateProfileRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+}
+
+// DeleteAccountRequest confirms account deletion with the password
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccountResponse tells when a scheduled deletion becomes final
+type DeleteAccountResponse struct {
+	Message       string    `json:"message"`
+	DeletionDueAt time.Time `json:"deletion_due_at"`
+}
+
+// ChangePasswordRequest represents the password change payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Profile returns (GET), updates (PATCH) or deletes (DELETE) the account of
+// the authenticated user. The profile carries its version as an ETag; a
+// PATCH with If-Match only applies to that version, and answers 409 if the
+// profile has changed since.
+func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getProfile(w, r)
+	case http.MethodPatch:
+		h.updateProfile(w, r)
+	case http.MethodDelete:
+		h.deleteAccount(w, r)
+	default:
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *UserHandler) getProfile(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	u, err := h.userUseCase.GetProfile(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err.Error(), profileErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(u.Version))
+	h.sendJSON(w, newProfileResponse(u), http.StatusOK)
+}
+
+func (h *UserHandler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	version, ok := parseIfMatch(r.Header.Get("If-Match"))
+	if !ok {
+		h.sendError(w, "Invalid If-Match header", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	u, err := h.userUseCase.UpdateProfile(r.Context(), userID, userUseCase.ProfileUpdate{
+		Username: req.Username,
+		Email:    req.Email,
+		Version:  version,
+	})
+	if err != nil {
+		h.sendError(w, err.Error(), profileErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(u.Version))
+	h.sendJSON(w, newProfileResponse(u), http.StatusOK)
+}
+
+// deleteAccount schedules the account for deletion and signs it out
+func (h *UserHandler) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		h.sendError(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	dueAt, err := h.userUseCase.DeleteAccount(r.Context(), userID, req.Password)
+	if err != nil {
+		h.sendError(w, err.Error(), profileErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, DeleteAccountResponse{
+		Message:       "Account scheduled for deletion. Log in before the deletion date to cancel it.",
+		DeletionDueAt: dueAt,
+	}, http.StatusOK)
+}
+
+// ChangePassword replaces the password of the authenticated user. Every
+// session is signed out, so the client has to log in again.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		h.sendError(w, "Current and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	if err := h.userUseCase.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
+		if h.sendPasswordPolicy(w, err) {
+			return
+		}
+		h.sendError(w, err.Error(), profileErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Password changed. Please log in again."}, http.StatusOK)
+}
+
+func newProfileResponse(u *user.User) ProfileResponse {
+	return ProfileResponse{
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		MFAEnabled:    u.MFAEnabled(),
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+func profileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, user.ErrEmptyUsername),
+		errors.Is(err, user.ErrInvalidEmail),
+		errors.Is(err, user.ErrDisposableEmail):
+		return http.StatusBadRequest
+	case errors.Is(err, userUseCase.ErrIncorrectPassword):
+		return http.StatusForbidden
+	case errors.Is(err, userUseCase.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, userUseCase.ErrEmailTaken),
+		errors.Is(err, user.ErrConcurrentModification),
+		errors.Is(err, transaction.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// versionETag formats an entity version as a strong ETag
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch returns the version in an If-Match header holding one ETag
+// from versionETag, or 0 when the header is empty or "*"
+func parseIfMatch(header string) (int64, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	unquoted, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return 0, false
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,187 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+)
+
+func TestUserHandler_Profile(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Profile)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Profile() GET status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var profile handler.ProfileResponse
+	json.NewDecoder(w.Body).Decode(&profile)
+	if profile.Email != "test@example.com" || profile.Username != "testuser" {
+		t.Errorf("Profile() GET = %+v", profile)
+	}
+
+	username := "renamed"
+	body, _ := json.Marshal(handler.UpdateProfileRequest{Username: &username})
+	req = httptest.NewRequest(http.MethodPatch, "/api/me", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	auth.Authenticate(h.Profile)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Profile() PATCH status = %v, want %v", w.Code, http.StatusOK)
+	}
+	json.NewDecoder(w.Body).Decode(&profile)
+	if profile.Username != username {
+		t.Errorf("Profile() PATCH username = %v, want %v", profile.Username, username)
+	}
+}
+
+func TestUserHandler_Profile_EmailTaken(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+
+	regData, _ := json.Marshal(handler.RegisterRequest{Username: "other", Email: "other@example.com", Password: "password123"})
+	h.Register(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(regData)))
+	login := loginTestUser(t, h)
+
+	email := "other@example.com"
+	body, _ := json.Marshal(handler.UpdateProfileRequest{Email: &email})
+	req := httptest.NewRequest(http.MethodPatch, "/api/me", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Profile)(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Profile() PATCH status = %v, want %v", w.Code, http.StatusConflict)
+	}
+}
+
+func TestUserHandler_Profile_IfMatch(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+
+	patch := func(ifMatch, username string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(handler.UpdateProfileRequest{Username: &username})
+		req := httptest.NewRequest(http.MethodPatch, "/api/me", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+login.Token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		auth.Authenticate(h.Profile)(w, req)
+		return w
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Profile)(w, req)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Profile() GET should set an ETag")
+	}
+
+	w = patch(etag, "first")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Profile() PATCH with the current ETag status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if next := w.Header().Get("ETag"); next == "" || next == etag {
+		t.Errorf("Profile() PATCH ETag = %q, want a new one", next)
+	}
+
+	// Another client still holding the old ETag
+	if w := patch(etag, "second"); w.Code != http.StatusConflict {
+		t.Errorf("Profile() PATCH with a stale ETag status = %v, want %v", w.Code, http.StatusConflict)
+	}
+
+	for _, header := range []string{"first", `W/"1"`, `"x"`} {
+		if w := patch(header, "third"); w.Code != http.StatusBadRequest {
+			t.Errorf("Profile() PATCH with If-Match %s status = %v, want %v", header, w.Code, http.StatusBadRequest)
+		}
+	}
+	if w := patch("*", "third"); w.Code != http.StatusOK {
+		t.Errorf("Profile() PATCH with If-Match * status = %v, want %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+
+	tests := []struct {
+		name       string
+		reqBody    handler.ChangePasswordRequest
+		wantStatus int
+	}{
+		{name: "missing fields", reqBody: handler.ChangePasswordRequest{NewPassword: "newpassword456"}, wantStatus: http.StatusBadRequest},
+		{name: "wrong current password", reqBody: handler.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "newpassword456"}, wantStatus: http.StatusForbidden},
+		{name: "success", reqBody: handler.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword456"}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/me/password", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+login.Token)
+			w := httptest.NewRecorder()
+			auth.Authenticate(h.ChangePassword)(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ChangePassword() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestUserHandler_DeleteAccount(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+
+	tests := []struct {
+		name       string
+		reqBody    handler.DeleteAccountRequest
+		wantStatus int
+	}{
+		{name: "missing password", reqBody: handler.DeleteAccountRequest{}, wantStatus: http.StatusBadRequest},
+		{name: "wrong password", reqBody: handler.DeleteAccountRequest{Password: "wrong"}, wantStatus: http.StatusForbidden},
+		{name: "success", reqBody: handler.DeleteAccountRequest{Password: "password123"}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.reqBody)
+			req := httptest.NewRequest(http.MethodDelete, "/api/me", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+login.Token)
+			w := httptest.NewRecorder()
+			auth.Authenticate(h.Profile)(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Profile() DELETE status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp handler.DeleteAccountResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.DeletionDueAt.IsZero() {
+				t.Error("Profile() DELETE should return when the deletion becomes final")
+			}
+		})
+	}
+
+	// The account is signed out everywhere
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Profile)(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Profile() after deletion status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
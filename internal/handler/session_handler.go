@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+// SessionResponse describes a signed-in device
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// Sessions lists the active sessions of the authenticated user
+func (h *UserHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	claims, _ := r.Context().Value(middleware.ClaimsKey).(*jwt.Claims)
+
+	sessions, err := h.userUseCase.ListSessions(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, SessionResponse{
+			ID:         s.ID,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    claims != nil && claims.SessionID == s.ID,
+		})
+	}
+	h.sendJSON(w, resp, http.StatusOK)
+}
+
+// Session signs out (DELETE) one of the authenticated user's sessions
+func (h *UserHandler) Session(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	err := h.userUseCase.RevokeSession(r.Context(), userID, r.PathValue("id"))
+	if errors.Is(err, userUseCase.ErrSessionNotFound) {
+		h.sendError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.sendError(w, "Failed to sign out session", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Session signed out"}, http.StatusOK)
+}
@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func setupSessionHandler() (*handler.UserHandler, *middleware.Auth) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+		userUseCase.WithSessions(user.NewInMemorySessionRepository(), time.Hour),
+	)
+	return handler.NewUserHandler(service), middleware.NewAuth(jwtService, middleware.WithSessions(service))
+}
+
+func listSessions(t *testing.T, h *handler.UserHandler, auth *middleware.Auth, token string) []handler.SessionResponse {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Sessions)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Sessions() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var sessions []handler.SessionResponse
+	json.NewDecoder(w.Body).Decode(&sessions)
+	return sessions
+}
+
+func TestUserHandler_Sessions(t *testing.T) {
+	h, auth := setupSessionHandler()
+	laptop := loginTestUser(t, h)
+	phone := loginTestUser(t, h)
+
+	sessions := listSessions(t, h, auth, laptop.Token)
+	if len(sessions) != 2 {
+		t.Fatalf("Sessions() returned %d sessions, want 2", len(sessions))
+	}
+
+	var current, other string
+	for _, s := range sessions {
+		if s.Current {
+			current = s.ID
+		} else {
+			other = s.ID
+		}
+	}
+	if current == "" || other == "" {
+		t.Fatalf("Sessions() should flag exactly the calling session as current: %+v", sessions)
+	}
+
+	// Sign out the phone from the laptop
+	req := httptest.NewRequest(http.MethodDelete, "/api/me/sessions/"+other, nil)
+	req.SetPathValue("id", other)
+	req.Header.Set("Authorization", "Bearer "+laptop.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Session)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Session() DELETE status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	// The phone's access token stops working right away
+	req = httptest.NewRequest(http.MethodGet, "/api/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+phone.Token)
+	w = httptest.NewRecorder()
+	auth.Authenticate(h.Sessions)(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("signed out session status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+
+	if sessions := listSessions(t, h, auth, laptop.Token); len(sessions) != 1 || sessions[0].ID != current {
+		t.Errorf("Sessions() after sign out = %+v, want only %s", sessions, current)
+	}
+}
+
+func TestUserHandler_Session_NotFound(t *testing.T) {
+	h, auth := setupSessionHandler()
+	login := loginTestUser(t, h)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/me/sessions/missing", nil)
+	req.SetPathValue("id", "missing")
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	auth.Authenticate(h.Session)(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Session() DELETE status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
@@ -2,9 +2,18 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
 )
 
 // UserHandler handles user-related HTTP requests
@@ -40,9 +49,29 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// LoginResponse represents the login response
+// LoginResponse represents the login and token refresh response. When the
+// account has a second factor only MFARequired and MFAToken are set.
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+}
+
+// RefreshRequest represents the token refresh request payload
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutRequest represents the optional logout request payload
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// MessageResponse represents a plain acknowledgement response
+type MessageResponse struct {
+	Message string `json:"message"`
 }
 
 // ErrorResponse represents an error response
@@ -50,6 +79,13 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// PasswordPolicyResponse lists every password rule a request failed
+type PasswordPolicyResponse struct {
+	Error      string               `json:"error"`
+	Code       string               `json:"code"`
+	Violations []password.Violation `json:"violations"`
+}
+
 // Register handles user registration
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -71,6 +107,13 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	// Call use case
 	newUser, err := h.userUseCase.Register(r.Context(), req.Username, req.Email, req.Password)
+	if h.sendPasswordPolicy(w, err) {
+		return
+	}
+	if errors.Is(err, transaction.ErrConflict) {
+		h.sendError(w, "Too many concurrent changes, please retry", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		h.sendError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -106,26 +149,158 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call use case
-	token, err := h.userUseCase.Login(r.Context(), req.Email, req.Password)
+	result, err := h.userUseCase.Login(r.Context(), req.Email, req.Password)
+	if h.sendLocked(w, err) {
+		return
+	}
+	if errors.Is(err, user.ErrMFARequired) || errors.Is(err, user.ErrEmailNotVerified) {
+		h.sendError(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		h.sendError(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
 	// Send response
-	resp := LoginResponse{
-		Token: token,
+	if result.MFAToken != "" {
+		h.sendJSON(w, LoginResponse{MFARequired: true, MFAToken: result.MFAToken}, http.StatusOK)
+		return
+	}
+	h.sendJSON(w, newLoginResponse(result.Tokens), http.StatusOK)
+}
+
+// Refresh exchanges a refresh token for a new token pair
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	h.sendJSON(w, resp, http.StatusOK)
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if req.RefreshToken == "" {
+		h.sendError(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	// Call use case
+	tokens, err := h.userUseCase.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// Send response
+	h.sendJSON(w, newLoginResponse(tokens), http.StatusOK)
+}
+
+// Logout revokes the current access token and, if provided, its refresh token
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The body is optional, so an empty one is fine
+	var req LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, _ := r.Context().Value(middleware.ClaimsKey).(*jwt.Claims)
+	if claims == nil {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.userUseCase.Logout(r.Context(), claims, req.RefreshToken); err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Logged out"}, http.StatusOK)
+}
+
+// LogoutAll revokes every token issued to the current user
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if userID == "" {
+		h.sendError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.userUseCase.LogoutAll(r.Context(), userID); err != nil {
+		h.sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Logged out from all sessions"}, http.StatusOK)
+}
+
+func newLoginResponse(tokens *user.Tokens) LoginResponse {
+	return LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
+}
+
+// sendLocked answers 429 with a Retry-After header when err is a lockout,
+// reporting whether it did
+func (h *UserHandler) sendLocked(w http.ResponseWriter, err error) bool {
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	h.sendError(w, err.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+// sendPasswordPolicy answers 400 with the failed rules when err is a
+// password policy violation, reporting whether it did
+func (h *UserHandler) sendPasswordPolicy(w http.ResponseWriter, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	h.sendJSON(w, PasswordPolicyResponse{
+		Error:      password.ErrPolicyViolation.Error(),
+		Code:       "password_policy",
+		Violations: policyErr.Violations,
+	}, http.StatusBadRequest)
+	return true
 }
 
 // Helper methods
 func (h *UserHandler) sendJSON(w http.ResponseWriter, data interface{}, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	sendJSON(w, data, status)
 }
 
 func (h *UserHandler) sendError(w http.ResponseWriter, message string, status int) {
 	h.sendJSON(w, ErrorResponse{Error: message}, status)
 }
+
+func sendError(w http.ResponseWriter, message string, status int) {
+	sendJSON(w, ErrorResponse{Error: message}, status)
+}
+
+func sendJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
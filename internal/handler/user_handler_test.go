@@ -2,23 +2,37 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
 	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
 	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
 )
 
 func setupHandler() *handler.UserHandler {
+	h, _ := setupHandlerWithAuth()
+	return h
+}
+
+func setupHandlerWithAuth() (*handler.UserHandler, *middleware.Auth) {
 	repo := user.NewInMemoryRepository()
-	jwtService := jwt.NewService("test-secret", 24*time.Hour)
-	service := userUseCase.NewService(repo, jwtService)
-	return handler.NewUserHandler(service)
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(repo, jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+	)
+	return handler.NewUserHandler(service), middleware.NewAuth(jwtService)
 }
 
 func TestUserHandler_Register_Success(t *testing.T) {
@@ -125,6 +139,10 @@ func TestUserHandler_Login_Success(t *testing.T) {
 	if resp.Token == "" {
 		t.Error("Login() should return a token")
 	}
+
+	if resp.RefreshToken == "" {
+		t.Error("Login() should return a refresh token")
+	}
 }
 
 func TestUserHandler_Login_InvalidCredentials(t *testing.T) {
@@ -164,3 +182,220 @@ func TestUserHandler_Login_MissingFields(t *testing.T) {
 		t.Errorf("Login() status = %v, want %v", w.Code, http.StatusBadRequest)
 	}
 }
+
+func loginTestUser(t *testing.T, h *handler.UserHandler) handler.LoginResponse {
+	t.Helper()
+
+	regData, _ := json.Marshal(handler.RegisterRequest{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	h.Register(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(regData)))
+
+	loginData, _ := json.Marshal(handler.LoginRequest{
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	w := httptest.NewRecorder()
+	h.Login(w, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(loginData)))
+
+	var resp handler.LoginResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp
+}
+
+func TestUserHandler_Refresh_Success(t *testing.T) {
+	h := setupHandler()
+	login := loginTestUser(t, h)
+
+	body, _ := json.Marshal(handler.RefreshRequest{RefreshToken: login.RefreshToken})
+	req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.Refresh(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Refresh() status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	var resp handler.LoginResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	if resp.Token == "" {
+		t.Error("Refresh() should return a token")
+	}
+
+	if resp.RefreshToken == "" || resp.RefreshToken == login.RefreshToken {
+		t.Error("Refresh() should return a rotated refresh token")
+	}
+}
+
+func TestUserHandler_Refresh_InvalidToken(t *testing.T) {
+	h := setupHandler()
+
+	body, _ := json.Marshal(handler.RefreshRequest{RefreshToken: "bogus"})
+	req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.Refresh(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Refresh() status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUserHandler_Refresh_MissingToken(t *testing.T) {
+	h := setupHandler()
+
+	body, _ := json.Marshal(handler.RefreshRequest{})
+	req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.Refresh(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Refresh() status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUserHandler_Logout_RevokesToken(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+	protected := auth.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+
+	auth.Authenticate(h.Logout)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Logout() status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+
+	protected(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("protected route after Logout() status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUserHandler_LogoutAll_RevokesRefreshTokens(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+	login := loginTestUser(t, h)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/logout-all", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+
+	auth.Authenticate(h.LogoutAll)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("LogoutAll() status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	body, _ := json.Marshal(handler.RefreshRequest{RefreshToken: login.RefreshToken})
+	w = httptest.NewRecorder()
+	h.Refresh(w, httptest.NewRequest(http.MethodPost, "/api/token/refresh", bytes.NewReader(body)))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Refresh() after LogoutAll() status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUserHandler_Logout_Unauthenticated(t *testing.T) {
+	h, auth := setupHandlerWithAuth()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	w := httptest.NewRecorder()
+
+	auth.Authenticate(h.Logout)(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Logout() status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUserHandler_Login_Locked(t *testing.T) {
+	tracker := lockout.NewTracker(lockout.NewMemoryStore(), lockout.Policy{MaxAttempts: 1, LockoutDuration: time.Minute, Window: time.Minute})
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
+	h := handler.NewUserHandler(userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithLoginThrottle(tracker, nil),
+	))
+
+	body, _ := json.Marshal(handler.LoginRequest{Email: "test@example.com", Password: "wrongpassword"})
+	h.Login(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+
+	w := httptest.NewRecorder()
+	h.Login(w, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Login() status = %v, want %v", w.Code, http.StatusTooManyRequests)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Login() Retry-After = %q, want %q", retryAfter, "60")
+	}
+}
+
+func TestUserHandler_Register_PasswordPolicy(t *testing.T) {
+	service := userUseCase.NewService(user.NewInMemoryRepository(),
+		jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour),
+		userUseCase.WithPasswordPolicy(password.DefaultPolicy()),
+	)
+	h := handler.NewUserHandler(service)
+
+	body, _ := json.Marshal(handler.RegisterRequest{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "a",
+	})
+	w := httptest.NewRecorder()
+	h.Register(w, httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Register() status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	var resp handler.PasswordPolicyResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != "password_policy" {
+		t.Errorf("Register() code = %q, want password_policy", resp.Code)
+	}
+	if len(resp.Violations) != 1 || resp.Violations[0].Rule != password.RuleMinLength {
+		t.Errorf("Register() violations = %v, want a single %q", resp.Violations, password.RuleMinLength)
+	}
+}
+
+// conflictingManager fails every transaction as if it had raced another one
+type conflictingManager struct{}
+
+func (conflictingManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return transaction.ErrConflict
+}
+
+func TestUserHandler_Register_Conflict(t *testing.T) {
+	service := userUseCase.NewService(user.NewInMemoryRepository(),
+		jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour),
+		userUseCase.WithTransactions(conflictingManager{}),
+	)
+	h := handler.NewUserHandler(service)
+
+	body, _ := json.Marshal(handler.RegisterRequest{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	w := httptest.NewRecorder()
+	h.Register(w, httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body)))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Register() status = %v, want %v", w.Code, http.StatusConflict)
+	}
+}
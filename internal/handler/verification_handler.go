@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ResendVerificationRequest represents the resend verification payload
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// VerifyEmail confirms an email address using the token from the verification link
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.sendError(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userUseCase.VerifyEmail(r.Context(), token); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Email address verified"}, http.StatusOK)
+}
+
+// ResendVerification sends a new verification email. The response is the
+// same whether or not the address belongs to an account.
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		h.sendError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userUseCase.ResendVerification(r.Context(), req.Email); err != nil {
+		h.sendError(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "If the address belongs to an unverified account, a verification email has been sent"}, http.StatusAccepted)
+}
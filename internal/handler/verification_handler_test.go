@@ -0,0 +1,47 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+)
+
+func TestUserHandler_VerifyEmail_InvalidToken(t *testing.T) {
+	h := setupHandler()
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{name: "missing token", target: "/api/verify-email", wantStatus: http.StatusBadRequest},
+		{name: "invalid token", target: "/api/verify-email?token=bogus", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.VerifyEmail(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("VerifyEmail() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestUserHandler_ResendVerification_UnknownEmail(t *testing.T) {
+	h := setupHandler()
+
+	body, _ := json.Marshal(handler.ResendVerificationRequest{Email: "unknown@example.com"})
+	w := httptest.NewRecorder()
+	h.ResendVerification(w, httptest.NewRequest(http.MethodPost, "/api/verify-email/resend", bytes.NewReader(body)))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("ResendVerification() status = %v, want %v", w.Code, http.StatusAccepted)
+	}
+}
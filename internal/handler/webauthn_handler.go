@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
+)
+
+// WebAuthnRegistrationOptionsResponse starts a passkey registration. Options
+// is passed as the publicKey member to navigator.credentials.create.
+type WebAuthnRegistrationOptionsResponse struct {
+	Options       *webauthn.CreationOptions `json:"options"`
+	CeremonyToken string                    `json:"ceremony_token"`
+}
+
+// WebAuthnRegistrationRequest finishes a passkey registration
+type WebAuthnRegistrationRequest struct {
+	CeremonyToken string                         `json:"ceremony_token"`
+	Name          string                         `json:"name"`
+	Credential    *webauthn.RegistrationResponse `json:"credential"`
+}
+
+// WebAuthnLoginBeginRequest starts a passkey login. The MFA token from a
+// password login makes the passkey the second factor.
+type WebAuthnLoginBeginRequest struct {
+	MFAToken string `json:"mfa_token,omitempty"`
+}
+
+// WebAuthnLoginOptionsResponse starts a passkey login. Options is passed as
+// the publicKey member to navigator.credentials.get.
+type WebAuthnLoginOptionsResponse struct {
+	Options       *webauthn.RequestOptions `json:"options"`
+	CeremonyToken string                   `json:"ceremony_token"`
+}
+
+// WebAuthnLoginRequest finishes a passkey login
+type WebAuthnLoginRequest struct {
+	CeremonyToken string                      `json:"ceremony_token"`
+	Credential    *webauthn.AssertionResponse `json:"credential"`
+}
+
+// WebAuthnCredentialResponse describes a registered passkey
+type WebAuthnCredentialResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// BeginWebAuthnRegistration starts registering a passkey for the
+// authenticated user
+func (h *UserHandler) BeginWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	registration, err := h.userUseCase.BeginWebAuthnRegistration(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err.Error(), webAuthnErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, WebAuthnRegistrationOptionsResponse{
+		Options:       registration.Options,
+		CeremonyToken: registration.CeremonyToken,
+	}, http.StatusOK)
+}
+
+// FinishWebAuthnRegistration stores the passkey created by the browser
+func (h *UserHandler) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req WebAuthnRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.CeremonyToken == "" || req.Credential == nil {
+		h.sendError(w, "Ceremony token and credential are required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	credential, err := h.userUseCase.FinishWebAuthnRegistration(r.Context(), userID, req.CeremonyToken, req.Name, req.Credential)
+	if errors.Is(err, userUseCase.ErrInvalidPasskey) {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.sendError(w, err.Error(), webAuthnErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, WebAuthnCredentialResponse{
+		ID:        credential.ID,
+		Name:      credential.Name,
+		CreatedAt: credential.CreatedAt,
+	}, http.StatusCreated)
+}
+
+// WebAuthnCredentials lists the authenticated user's passkeys
+func (h *UserHandler) WebAuthnCredentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	credentials, err := h.userUseCase.ListWebAuthnCredentials(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err.Error(), webAuthnErrorStatus(err))
+		return
+	}
+
+	resp := make([]WebAuthnCredentialResponse, 0, len(credentials))
+	for _, c := range credentials {
+		resp = append(resp, WebAuthnCredentialResponse{
+			ID:         c.ID,
+			Name:       c.Name,
+			CreatedAt:  c.CreatedAt,
+			LastUsedAt: c.LastUsedAt,
+		})
+	}
+	h.sendJSON(w, resp, http.StatusOK)
+}
+
+// WebAuthnCredential removes (DELETE) one of the authenticated user's passkeys
+func (h *UserHandler) WebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	if err := h.userUseCase.DeleteWebAuthnCredential(r.Context(), userID, r.PathValue("id")); err != nil {
+		h.sendError(w, err.Error(), webAuthnErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, MessageResponse{Message: "Passkey removed"}, http.StatusOK)
+}
+
+// BeginWebAuthnLogin starts a passwordless passkey login, or the passkey
+// step of a two-factor login when an MFA token is given
+func (h *UserHandler) BeginWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req WebAuthnLoginBeginRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	login, err := h.userUseCase.BeginWebAuthnLogin(r.Context(), req.MFAToken)
+	if err != nil {
+		h.sendError(w, err.Error(), webAuthnErrorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, WebAuthnLoginOptionsResponse{
+		Options:       login.Options,
+		CeremonyToken: login.CeremonyToken,
+	}, http.StatusOK)
+}
+
+// FinishWebAuthnLogin signs in with a passkey assertion. It responds like
+// Login.
+func (h *UserHandler) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req WebAuthnLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.CeremonyToken == "" || req.Credential == nil {
+		h.sendError(w, "Ceremony token and credential are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.userUseCase.FinishWebAuthnLogin(r.Context(), req.CeremonyToken, req.Credential)
+	if h.sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		h.sendError(w, err.Error(), webAuthnErrorStatus(err))
+		return
+	}
+
+	if result.MFAToken != "" {
+		h.sendJSON(w, LoginResponse{MFARequired: true, MFAToken: result.MFAToken}, http.StatusOK)
+		return
+	}
+	h.sendJSON(w, newLoginResponse(result.Tokens), http.StatusOK)
+}
+
+func webAuthnErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, userUseCase.ErrWebAuthnDisabled),
+		errors.Is(err, userUseCase.ErrPasskeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, userUseCase.ErrInvalidPasskey),
+		errors.Is(err, userUseCase.ErrInvalidWebAuthnCeremony),
+		errors.Is(err, userUseCase.ErrInvalidMFAToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, userUseCase.ErrMFARequired),
+		errors.Is(err, userUseCase.ErrEmailNotVerified):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/handler"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn/webauthntest"
+)
+
+func setupWebAuthnHandler() (*handler.UserHandler, *middleware.Auth) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	rp := webauthn.NewRelyingParty("localhost", "Test", []string{"http://localhost:8080"}, time.Minute)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithWebAuthn(rp, user.NewInMemoryWebAuthnCredentialRepository()),
+	)
+	return handler.NewUserHandler(service), middleware.NewAuth(jwtService)
+}
+
+// postJSON calls a handler with body encoded as JSON and an optional token
+func postJSON(h http.HandlerFunc, path, token string, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+func TestUserHandler_WebAuthnFlow(t *testing.T) {
+	h, auth := setupWebAuthnHandler()
+	login := loginTestUser(t, h)
+	authenticator := webauthntest.NewAuthenticator("http://localhost:8080")
+
+	// Register a passkey
+	w := postJSON(auth.Authenticate(h.BeginWebAuthnRegistration), "/api/webauthn/register/begin", login.Token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("BeginWebAuthnRegistration() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var registration handler.WebAuthnRegistrationOptionsResponse
+	json.NewDecoder(w.Body).Decode(&registration)
+
+	created, err := authenticator.Create(registration.Options)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	w = postJSON(auth.Authenticate(h.FinishWebAuthnRegistration), "/api/webauthn/register/finish", login.Token, handler.WebAuthnRegistrationRequest{
+		CeremonyToken: registration.CeremonyToken,
+		Name:          "Laptop",
+		Credential:    created,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("FinishWebAuthnRegistration() status = %v, want %v: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/webauthn/credentials", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	auth.Authenticate(h.WebAuthnCredentials)(w, req)
+	var credentials []handler.WebAuthnCredentialResponse
+	json.NewDecoder(w.Body).Decode(&credentials)
+	if len(credentials) != 1 || credentials[0].Name != "Laptop" {
+		t.Fatalf("WebAuthnCredentials() = %+v, want the new passkey", credentials)
+	}
+
+	// Sign in without a password
+	req = httptest.NewRequest(http.MethodPost, "/api/login/webauthn/begin", nil)
+	w = httptest.NewRecorder()
+	h.BeginWebAuthnLogin(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("BeginWebAuthnLogin() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	var options handler.WebAuthnLoginOptionsResponse
+	json.NewDecoder(w.Body).Decode(&options)
+
+	assertion, err := authenticator.Get(options.Options)
+	if err != nil {
+		t.Fatalf("Get() unexpected error = %v", err)
+	}
+	w = postJSON(h.FinishWebAuthnLogin, "/api/login/webauthn/finish", "", handler.WebAuthnLoginRequest{
+		CeremonyToken: options.CeremonyToken,
+		Credential:    assertion,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("FinishWebAuthnLogin() status = %v, want %v: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp handler.LoginResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Token == "" {
+		t.Error("FinishWebAuthnLogin() should return a token")
+	}
+
+	// The ceremony cannot be replayed
+	w = postJSON(h.FinishWebAuthnLogin, "/api/login/webauthn/finish", "", handler.WebAuthnLoginRequest{
+		CeremonyToken: options.CeremonyToken,
+		Credential:    assertion,
+	})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("FinishWebAuthnLogin() replay status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUserHandler_WebAuthn_Disabled(t *testing.T) {
+	h := setupHandler()
+
+	w := httptest.NewRecorder()
+	h.BeginWebAuthnLogin(w, httptest.NewRequest(http.MethodPost, "/api/login/webauthn/begin", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("BeginWebAuthnLogin() status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
@@ -4,29 +4,108 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
 	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	// UserIDKey holds the effective user, the one being impersonated
+	// during an impersonation
+	UserIDKey contextKey = "user_id"
+	// RealUserIDKey holds the user actually making the request. It equals
+	// the UserIDKey value unless impersonating.
+	RealUserIDKey contextKey = "real_user_id"
+	ClaimsKey     contextKey = "claims"
+	APIKeyKey     contextKey = "api_key"
+)
+
+// apiKeyPrefix starts every API key secret, telling it apart from a JWT
+const apiKeyPrefix = "sk_"
+
+// APIKeyAuthenticator resolves an API key secret to its active key
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (*apikey.APIKey, error)
+}
 
-// Auth is a middleware that validates JWT tokens
+// SessionValidator reports whether the login session a JWT was issued for
+// is still active
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, userID, sessionID string) error
+}
+
+// Auth is a middleware that validates JWT tokens and API keys
 type Auth struct {
-	jwtService *jwt.Service
+	jwtService            *jwt.Service
+	apiKeys               APIKeyAuthenticator
+	sessions              SessionValidator
+	auditLog              audit.Recorder
+	requireVerifiedEmails bool
+}
+
+// Option configures optional behaviour of the Auth middleware
+type Option func(*Auth)
+
+// WithAPIKeys lets routes registered with AuthenticateWithScope accept API
+// keys as bearer tokens
+func WithAPIKeys(authenticator APIKeyAuthenticator) Option {
+	return func(a *Auth) {
+		a.apiKeys = authenticator
+	}
+}
+
+// WithSessions rejects JWTs whose sid claim names a session that was signed
+// out or expired. Tokens without a session are unaffected.
+func WithSessions(validator SessionValidator) Option {
+	return func(a *Auth) {
+		a.sessions = validator
+	}
+}
+
+// WithAuditLog records permission denials and impersonated requests in the
+// security audit log
+func WithAuditLog(recorder audit.Recorder) Option {
+	return func(a *Auth) {
+		a.auditLog = recorder
+	}
+}
+
+// WithVerifiedEmailRequired makes RequireVerifiedEmail reject users whose
+// email address has not been verified
+func WithVerifiedEmailRequired(required bool) Option {
+	return func(a *Auth) {
+		a.requireVerifiedEmails = required
+	}
 }
 
 // NewAuth creates a new authentication middleware
-func NewAuth(jwtService *jwt.Service) *Auth {
-	return &Auth{
+func NewAuth(jwtService *jwt.Service, opts ...Option) *Auth {
+	a := &Auth{
 		jwtService: jwtService,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
-// Authenticate validates the JWT token and adds user info to context
+// Authenticate validates the JWT token and adds user info to context. API
+// keys are refused; use AuthenticateWithScope for routes open to them.
 func (a *Auth) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return a.AuthenticateWithScope("", next)
+}
+
+// AuthenticateWithScope accepts a JWT or an API key granted scope. Both put
+// the user ID into the context; API keys also add the key under APIKeyKey.
+// An empty scope refuses API keys.
+func (a *Auth) AuthenticateWithScope(scope string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get token from Authorization header
 		authHeader := r.Header.Get("Authorization")
@@ -44,16 +123,170 @@ func (a *Auth) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 
 		token := parts[1]
 
+		if a.apiKeys != nil && strings.HasPrefix(token, apiKeyPrefix) {
+			a.authenticateAPIKey(w, r, token, scope, next)
+			return
+		}
+
 		// Validate token
 		claims, err := a.jwtService.ValidateToken(token)
 		if err != nil {
 			a.sendError(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
+		// Impersonation tokens share the session of the impersonating user
+		if a.sessions != nil && claims.SessionID != "" {
+			if err := a.sessions.ValidateSession(r.Context(), claims.RealUserID(), claims.SessionID); err != nil {
+				a.sendError(w, "Session has been signed out or expired", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		// Add user IDs and claims to context
+		ctx := requestmeta.WithActor(r.Context(), claims.RealUserID())
+		ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, RealUserIDKey, claims.RealUserID())
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
+		r = r.WithContext(ctx)
+		if claims.IsImpersonation() {
+			a.auditImpersonatedRequest(r, claims)
+		}
+		next.ServeHTTP(w, r)
+	}
+}
 
-		// Add user ID to context
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+func (a *Auth) authenticateAPIKey(w http.ResponseWriter, r *http.Request, secret, scope string, next http.HandlerFunc) {
+	key, err := a.apiKeys.Authenticate(r.Context(), secret)
+	if err != nil {
+		a.sendError(w, "Invalid or expired API key", http.StatusUnauthorized)
+		return
+	}
+	if scope == "" || !key.HasScope(scope) {
+		a.sendError(w, "API key is not allowed to access this endpoint", http.StatusForbidden)
+		return
+	}
+
+	ctx := requestmeta.WithActor(r.Context(), key.UserID)
+	ctx = context.WithValue(ctx, UserIDKey, key.UserID)
+	ctx = context.WithValue(ctx, RealUserIDKey, key.UserID)
+	ctx = context.WithValue(ctx, APIKeyKey, key)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PermissionDeniedResponse is the body of a 403 caused by a missing permission
+type PermissionDeniedResponse struct {
+	Error              string `json:"error"`
+	Code               string `json:"code"`
+	RequiredPermission string `json:"required_permission"`
+}
+
+// RequirePermission only lets through requests allowed to use permission:
+// users whose role grants it, or API keys holding it as a scope. It must be
+// wrapped by Authenticate or AuthenticateWithScope. Denials are audited.
+func (a *Auth) RequirePermission(permission user.Permission) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if allowed(r.Context(), permission) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			a.auditDenial(r, permission)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(PermissionDeniedResponse{
+				Error:              "Forbidden",
+				Code:               "permission_denied",
+				RequiredPermission: string(permission),
+			})
+		}
+	}
+}
+
+// auditDenial records a refused request. The actor comes from the request
+// metadata set during authentication.
+func (a *Auth) auditDenial(r *http.Request, permission user.Permission) {
+	if a.auditLog == nil {
+		return
+	}
+
+	details := map[string]string{
+		"permission": string(permission),
+		"method":     r.Method,
+		"path":       r.URL.Path,
+	}
+	if key, ok := r.Context().Value(APIKeyKey).(*apikey.APIKey); ok {
+		details["api_key_id"] = key.ID
+	}
+	userID, _ := r.Context().Value(UserIDKey).(string)
+	a.auditLog.Record(r.Context(), audit.Event{
+		Type:      audit.EventAccessDenied,
+		SubjectID: userID,
+		Details:   details,
+	})
+}
+
+// auditImpersonatedRequest records a request made with an impersonation
+// token, whatever its outcome
+func (a *Auth) auditImpersonatedRequest(r *http.Request, claims *jwt.Claims) {
+	if a.auditLog == nil {
+		return
+	}
+	a.auditLog.Record(r.Context(), audit.Event{
+		Type:      audit.EventImpersonatedRequest,
+		SubjectID: claims.UserID,
+		Details:   map[string]string{"method": r.Method, "path": r.URL.Path, "jti": claims.ID},
+	})
+}
+
+// RejectImpersonation refuses requests made while impersonating a user for
+// the given methods, or for any method when none are given. Use it on
+// sensitive actions such as payouts, API key creation and password
+// changes. It must be wrapped by Authenticate.
+func (a *Auth) RejectImpersonation(methods ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, _ := r.Context().Value(ClaimsKey).(*jwt.Claims)
+			if claims == nil || !claims.IsImpersonation() || (len(methods) > 0 && !slices.Contains(methods, r.Method)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if a.auditLog != nil {
+				a.auditLog.Record(r.Context(), audit.Event{
+					Type:      audit.EventAccessDenied,
+					SubjectID: claims.UserID,
+					Reason:    "impersonating",
+					Details:   map[string]string{"method": r.Method, "path": r.URL.Path},
+				})
+			}
+			a.sendError(w, "Not allowed while impersonating a user", http.StatusForbidden)
+		}
+	}
+}
+
+// allowed reports whether the authenticated caller holds permission. The
+// API key authenticator has already dropped scopes the key's owner lost.
+func allowed(ctx context.Context, permission user.Permission) bool {
+	if key, ok := ctx.Value(APIKeyKey).(*apikey.APIKey); ok {
+		return key.HasScope(string(permission))
+	}
+	claims, _ := ctx.Value(ClaimsKey).(*jwt.Claims)
+	return claims != nil && user.Role(claims.Role).Can(permission)
+}
+
+// RequireVerifiedEmail guards features such as payments that must wait for
+// email verification when the policy is enabled. It must be wrapped by
+// Authenticate so the token claims are available.
+func (a *Auth) RequireVerifiedEmail(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := r.Context().Value(ClaimsKey).(*jwt.Claims)
+		if a.requireVerifiedEmails && (claims == nil || !claims.EmailVerified) {
+			a.sendError(w, "Email address has not been verified", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
 	}
 }
 
@@ -0,0 +1,157 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/middleware"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+var errUnknown = errors.New("unknown")
+
+// stubAPIKeys authenticates the secrets it was given
+type stubAPIKeys map[string]*apikey.APIKey
+
+func (s stubAPIKeys) Authenticate(ctx context.Context, secret string) (*apikey.APIKey, error) {
+	if key, ok := s[secret]; ok {
+		return key, nil
+	}
+	return nil, errUnknown
+}
+
+// stubSessions treats every session except the ended ones as active
+type stubSessions map[string]bool
+
+func (s stubSessions) ValidateSession(ctx context.Context, userID, sessionID string) error {
+	if s[sessionID] {
+		return errUnknown
+	}
+	return nil
+}
+
+// recorder keeps the audit events it is given
+type recorder struct {
+	events []audit.Event
+}
+
+func (r *recorder) Record(ctx context.Context, event audit.Event) {
+	r.events = append(r.events, event)
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestAuth(t *testing.T) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour)
+	auditLog := &recorder{}
+	auth := middleware.NewAuth(jwtService,
+		middleware.WithAPIKeys(stubAPIKeys{
+			"sk_live_read": {ID: "key-1", UserID: "merchant", PublicKey: "pk_live_read", Scopes: []string{apikey.ScopePaymentsRead}},
+		}),
+		middleware.WithSessions(stubSessions{"ended": true}),
+		middleware.WithAuditLog(auditLog),
+	)
+
+	token := func(claims jwt.Claims) string {
+		t.Helper()
+		signed, err := jwtService.GenerateAccessToken(claims)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken() unexpected error = %v", err)
+		}
+		return signed
+	}
+	owner := token(jwt.Claims{UserID: "merchant", Role: string(user.RoleMerchantOwner), SessionID: "active"})
+	readOnly := token(jwt.Claims{UserID: "finance", Role: string(user.RoleFinanceReadOnly), SessionID: "active"})
+	ended := token(jwt.Claims{UserID: "merchant", Role: string(user.RoleMerchantOwner), SessionID: "ended"})
+	impersonation, err := jwtService.GenerateImpersonationToken(
+		jwt.Claims{UserID: "merchant", Role: string(user.RoleMerchantOwner), SessionID: "active"},
+		jwt.Actor{UserID: "admin"}, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken() unexpected error = %v", err)
+	}
+
+	// One route of each kind cmd/api registers
+	accountOnly := auth.Authenticate(ok)
+	payments := auth.AuthenticateWithScope(apikey.ScopePaymentsRead, ok)
+	refunds := auth.AuthenticateWithScope(apikey.ScopePaymentsRefund, auth.RequirePermission(user.PermPaymentsRefund)(ok))
+	sensitive := auth.Authenticate(auth.RejectImpersonation()(ok))
+
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		authorization string
+		wantStatus    int
+		wantAudit     audit.EventType
+	}{
+		{name: "missing header", handler: accountOnly, wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", handler: accountOnly, authorization: "Basic " + owner, wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", handler: accountOnly, authorization: "Bearer not-a-jwt", wantStatus: http.StatusUnauthorized},
+		{name: "valid token", handler: accountOnly, authorization: "Bearer " + owner, wantStatus: http.StatusOK},
+		{name: "ended session", handler: accountOnly, authorization: "Bearer " + ended, wantStatus: http.StatusUnauthorized},
+		{name: "role with the permission", handler: refunds, authorization: "Bearer " + owner, wantStatus: http.StatusOK},
+		{name: "role without the permission", handler: refunds, authorization: "Bearer " + readOnly, wantStatus: http.StatusForbidden, wantAudit: audit.EventAccessDenied},
+		{name: "API key with the scope", handler: payments, authorization: "Bearer sk_live_read", wantStatus: http.StatusOK},
+		{name: "API key without the scope", handler: refunds, authorization: "Bearer sk_live_read", wantStatus: http.StatusForbidden},
+		{name: "unknown API key", handler: payments, authorization: "Bearer sk_live_unknown", wantStatus: http.StatusUnauthorized},
+		{name: "API key on a JWT only route", handler: accountOnly, authorization: "Bearer sk_live_read", wantStatus: http.StatusForbidden},
+		{name: "publishable key instead of the secret", handler: payments, authorization: "Bearer pk_live_read", wantStatus: http.StatusUnauthorized},
+		{name: "impersonation on a regular route", handler: accountOnly, authorization: "Bearer " + impersonation, wantStatus: http.StatusOK, wantAudit: audit.EventImpersonatedRequest},
+		{name: "impersonation on a protected route", handler: sensitive, authorization: "Bearer " + impersonation, wantStatus: http.StatusForbidden, wantAudit: audit.EventAccessDenied},
+		{name: "own token on a protected route", handler: sensitive, authorization: "Bearer " + owner, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog.events = nil
+			req := httptest.NewRequest(http.MethodPost, "/api/protected", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v; body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantAudit != "" {
+				if n := len(auditLog.events); n == 0 || auditLog.events[n-1].Type != tt.wantAudit {
+					t.Errorf("audit events = %+v, want one of type %q last", auditLog.events, tt.wantAudit)
+				}
+			}
+		})
+	}
+}
+
+func TestAuth_ContextValues(t *testing.T) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), time.Hour)
+	auth := middleware.NewAuth(jwtService)
+
+	signed, _ := jwtService.GenerateImpersonationToken(jwt.Claims{UserID: "merchant"}, jwt.Actor{UserID: "admin"}, time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/api/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+signed)
+	w := httptest.NewRecorder()
+	auth.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		realUserID, _ := r.Context().Value(middleware.RealUserIDKey).(string)
+		if userID != "merchant" || realUserID != "admin" {
+			t.Errorf("user IDs in the context = %q, %q; want %q, %q", userID, realUserID, "merchant", "admin")
+		}
+		if claims, _ := r.Context().Value(middleware.ClaimsKey).(*jwt.Claims); claims == nil || !claims.IsImpersonation() {
+			t.Errorf("claims in the context = %+v, want the impersonation claims", claims)
+		}
+		w.WriteHeader(http.StatusOK)
+	})(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %v, want %v", w.Code, http.StatusOK)
+	}
+}
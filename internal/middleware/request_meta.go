@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+)
+
+// RequestMeta stores the client IP, user agent and request ID of every
+// request in its context and echoes the request ID in the response. Enable
+// trustForwarded only behind a reverse proxy that sets X-Forwarded-For.
+func RequestMeta(trustForwarded bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			meta := requestmeta.FromRequest(r, trustForwarded)
+			w.Header().Set(requestmeta.RequestIDHeader, meta.RequestID)
+
+			ctx := requestmeta.NewContext(r.Context(), meta)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
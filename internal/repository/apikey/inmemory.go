@@ -0,0 +1,108 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// InMemoryRepository implements apikey.Repository using in-memory storage.
+// It takes part in memtx transactions.
+type InMemoryRepository struct {
+	keys *memtx.Store[string, *apikey.APIKey]
+}
+
+// NewInMemoryRepository creates a new in-memory API key repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		keys: memtx.NewStore[string, *apikey.APIKey](),
+	}
+}
+
+// Create stores a new API key
+func (r *InMemoryRepository) Create(ctx context.Context, k *apikey.APIKey) error {
+	if k.ID == "" {
+		k.ID = uuid.New().String()
+	}
+
+	stored := clone(k)
+	r.keys.Put(ctx, stored.ID, stored)
+	return nil
+}
+
+// FindByID retrieves an API key by ID
+func (r *InMemoryRepository) FindByID(ctx context.Context, id string) (*apikey.APIKey, error) {
+	k, exists := r.keys.Get(ctx, id)
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+	return clone(k), nil
+}
+
+// FindBySecretHash retrieves an API key by the hash of its secret
+func (r *InMemoryRepository) FindBySecretHash(ctx context.Context, secretHash string) (*apikey.APIKey, error) {
+	matches := r.keys.Select(ctx, func(_ string, k *apikey.APIKey) bool {
+		return k.SecretHash == secretHash
+	})
+	for _, k := range matches {
+		return clone(k), nil
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+// ListByUser returns the keys of a user, oldest first
+func (r *InMemoryRepository) ListByUser(ctx context.Context, userID string) ([]*apikey.APIKey, error) {
+	owned := r.keys.Select(ctx, func(_ string, k *apikey.APIKey) bool {
+		return k.UserID == userID
+	})
+	var found []*apikey.APIKey
+	for _, k := range owned {
+		found = append(found, clone(k))
+	}
+	slices.SortFunc(found, func(a, b *apikey.APIKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return found, nil
+}
+
+// Update updates an existing API key
+func (r *InMemoryRepository) Update(ctx context.Context, k *apikey.APIKey) error {
+	stored := clone(k)
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		if _, exists := r.keys.Get(ctx, stored.ID); !exists {
+			return ErrAPIKeyNotFound
+		}
+		r.keys.Put(ctx, stored.ID, stored)
+		return nil
+	})
+}
+
+// TouchLastUsed records when the key was last used
+func (r *InMemoryRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		k, exists := r.keys.Get(ctx, id)
+		if !exists {
+			return ErrAPIKeyNotFound
+		}
+		updated := clone(k)
+		updated.LastUsedAt = &usedAt
+		r.keys.Put(ctx, id, updated)
+		return nil
+	})
+}
+
+// clone copies a key so callers never share state with the store
+func clone(k *apikey.APIKey) *apikey.APIKey {
+	c := *k
+	c.Scopes = slices.Clone(k.Scopes)
+	return &c
+}
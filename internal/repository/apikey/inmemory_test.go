@@ -0,0 +1,78 @@
+package apikey_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	apiKeyRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/apikey"
+)
+
+func newKey(t *testing.T, userID, secretHash string) *apikey.APIKey {
+	t.Helper()
+	key, err := apikey.NewAPIKey(userID, "label", "pk_"+secretHash, secretHash, []string{apikey.ScopePaymentsRead}, nil)
+	if err != nil {
+		t.Fatalf("NewAPIKey() unexpected error = %v", err)
+	}
+	return key
+}
+
+func TestInMemoryRepository_CreateAndFindBySecretHash(t *testing.T) {
+	repo := apiKeyRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	key := newKey(t, "user123", "hash123")
+	if err := repo.Create(ctx, key); err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if key.ID == "" {
+		t.Error("Create() should generate ID for key")
+	}
+
+	found, err := repo.FindBySecretHash(ctx, "hash123")
+	if err != nil {
+		t.Fatalf("FindBySecretHash() unexpected error = %v", err)
+	}
+	if found.ID != key.ID {
+		t.Errorf("FindBySecretHash() ID = %v, want %v", found.ID, key.ID)
+	}
+
+	if _, err := repo.FindBySecretHash(ctx, "missing"); err != apiKeyRepo.ErrAPIKeyNotFound {
+		t.Errorf("FindBySecretHash() expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryRepository_ListByUser(t *testing.T) {
+	repo := apiKeyRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	_ = repo.Create(ctx, newKey(t, "user123", "hash1"))
+	_ = repo.Create(ctx, newKey(t, "user123", "hash2"))
+	_ = repo.Create(ctx, newKey(t, "user456", "hash3"))
+
+	keys, err := repo.ListByUser(ctx, "user123")
+	if err != nil {
+		t.Fatalf("ListByUser() unexpected error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("ListByUser() returned %d keys, want 2", len(keys))
+	}
+}
+
+func TestInMemoryRepository_TouchLastUsed(t *testing.T) {
+	repo := apiKeyRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	key := newKey(t, "user123", "hash123")
+	_ = repo.Create(ctx, key)
+
+	if err := repo.TouchLastUsed(ctx, key.ID, time.Now()); err != nil {
+		t.Fatalf("TouchLastUsed() unexpected error = %v", err)
+	}
+
+	found, _ := repo.FindByID(ctx, key.ID)
+	if found.LastUsedAt == nil {
+		t.Error("TouchLastUsed() should record the time of use")
+	}
+}
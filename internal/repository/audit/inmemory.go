@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"maps"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+)
+
+// InMemoryRepository implements audit.Repository using in-memory storage.
+// It takes part in memtx transactions: events appended in one are only
+// recorded, and sealed, when it commits.
+type InMemoryRepository struct {
+	events *memtx.Log[[]*audit.Event]
+}
+
+// NewInMemoryRepository creates a new in-memory audit repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		events: memtx.NewLog[[]*audit.Event](nil),
+	}
+}
+
+// Append seals the event after the last entry and stores it
+func (r *InMemoryRepository) Append(ctx context.Context, e *audit.Event) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	r.events.Append(ctx, func(events []*audit.Event) []*audit.Event {
+		var prev *audit.Event
+		if len(events) > 0 {
+			prev = events[len(events)-1]
+		}
+		e.Seal(prev)
+		return append(events, clone(e))
+	})
+	return nil
+}
+
+// Query returns matching events in sequence order
+func (r *InMemoryRepository) Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, error) {
+	var events []*audit.Event
+	err := r.events.Read(ctx, func(all []*audit.Event) error {
+		for _, e := range all {
+			if !filter.Matches(e) {
+				continue
+			}
+			events = append(events, clone(e))
+			if filter.Limit > 0 && len(events) == filter.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return events, err
+}
+
+func clone(e *audit.Event) *audit.Event {
+	c := *e
+	c.Details = maps.Clone(e.Details)
+	return &c
+}
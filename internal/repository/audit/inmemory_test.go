@@ -0,0 +1,78 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	auditRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+)
+
+func TestInMemoryRepository_Append(t *testing.T) {
+	repo := auditRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	if err := repo.Append(ctx, &audit.Event{}); err != audit.ErrEmptyEventType {
+		t.Fatalf("Append() without a type error = %v, want %v", err, audit.ErrEmptyEventType)
+	}
+
+	first := &audit.Event{Type: audit.EventUserRegistered, SubjectID: "user-1"}
+	second := &audit.Event{Type: audit.EventLoginSucceeded, SubjectID: "user-2"}
+	repo.Append(ctx, first)
+	repo.Append(ctx, second)
+
+	if first.Sequence != 1 || second.Sequence != 2 || second.PrevHash != first.Hash {
+		t.Errorf("Append() should chain entries: %+v, %+v", first, second)
+	}
+
+	events, _ := repo.Query(ctx, audit.Filter{})
+	if err := audit.VerifyChain(nil, events); err != nil {
+		t.Errorf("stored chain should verify: %v", err)
+	}
+}
+
+func TestInMemoryRepository_Query(t *testing.T) {
+	repo := auditRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	for _, subject := range []string{"user-1", "user-2", "user-1", "user-1"} {
+		repo.Append(ctx, &audit.Event{Type: audit.EventLoginFailed, SubjectID: subject})
+	}
+
+	events, _ := repo.Query(ctx, audit.Filter{UserID: "user-1", Limit: 2})
+	if len(events) != 2 || events[0].Sequence != 1 || events[1].Sequence != 3 {
+		t.Fatalf("Query() = %+v, want the first two entries for user-1", events)
+	}
+
+	// Returned events are copies
+	events[0].SubjectID = "tampered"
+	events, _ = repo.Query(ctx, audit.Filter{})
+	if err := audit.VerifyChain(nil, events); err != nil {
+		t.Errorf("modifying a query result should not change the stored log: %v", err)
+	}
+}
+
+func TestInMemoryRepository_Transaction(t *testing.T) {
+	repo := auditRepo.NewInMemoryRepository()
+	tm := memtx.NewManager()
+	ctx := context.Background()
+	errRollback := errors.New("roll back")
+
+	tm.WithinTx(ctx, func(ctx context.Context) error {
+		repo.Append(ctx, &audit.Event{Type: audit.EventPasswordChanged, SubjectID: "user-1"})
+		return errRollback
+	})
+	tm.WithinTx(ctx, func(ctx context.Context) error {
+		return repo.Append(ctx, &audit.Event{Type: audit.EventPasswordReset, SubjectID: "user-1"})
+	})
+
+	events, _ := repo.Query(ctx, audit.Filter{})
+	if len(events) != 1 || events[0].Type != audit.EventPasswordReset {
+		t.Fatalf("Query() = %+v, want only the committed event", events)
+	}
+	if events[0].Hash == "" {
+		t.Error("an event appended in a transaction should be sealed on commit")
+	}
+}
@@ -0,0 +1,494 @@
+// Package memtx gives the in-memory repositories transactions. A
+// transaction keeps its writes to a Store aside and remembers which
+// entries it read; when it commits, it publishes the writes together if
+// none of those entries has changed in the meantime, and fails with
+// transaction.ErrConflict otherwise. Rolling back drops the writes. Appends
+// to a Log are held back until the commit instead, and never conflict.
+package memtx
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+)
+
+// nextStoreID numbers stores and logs, so commits lock them in one order
+var nextStoreID atomic.Uint64
+
+type txKey struct{}
+
+// tx is a running transaction
+type tx struct {
+	mu       sync.Mutex
+	views    map[any]storeView
+	order    []storeView
+	prepared bool
+}
+
+// storeView is a transaction's reads and writes of one store
+type storeView interface {
+	id() uint64
+	lock()
+	unlock()
+	dirty() bool
+	conflicts() bool
+	publish()
+}
+
+// Manager implements transaction.Manager for the stores of this package
+type Manager struct {
+	outer transaction.Manager
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// Within runs every transaction inside one of outer, for in-memory stores
+// that must change together with a database. The stores are checked for
+// conflicts and locked before the outer transaction commits, so a conflict
+// rolls it back, and their changes are published once it has committed;
+// if it fails to, they are dropped.
+func Within(outer transaction.Manager) Option {
+	return func(m *Manager) {
+		m.outer = outer
+	}
+}
+
+// NewManager creates a transaction manager
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithinTx runs fn in a transaction; see transaction.Manager
+func (m *Manager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.outer == nil {
+		return run(ctx, fn)
+	}
+	if _, ok := ctx.Value(txKey{}).(*tx); ok {
+		return m.outer.WithinTx(ctx, fn)
+	}
+
+	var t *tx
+	err := m.outer.WithinTx(ctx, func(ctx context.Context) error {
+		if t != nil {
+			// The outer manager is running fn again
+			t.release()
+		}
+		t = &tx{views: make(map[any]storeView)}
+		if err := fn(context.WithValue(ctx, txKey{}, t)); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return t.prepare()
+	})
+	if t == nil {
+		return err
+	}
+	if err == nil {
+		t.publish()
+	}
+	t.release()
+	return err
+}
+
+// Atomically runs fn in the transaction carried by ctx. Without one, fn
+// runs in a transaction of its own that is retried until it commits, so a
+// repository can read and write several entries or stores as one step.
+func Atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	for {
+		if err := run(ctx, fn); err != transaction.ErrConflict {
+			return err
+		}
+	}
+}
+
+// run runs fn in a transaction that commits on its own, or in the one
+// carried by ctx
+func run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*tx); ok {
+		return fn(ctx)
+	}
+
+	t := &tx{views: make(map[any]storeView)}
+	if err := fn(context.WithValue(ctx, txKey{}, t)); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := t.prepare(); err != nil {
+		return err
+	}
+	t.publish()
+	t.release()
+	return nil
+}
+
+// view returns the transaction's view of a store, creating it on first use
+func (t *tx) view(key any, create func() storeView) storeView {
+	v, ok := t.views[key]
+	if !ok {
+		v = create()
+		t.views[key] = v
+		t.order = append(t.order, v)
+	}
+	return v
+}
+
+// prepare locks the stores the transaction wrote to and checks that
+// everything it read is unchanged, so the result is the same as if it had
+// run alone. The locks are held until release; on a conflict they are
+// released at once.
+func (t *tx) prepare() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !slices.ContainsFunc(t.order, storeView.dirty) {
+		return nil
+	}
+
+	// Stores are always locked in the same order, so two commits cannot
+	// wait for each other
+	slices.SortFunc(t.order, func(a, b storeView) int {
+		return cmp.Compare(a.id(), b.id())
+	})
+	for _, v := range t.order {
+		v.lock()
+	}
+	t.prepared = true
+
+	for _, v := range t.order {
+		if v.conflicts() {
+			t.releaseLocked()
+			return transaction.ErrConflict
+		}
+	}
+	return nil
+}
+
+// publish applies the writes of a prepared transaction
+func (t *tx) publish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.prepared {
+		return
+	}
+	for _, v := range t.order {
+		v.publish()
+	}
+}
+
+// release unlocks the stores locked by prepare, if any
+func (t *tx) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.releaseLocked()
+}
+
+func (t *tx) releaseLocked() {
+	if !t.prepared {
+		return
+	}
+	for _, v := range t.order {
+		v.unlock()
+	}
+	t.prepared = false
+}
+
+// Store holds the entities of an in-memory repository by key. Outside a
+// transaction reads and writes apply to the shared entries at once;
+// inside one, writes are kept aside until the commit. Values are shared
+// between transactions, so an entity must be replaced with Put rather than
+// modified in place.
+type Store[K comparable, V any] struct {
+	storeID uint64
+	mu      sync.RWMutex
+	entries map[K]entry[V]
+	// seq counts the commits that changed the store; each entry records
+	// the one that last wrote it
+	seq uint64
+}
+
+type entry[V any] struct {
+	value V
+	seq   uint64
+}
+
+// NewStore creates an empty store
+func NewStore[K comparable, V any]() *Store[K, V] {
+	return &Store[K, V]{
+		storeID: nextStoreID.Add(1),
+		entries: make(map[K]entry[V]),
+	}
+}
+
+// Get returns the value stored under key as visible to ctx
+func (s *Store[K, V]) Get(ctx context.Context, key K) (V, bool) {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return s.viewOf(t).get(key)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[key]
+	return e.value, ok
+}
+
+// Select returns the entries visible to ctx that match accepts. Inside a
+// transaction the commit conflicts if one of them changes, or another one
+// that match accepts appears, in the meantime.
+func (s *Store[K, V]) Select(ctx context.Context, match func(key K, value V) bool) map[K]V {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return s.viewOf(t).selectWhere(match)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := make(map[K]V)
+	for key, e := range s.entries {
+		if match(key, e.value) {
+			found[key] = e.value
+		}
+	}
+	return found
+}
+
+// Put stores value under key
+func (s *Store[K, V]) Put(ctx context.Context, key K, value V) {
+	s.write(ctx, key, write[V]{value: value})
+}
+
+// Delete removes the value stored under key
+func (s *Store[K, V]) Delete(ctx context.Context, key K) {
+	s.write(ctx, key, write[V]{deleted: true})
+}
+
+func (s *Store[K, V]) write(ctx context.Context, key K, w write[V]) {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		s.viewOf(t).writes[key] = w
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.apply(key, w)
+}
+
+// apply makes a write visible; the caller must hold the write lock and
+// have advanced seq
+func (s *Store[K, V]) apply(key K, w write[V]) {
+	if w.deleted {
+		delete(s.entries, key)
+		return
+	}
+	s.entries[key] = entry[V]{value: w.value, seq: s.seq}
+}
+
+// viewOf returns the view of t on the store; the caller must hold t.mu
+func (s *Store[K, V]) viewOf(t *tx) *view[K, V] {
+	return t.view(s, func() storeView {
+		return &view[K, V]{
+			store:  s,
+			reads:  make(map[K]uint64),
+			writes: make(map[K]write[V]),
+		}
+	}).(*view[K, V])
+}
+
+// write is a change a transaction made to an entry
+type write[V any] struct {
+	value   V
+	deleted bool
+}
+
+// selection is a Select made in a transaction
+type selection[K comparable, V any] struct {
+	seq   uint64
+	match func(key K, value V) bool
+}
+
+// view implements storeView for a Store[K, V]. reads holds the seq of
+// every entry the transaction read, zero for one that did not exist.
+type view[K comparable, V any] struct {
+	store      *Store[K, V]
+	reads      map[K]uint64
+	writes     map[K]write[V]
+	selections []selection[K, V]
+}
+
+func (v *view[K, V]) get(key K) (V, bool) {
+	if w, ok := v.writes[key]; ok {
+		return w.value, !w.deleted
+	}
+
+	v.store.mu.RLock()
+	defer v.store.mu.RUnlock()
+	e, ok := v.store.entries[key]
+	v.read(key, e.seq)
+	return e.value, ok
+}
+
+func (v *view[K, V]) selectWhere(match func(key K, value V) bool) map[K]V {
+	v.store.mu.RLock()
+	defer v.store.mu.RUnlock()
+
+	found := make(map[K]V)
+	for key, e := range v.store.entries {
+		if _, written := v.writes[key]; !written && match(key, e.value) {
+			v.read(key, e.seq)
+			found[key] = e.value
+		}
+	}
+	for key, w := range v.writes {
+		if !w.deleted && match(key, w.value) {
+			found[key] = w.value
+		}
+	}
+	v.selections = append(v.selections, selection[K, V]{seq: v.store.seq, match: match})
+	return found
+}
+
+// read records the seq an entry had when the transaction first read it
+func (v *view[K, V]) read(key K, seq uint64) {
+	if _, ok := v.reads[key]; !ok {
+		v.reads[key] = seq
+	}
+}
+
+func (v *view[K, V]) id() uint64 {
+	return v.store.storeID
+}
+
+func (v *view[K, V]) lock() {
+	v.store.mu.Lock()
+}
+
+func (v *view[K, V]) unlock() {
+	v.store.mu.Unlock()
+}
+
+func (v *view[K, V]) dirty() bool {
+	return len(v.writes) > 0
+}
+
+func (v *view[K, V]) conflicts() bool {
+	for key, seq := range v.reads {
+		if v.store.entries[key].seq != seq {
+			return true
+		}
+	}
+	for _, sel := range v.selections {
+		if v.store.seq == sel.seq {
+			continue
+		}
+		// Entries that matched are covered by reads; look for new matches
+		for key, e := range v.store.entries {
+			if e.seq > sel.seq && sel.match(key, e.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *view[K, V]) publish() {
+	if len(v.writes) == 0 {
+		return
+	}
+	v.store.seq++
+	for key, w := range v.writes {
+		v.store.apply(key, w)
+	}
+}
+
+// Log holds append-only state, such as an audit trail. Outside a
+// transaction an append applies at once. Inside one it is deferred to the
+// commit and applied after everything committed before, so appends never
+// conflict; the transaction does not see its own appends.
+type Log[T any] struct {
+	logID uint64
+	mu    sync.RWMutex
+	state T
+}
+
+// NewLog creates a log holding state
+func NewLog[T any](state T) *Log[T] {
+	return &Log[T]{logID: nextStoreID.Add(1), state: state}
+}
+
+// Read calls fn with the committed state. fn must not modify it.
+func (l *Log[T]) Read(ctx context.Context, fn func(state T) error) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fn(l.state)
+}
+
+// Append replaces the state with what fn returns for it, now or when the
+// transaction in ctx commits
+func (l *Log[T]) Append(ctx context.Context, fn func(state T) T) {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		v := t.view(l, func() storeView {
+			return &logView[T]{log: l}
+		}).(*logView[T])
+		v.pending = append(v.pending, fn)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state = fn(l.state)
+}
+
+// logView implements storeView for a Log[T], holding the appends of a
+// transaction
+type logView[T any] struct {
+	log     *Log[T]
+	pending []func(state T) T
+}
+
+func (v *logView[T]) id() uint64 {
+	return v.log.logID
+}
+
+func (v *logView[T]) lock() {
+	v.log.mu.Lock()
+}
+
+func (v *logView[T]) unlock() {
+	v.log.mu.Unlock()
+}
+
+func (v *logView[T]) dirty() bool {
+	return len(v.pending) > 0
+}
+
+func (v *logView[T]) conflicts() bool {
+	return false
+}
+
+func (v *logView[T]) publish() {
+	for _, fn := range v.pending {
+		v.log.state = fn(v.log.state)
+	}
+}
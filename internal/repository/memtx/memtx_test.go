@@ -0,0 +1,340 @@
+package memtx_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+)
+
+var errRollback = errors.New("roll back")
+
+func newStore() *memtx.Store[string, int] {
+	return memtx.NewStore[string, int]()
+}
+
+func set(ctx context.Context, s *memtx.Store[string, int], key string, value int) error {
+	s.Put(ctx, key, value)
+	return nil
+}
+
+func get(ctx context.Context, s *memtx.Store[string, int], key string) int {
+	value, _ := s.Get(ctx, key)
+	return value
+}
+
+func TestManager_CommitsAllStores(t *testing.T) {
+	tm := memtx.NewManager()
+	invoices, ledger := newStore(), newStore()
+
+	err := tm.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := set(ctx, invoices, "inv-1", 100); err != nil {
+			return err
+		}
+		return set(ctx, ledger, "inv-1", -100)
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() unexpected error = %v", err)
+	}
+
+	ctx := context.Background()
+	if get(ctx, invoices, "inv-1") != 100 || get(ctx, ledger, "inv-1") != -100 {
+		t.Error("WithinTx() should commit the writes to both stores")
+	}
+}
+
+func TestManager_RollsBackAllStores(t *testing.T) {
+	tm := memtx.NewManager()
+	invoices, ledger := newStore(), newStore()
+	ctx := context.Background()
+	set(ctx, invoices, "inv-1", 1)
+
+	err := tm.WithinTx(ctx, func(ctx context.Context) error {
+		set(ctx, invoices, "inv-1", 100)
+		set(ctx, ledger, "inv-1", -100)
+		if get(ctx, invoices, "inv-1") != 100 {
+			t.Error("a transaction should see its own writes")
+		}
+		if get(context.Background(), invoices, "inv-1") != 1 {
+			t.Error("uncommitted writes should not be visible outside the transaction")
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+	}
+
+	if get(ctx, invoices, "inv-1") != 1 || get(ctx, ledger, "inv-1") != 0 {
+		t.Error("WithinTx() should roll back the writes to both stores")
+	}
+}
+
+func TestManager_CanceledContext(t *testing.T) {
+	tm := memtx.NewManager()
+	s := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := tm.WithinTx(ctx, func(ctx context.Context) error {
+		set(ctx, s, "key", 1)
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, context.Canceled)
+	}
+	if get(context.Background(), s, "key") != 0 {
+		t.Error("a transaction whose context was canceled should not commit")
+	}
+}
+
+func TestManager_Conflict(t *testing.T) {
+	tm := memtx.NewManager()
+	s := newStore()
+	ctx := context.Background()
+
+	err := tm.WithinTx(ctx, func(txCtx context.Context) error {
+		balance := get(txCtx, s, "balance")
+		// A concurrent write to data the transaction read
+		set(ctx, s, "balance", 50)
+		return set(txCtx, s, "balance", balance+10)
+	})
+	if err != transaction.ErrConflict {
+		t.Fatalf("WithinTx() error = %v, want %v", err, transaction.ErrConflict)
+	}
+	if got := get(ctx, s, "balance"); got != 50 {
+		t.Errorf("balance = %d, want the concurrent write 50", got)
+	}
+}
+
+func TestManager_ConflictsPerKey(t *testing.T) {
+	tm := memtx.NewManager()
+	s := newStore()
+	ctx := context.Background()
+	set(ctx, s, "alice", 10)
+
+	// Concurrent writes to other keys do not conflict
+	err := tm.WithinTx(ctx, func(txCtx context.Context) error {
+		balance := get(txCtx, s, "alice")
+		set(ctx, s, "bob", 20)
+		return set(txCtx, s, "alice", balance+10)
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() unexpected error = %v", err)
+	}
+	if get(ctx, s, "alice") != 20 || get(ctx, s, "bob") != 20 {
+		t.Errorf("alice, bob = %d, %d; want 20, 20", get(ctx, s, "alice"), get(ctx, s, "bob"))
+	}
+
+	// Reading a missing key conflicts with its creation
+	err = tm.WithinTx(ctx, func(txCtx context.Context) error {
+		if _, exists := s.Get(txCtx, "carol"); exists {
+			return errRollback
+		}
+		set(ctx, s, "carol", 1)
+		return set(txCtx, s, "carol", 2)
+	})
+	if err != transaction.ErrConflict {
+		t.Errorf("WithinTx() after a concurrent create error = %v, want %v", err, transaction.ErrConflict)
+	}
+
+	// and so does reading a key with its deletion
+	err = tm.WithinTx(ctx, func(txCtx context.Context) error {
+		balance := get(txCtx, s, "bob")
+		s.Delete(ctx, "bob")
+		return set(txCtx, s, "alice", balance)
+	})
+	if err != transaction.ErrConflict {
+		t.Errorf("WithinTx() after a concurrent delete error = %v, want %v", err, transaction.ErrConflict)
+	}
+}
+
+func TestManager_SelectConflicts(t *testing.T) {
+	tm := memtx.NewManager()
+	s := newStore()
+	ctx := context.Background()
+	set(ctx, s, "small", 1)
+
+	large := func(_ string, value int) bool {
+		return value >= 100
+	}
+	countLarge := func(concurrent func()) error {
+		return tm.WithinTx(ctx, func(txCtx context.Context) error {
+			n := len(s.Select(txCtx, large))
+			concurrent()
+			return set(txCtx, s, "large-count", n)
+		})
+	}
+
+	// Changes to entries the selection does not cover do not conflict
+	if err := countLarge(func() { set(ctx, s, "small", 2) }); err != nil {
+		t.Errorf("WithinTx() after an unrelated change error = %v, want nil", err)
+	}
+	// A new match appearing does
+	if err := countLarge(func() { set(ctx, s, "large", 100) }); err != transaction.ErrConflict {
+		t.Errorf("WithinTx() after a new match error = %v, want %v", err, transaction.ErrConflict)
+	}
+	// and so does a match changing
+	if err := countLarge(func() { set(ctx, s, "large", 1) }); err != transaction.ErrConflict {
+		t.Errorf("WithinTx() after a match changed error = %v, want %v", err, transaction.ErrConflict)
+	}
+}
+
+func TestManager_ConcurrentTransactions(t *testing.T) {
+	tm := memtx.NewManager()
+	s := newStore()
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Retry until the increment commits without a conflict
+			for {
+				err := tm.WithinTx(context.Background(), func(ctx context.Context) error {
+					return set(ctx, s, "count", get(ctx, s, "count")+1)
+				})
+				if err != transaction.ErrConflict {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := get(context.Background(), s, "count"); got != workers {
+		t.Errorf("count = %d, want %d; an increment was lost", got, workers)
+	}
+}
+
+func appendTo(ctx context.Context, l *memtx.Log[[]string], entry string) {
+	l.Append(ctx, func(entries []string) []string {
+		return append(entries, entry)
+	})
+}
+
+func entries(l *memtx.Log[[]string]) []string {
+	var all []string
+	l.Read(context.Background(), func(entries []string) error {
+		all = append(all, entries...)
+		return nil
+	})
+	return all
+}
+
+func TestLog_AppendsOnCommit(t *testing.T) {
+	tm := memtx.NewManager()
+	l := memtx.NewLog[[]string](nil)
+	s := newStore()
+	ctx := context.Background()
+
+	err := tm.WithinTx(ctx, func(txCtx context.Context) error {
+		appendTo(txCtx, l, "in-tx")
+		// A concurrent append neither conflicts nor is overtaken
+		appendTo(ctx, l, "concurrent")
+		if got := entries(l); len(got) != 1 {
+			t.Errorf("entries before commit = %v, want only the concurrent one", got)
+		}
+		return set(txCtx, s, "key", 1)
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() unexpected error = %v", err)
+	}
+	if got := entries(l); len(got) != 2 || got[0] != "concurrent" || got[1] != "in-tx" {
+		t.Errorf("entries = %v, want [concurrent in-tx]", got)
+	}
+
+	err = tm.WithinTx(ctx, func(txCtx context.Context) error {
+		appendTo(txCtx, l, "rolled back")
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+	}
+	if got := entries(l); len(got) != 2 {
+		t.Errorf("entries = %v, a rolled back append was kept", got)
+	}
+}
+
+// recordingManager stands in for a database transaction manager
+type recordingManager struct {
+	committed *bool
+}
+
+func (m recordingManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	*m.committed = true
+	return nil
+}
+
+// failingManager stands in for a database whose commit fails
+type failingManager struct{}
+
+var errCommit = errors.New("commit failed")
+
+func (failingManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return errCommit
+}
+
+func TestManager_WithinFailedCommit(t *testing.T) {
+	tm := memtx.NewManager(memtx.Within(failingManager{}))
+	s := newStore()
+	l := memtx.NewLog[[]string](nil)
+	ctx := context.Background()
+	set(ctx, s, "key", 1)
+
+	err := tm.WithinTx(ctx, func(txCtx context.Context) error {
+		appendTo(txCtx, l, "in-tx")
+		return set(txCtx, s, "key", 2)
+	})
+	if err != errCommit {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errCommit)
+	}
+
+	// Nothing is published, and the stores are unlocked again
+	if got := get(ctx, s, "key"); got != 1 {
+		t.Errorf("key = %d after the outer commit failed, want 1", got)
+	}
+	if got := entries(l); len(got) != 0 {
+		t.Errorf("entries = %v after the outer commit failed, want none", got)
+	}
+	set(ctx, s, "key", 3)
+	appendTo(ctx, l, "after")
+	if get(ctx, s, "key") != 3 || len(entries(l)) != 1 {
+		t.Error("writes after a failed commit should apply")
+	}
+}
+
+func TestManager_Within(t *testing.T) {
+	committed := false
+	tm := memtx.NewManager(memtx.Within(recordingManager{committed: &committed}))
+	s := newStore()
+	ctx := context.Background()
+
+	// A conflict in the stores rolls the outer transaction back
+	err := tm.WithinTx(ctx, func(txCtx context.Context) error {
+		get(txCtx, s, "key")
+		set(ctx, s, "key", 1)
+		return set(txCtx, s, "key", 2)
+	})
+	if err != transaction.ErrConflict || committed {
+		t.Fatalf("WithinTx() = %v with outer committed = %v, want %v and a rollback", err, committed, transaction.ErrConflict)
+	}
+
+	if err := tm.WithinTx(ctx, func(txCtx context.Context) error {
+		return set(txCtx, s, "key", 3)
+	}); err != nil || !committed {
+		t.Fatalf("WithinTx() = %v with outer committed = %v, want both to commit", err, committed)
+	}
+	if got := get(ctx, s, "key"); got != 3 {
+		t.Errorf("key = %d, want 3", got)
+	}
+}
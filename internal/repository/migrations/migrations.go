@@ -0,0 +1,246 @@
+// Package migrations holds the versioned database schema and applies it.
+// Each dialect has its own directory of files named
+// <version>_<description>.sql; versions are applied in order, each in its
+// own transaction, and recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownDialect    = errors.New("unknown database dialect")
+	ErrInvalidMigration  = errors.New("invalid migration file")
+	ErrUnknownMigrations = errors.New("database has migrations this build does not know")
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Dialect names the SQL flavour of a database
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// postgresLockID is the advisory lock key that serializes migrations when
+// several instances start at once
+const postgresLockID = 7_201_843_116
+
+// Migration is a single schema change
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Load returns the migrations embedded for dialect, ordered by version
+func Load(dialect Dialect) ([]Migration, error) {
+	if _, err := dialectFor(dialect); err != nil {
+		return nil, err
+	}
+
+	dir, err := fs.Sub(files, string(dialect))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(dir)
+}
+
+// Parse reads the *.sql migration files at the root of fsys, ordered by
+// version. Versions must be unique.
+func Parse(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	seen := make(map[int]string)
+	for _, name := range names {
+		prefix, description, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 || description == "" {
+			return nil, fmt.Errorf("%w: %s: want <version>_<description>.sql", ErrInvalidMigration, name)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("%w: %s and %s share version %d", ErrInvalidMigration, other, name, version)
+		}
+		seen[version] = name
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    path.Base(name),
+			SQL:     string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Run applies the embedded migrations for dialect that db has not seen yet
+// and returns how many were applied
+func Run(ctx context.Context, db *sql.DB, dialect Dialect) (int, error) {
+	migrations, err := Load(dialect)
+	if err != nil {
+		return 0, err
+	}
+	return Apply(ctx, db, dialect, migrations)
+}
+
+// Apply applies the given migrations that db has not seen yet and returns
+// how many were applied. It fails without changes if db has a version that
+// is not among migrations, which means it was migrated by a newer build.
+func Apply(ctx context.Context, db *sql.DB, dialect Dialect, migrations []Migration) (int, error) {
+	d, err := dialectFor(dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	// Use a single connection so session-level locks hold throughout
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	unlock, err := d.lock(ctx, conn)
+	if err != nil {
+		return 0, fmt.Errorf("locking schema_migrations: %w", err)
+	}
+	defer unlock()
+
+	if _, err := conn.ExecContext(ctx, d.createTable); err != nil {
+		return 0, fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			return 0, fmt.Errorf("%w: version %d", ErrUnknownMigrations, version)
+		}
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := apply(ctx, conn, d, m); err != nil {
+			return count, fmt.Errorf("applying %s: %w", m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func apply(ctx context.Context, conn *sql.Conn, d *dialectSQL, m Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, d.insert, m.Version, m.Name, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// dialectSQL holds the statements the runner needs in each dialect
+type dialectSQL struct {
+	createTable string
+	insert      string
+	lock        func(ctx context.Context, conn *sql.Conn) (func(), error)
+}
+
+func dialectFor(dialect Dialect) (*dialectSQL, error) {
+	switch dialect {
+	case Postgres:
+		return &dialectSQL{
+			createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    BIGINT PRIMARY KEY,
+				name       TEXT NOT NULL,
+				applied_at TIMESTAMPTZ NOT NULL
+			)`,
+			insert: "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+			lock:   postgresLock,
+		}, nil
+	case SQLite:
+		return &dialectSQL{
+			createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    INTEGER PRIMARY KEY,
+				name       TEXT NOT NULL,
+				applied_at DATETIME NOT NULL
+			)`,
+			insert: "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			lock:   noLock,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDialect, dialect)
+	}
+}
+
+// postgresLock takes a session-level advisory lock, so instances starting
+// together apply migrations one after the other
+func postgresLock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockID); err != nil {
+		return nil, err
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", postgresLockID)
+	}, nil
+}
+
+// noLock is used for SQLite, which only one process opens. Should a second
+// one race it anyway, the primary key on version fails its transaction.
+func noLock(context.Context, *sql.Conn) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,127 @@
+package migrations_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/migrations"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/sqlite"
+)
+
+func TestParse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_add_index.sql":    {Data: []byte("CREATE INDEX ...")},
+		"0002_add_column.sql":   {Data: []byte("ALTER TABLE ...")},
+		"0001_create_users.sql": {Data: []byte("CREATE TABLE ...")},
+		"README.md":             {Data: []byte("not a migration")},
+	}
+
+	got, err := migrations.Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+
+	want := []int{1, 2, 10}
+	if len(got) != len(want) {
+		t.Fatalf("Parse() returned %d migrations, want %d", len(got), len(want))
+	}
+	for i, m := range got {
+		if m.Version != want[i] {
+			t.Errorf("migration %d version = %d, want %d", i, m.Version, want[i])
+		}
+	}
+	if got[0].Name != "0001_create_users.sql" || got[0].SQL != "CREATE TABLE ..." {
+		t.Errorf("Parse() first migration = %+v", got[0])
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+	}{
+		{name: "No description", files: []string{"0001.sql"}},
+		{name: "No version", files: []string{"create_users.sql"}},
+		{name: "Zero version", files: []string{"0000_init.sql"}},
+		{name: "Duplicate version", files: []string{"0001_create_users.sql", "1_create_sessions.sql"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, name := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte("SELECT 1")}
+			}
+
+			if _, err := migrations.Parse(fsys); !errors.Is(err, migrations.ErrInvalidMigration) {
+				t.Errorf("Parse() error = %v, want %v", err, migrations.ErrInvalidMigration)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	got, err := migrations.Load(migrations.Postgres)
+	if err != nil || len(got) == 0 || got[0].Version != 1 {
+		t.Fatalf("Load(Postgres) = %+v, %v; want the embedded migrations", got, err)
+	}
+
+	if _, err := migrations.Load("oracle"); !errors.Is(err, migrations.ErrUnknownDialect) {
+		t.Errorf("Load() unknown dialect error = %v, want %v", err, migrations.ErrUnknownDialect)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "test.db"), time.Second)
+	if err != nil {
+		t.Fatalf("sqlite.Open() unexpected error = %v", err)
+	}
+	defer db.Close()
+
+	all := []migrations.Migration{
+		{Version: 1, Name: "0001_create_a.sql", SQL: "CREATE TABLE a (id INTEGER)"},
+		{Version: 2, Name: "0002_create_b.sql", SQL: "CREATE TABLE b (id INTEGER)"},
+	}
+
+	if applied, err := migrations.Apply(ctx, db, migrations.SQLite, all[:1]); err != nil || applied != 1 {
+		t.Fatalf("Apply() first = %d, %v; want 1, nil", applied, err)
+	}
+	if applied, err := migrations.Apply(ctx, db, migrations.SQLite, all); err != nil || applied != 1 {
+		t.Fatalf("Apply() second = %d, %v; want 1, nil", applied, err)
+	}
+	if applied, err := migrations.Apply(ctx, db, migrations.SQLite, all); err != nil || applied != 0 {
+		t.Errorf("Apply() again = %d, %v; want 0, nil", applied, err)
+	}
+
+	// An older build must not run against a newer schema
+	if _, err := migrations.Apply(ctx, db, migrations.SQLite, all[:1]); !errors.Is(err, migrations.ErrUnknownMigrations) {
+		t.Errorf("Apply() older build error = %v, want %v", err, migrations.ErrUnknownMigrations)
+	}
+}
+
+func TestApply_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "test.db"), time.Second)
+	if err != nil {
+		t.Fatalf("sqlite.Open() unexpected error = %v", err)
+	}
+	defer db.Close()
+
+	broken := []migrations.Migration{
+		{Version: 1, Name: "0001_broken.sql", SQL: "CREATE TABLE a (id INTEGER); CREATE TABLE a (id INTEGER)"},
+	}
+	if _, err := migrations.Apply(ctx, db, migrations.SQLite, broken); err == nil {
+		t.Fatal("Apply() of a broken migration should fail")
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'a'").Scan(&count)
+	if count != 0 {
+		t.Error("a failed migration should leave no changes")
+	}
+}
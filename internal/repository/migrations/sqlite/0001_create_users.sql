@@ -0,0 +1,27 @@
+-- Times are stored as UTC text, which sorts chronologically
+CREATE TABLE users (
+    id                    TEXT PRIMARY KEY,
+    username              TEXT NOT NULL,
+    email                 TEXT NOT NULL,
+    -- Lookup key; see user.CanonicalEmail
+    email_canonical       TEXT NOT NULL,
+    password_hash         TEXT NOT NULL,
+    role                  TEXT NOT NULL,
+    created_at            DATETIME NOT NULL,
+    updated_at            DATETIME NOT NULL,
+    email_verified        BOOLEAN NOT NULL DEFAULT FALSE,
+    email_verified_at     DATETIME,
+    mfa_required          BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_secret           TEXT NOT NULL DEFAULT '',
+    totp_pending_secret   TEXT NOT NULL DEFAULT '',
+    totp_last_step        INTEGER NOT NULL DEFAULT 0,
+    -- JSON array of strings
+    recovery_code_hashes  TEXT NOT NULL DEFAULT '[]',
+    deletion_requested_at DATETIME,
+    anonymized_at         DATETIME
+);
+
+CREATE UNIQUE INDEX users_email_canonical_key ON users (email_canonical);
+
+CREATE INDEX users_pending_deletion_idx ON users (deletion_requested_at)
+    WHERE deletion_requested_at IS NOT NULL AND anonymized_at IS NULL;
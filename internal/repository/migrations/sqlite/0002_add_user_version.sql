@@ -0,0 +1,2 @@
+-- Revision counter for optimistic concurrency control; see user.Repository
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,63 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/migrations"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/postgres"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/repositorytest"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/sqltx"
+)
+
+// openTestDB connects to POSTGRES_TEST_DSN, migrates it and empties the
+// users table. The tests are skipped when it is not set.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+
+	ctx := context.Background()
+	db, err := postgres.Open(ctx, dsn)
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := migrations.Run(ctx, db, migrations.Postgres); err != nil {
+		t.Fatalf("migrations.Run() unexpected error = %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "TRUNCATE users"); err != nil {
+		t.Fatalf("TRUNCATE unexpected error = %v", err)
+	}
+	return db
+}
+
+func TestMigrations_Idempotent(t *testing.T) {
+	db := openTestDB(t)
+
+	applied, err := migrations.Run(context.Background(), db, migrations.Postgres)
+	if err != nil || applied != 0 {
+		t.Errorf("migrations.Run() again = %d, %v; want 0, nil", applied, err)
+	}
+}
+
+func TestUserRepository_Suite(t *testing.T) {
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) user.Repository {
+		return postgres.NewUserRepository(openTestDB(t))
+	})
+}
+
+func TestUserRepository_TransactionSuite(t *testing.T) {
+	repositorytest.RunUserTransactionSuite(t, func(t *testing.T) (user.Repository, transaction.Manager) {
+		db := openTestDB(t)
+		return postgres.NewUserRepository(db), sqltx.NewManager(db)
+	})
+}
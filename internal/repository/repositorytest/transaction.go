@@ -0,0 +1,143 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+)
+
+var errRollback = errors.New("roll back")
+
+// RunUserTransactionSuite checks that a user.Repository takes part in the
+// transactions of its transaction.Manager. newStorage must return an empty
+// repository and the manager for its storage each time it is called.
+func RunUserTransactionSuite(t *testing.T, newStorage func(t *testing.T) (user.Repository, transaction.Manager)) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo user.Repository, tm transaction.Manager)
+	}{
+		{"Commit", testTxCommit},
+		{"Rollback", testTxRollback},
+		{"Isolation", testTxIsolation},
+		{"Nested", testTxNested},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, tm := newStorage(t)
+			tt.run(t, repo, tm)
+		})
+	}
+}
+
+func testTxCommit(t *testing.T, repo user.Repository, tm transaction.Manager) {
+	ctx := context.Background()
+
+	existing := newUser(t, "existing", "existing@example.com")
+	mustCreate(t, repo, existing)
+
+	created := newUser(t, "created", "created@example.com")
+	err := tm.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, created); err != nil {
+			return err
+		}
+		// The transaction sees its own writes
+		if _, err := repo.FindByEmail(ctx, "created@example.com"); err != nil {
+			return err
+		}
+		existing.Username = "renamed"
+		return repo.Update(ctx, existing)
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() unexpected error = %v", err)
+	}
+
+	if _, err := repo.FindByID(ctx, created.ID); err != nil {
+		t.Errorf("FindByID() of the committed user unexpected error = %v", err)
+	}
+	if found, _ := repo.FindByID(ctx, existing.ID); found == nil || found.Username != "renamed" {
+		t.Errorf("committed Update() left %+v", found)
+	}
+}
+
+func testTxRollback(t *testing.T, repo user.Repository, tm transaction.Manager) {
+	ctx := context.Background()
+
+	existing := newUser(t, "existing", "existing@example.com")
+	mustCreate(t, repo, existing)
+
+	err := tm.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, newUser(t, "created", "created@example.com")); err != nil {
+			return err
+		}
+		existing.Username = "renamed"
+		if err := repo.Update(ctx, existing); err != nil {
+			return err
+		}
+		existing.RequestDeletion()
+		if err := repo.Update(ctx, existing); err != nil {
+			return err
+		}
+		if _, err := repo.Anonymize(ctx, existing.ID, time.Now().Add(time.Second)); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+	}
+
+	if _, err := repo.FindByEmail(ctx, "created@example.com"); err != userRepo.ErrUserNotFound {
+		t.Errorf("rolled back Create() is visible: FindByEmail() error = %v", err)
+	}
+	found, err := repo.FindByID(ctx, existing.ID)
+	if err != nil || found.Username != "existing" || found.IsAnonymized() {
+		t.Errorf("rolled back changes are visible: FindByID() = %+v, %v", found, err)
+	}
+}
+
+func testTxIsolation(t *testing.T, repo user.Repository, tm transaction.Manager) {
+	ctx := context.Background()
+
+	err := tm.WithinTx(ctx, func(txCtx context.Context) error {
+		if err := repo.Create(txCtx, newUser(t, "created", "created@example.com")); err != nil {
+			return err
+		}
+		if _, err := repo.FindByEmail(ctx, "created@example.com"); err != userRepo.ErrUserNotFound {
+			t.Errorf("uncommitted Create() is visible outside the transaction: FindByEmail() error = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() unexpected error = %v", err)
+	}
+}
+
+func testTxNested(t *testing.T, repo user.Repository, tm transaction.Manager) {
+	ctx := context.Background()
+
+	err := tm.WithinTx(ctx, func(ctx context.Context) error {
+		err := tm.WithinTx(ctx, func(ctx context.Context) error {
+			return repo.Create(ctx, newUser(t, "inner", "inner@example.com"))
+		})
+		if err != nil {
+			return err
+		}
+		// The inner call joined the outer transaction, so this undoes it
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errRollback)
+	}
+
+	if _, err := repo.FindByEmail(ctx, "inner@example.com"); err != userRepo.ErrUserNotFound {
+		t.Errorf("a nested transaction committed on its own: FindByEmail() error = %v", err)
+	}
+}
@@ -0,0 +1,518 @@
+// Package repositorytest holds conformance suites that every implementation
+// of a domain repository must pass, so the storage backends keep the
+// semantics of the in-memory reference implementation.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+)
+
+// concurrency is how many goroutines the concurrent tests start
+const concurrency = 8
+
+// RunUserRepositorySuite runs the user.Repository conformance tests.
+// newRepository must return an empty repository each time it is called;
+// the subtests do not run in parallel, so it may reuse one database.
+func RunUserRepositorySuite(t *testing.T, newRepository func(t *testing.T) user.Repository) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo user.Repository)
+	}{
+		{"RoundTrip", testUserRoundTrip},
+		{"IDGeneration", testUserIDGeneration},
+		{"ReturnsCopies", testUserReturnsCopies},
+		{"NotFound", testUserNotFound},
+		{"UniqueEmail", testUserUniqueEmail},
+		{"UpdateDuplicateEmail", testUserUpdateDuplicateEmail},
+		{"ConcurrentCreates", testUserConcurrentCreates},
+		{"ConcurrentCreatesSameEmail", testUserConcurrentCreatesSameEmail},
+		{"Versioning", testUserVersioning},
+		{"ConcurrentUpdates", testUserConcurrentUpdates},
+		{"PendingDeletion", testUserPendingDeletion},
+		{"CancelledDeletion", testUserCancelledDeletion},
+		{"ContextCanceled", testUserContextCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepository(t))
+		})
+	}
+}
+
+func newUser(t *testing.T, username, email string) *user.User {
+	t.Helper()
+
+	u, err := user.NewUser(username, email, "hashedpassword")
+	if err != nil {
+		t.Fatalf("NewUser() unexpected error = %v", err)
+	}
+	return u
+}
+
+func mustCreate(t *testing.T, repo user.Repository, u *user.User) {
+	t.Helper()
+
+	if err := repo.Create(context.Background(), u); err != nil {
+		t.Fatalf("Create(%s) unexpected error = %v", u.Email, err)
+	}
+}
+
+func testUserRoundTrip(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	u := newUser(t, "testuser", "test@example.com")
+	u.MarkEmailVerified()
+	u.MFARequired = true
+	u.TOTPSecret = "secret"
+	u.TOTPPendingSecret = "pending"
+	u.TOTPLastStep = 42
+	u.RecoveryCodeHashes = []string{"a", "b"}
+	mustCreate(t, repo, u)
+
+	found, err := repo.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("FindByID() unexpected error = %v", err)
+	}
+	if found.ID != u.ID || found.Username != u.Username || found.Email != u.Email ||
+		found.PasswordHash != u.PasswordHash || found.Role != u.Role {
+		t.Errorf("FindByID() = %+v, want %+v", found, u)
+	}
+	if !found.CreatedAt.Equal(u.CreatedAt) || !found.UpdatedAt.Equal(u.UpdatedAt) {
+		t.Errorf("timestamps = %v, %v; want %v, %v", found.CreatedAt, found.UpdatedAt, u.CreatedAt, u.UpdatedAt)
+	}
+	if !found.EmailVerified || found.EmailVerifiedAt == nil || !found.EmailVerifiedAt.Equal(*u.EmailVerifiedAt) {
+		t.Errorf("email verification = %v, %v; want true, %v", found.EmailVerified, found.EmailVerifiedAt, u.EmailVerifiedAt)
+	}
+	if !found.MFARequired || found.TOTPSecret != "secret" || found.TOTPPendingSecret != "pending" || found.TOTPLastStep != 42 {
+		t.Errorf("MFA fields = %v, %q, %q, %d", found.MFARequired, found.TOTPSecret, found.TOTPPendingSecret, found.TOTPLastStep)
+	}
+	if len(found.RecoveryCodeHashes) != 2 || found.RecoveryCodeHashes[0] != "a" || found.RecoveryCodeHashes[1] != "b" {
+		t.Errorf("RecoveryCodeHashes = %v, want [a b]", found.RecoveryCodeHashes)
+	}
+	if found.DeletionRequestedAt != nil || found.AnonymizedAt != nil {
+		t.Errorf("deletion fields = %v, %v; want nil", found.DeletionRequestedAt, found.AnonymizedAt)
+	}
+
+	found.Username = "updateduser"
+	found.RecoveryCodeHashes = nil
+	if err := repo.Update(ctx, found); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	updated, _ := repo.FindByEmail(ctx, "test@example.com")
+	if updated == nil || updated.Username != "updateduser" || len(updated.RecoveryCodeHashes) != 0 {
+		t.Errorf("FindByEmail() after Update() = %+v", updated)
+	}
+}
+
+func testUserIDGeneration(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	first := newUser(t, "first", "first@example.com")
+	second := newUser(t, "second", "second@example.com")
+	mustCreate(t, repo, first)
+	mustCreate(t, repo, second)
+	if first.ID == "" || second.ID == "" {
+		t.Fatal("Create() should generate an ID")
+	}
+	if first.ID == second.ID {
+		t.Errorf("Create() generated the same ID %q twice", first.ID)
+	}
+
+	preset := newUser(t, "preset", "preset@example.com")
+	preset.ID = "5b0c7c4e-3f7d-4d55-9c1a-8a4f1c9e2d10"
+	mustCreate(t, repo, preset)
+	if preset.ID != "5b0c7c4e-3f7d-4d55-9c1a-8a4f1c9e2d10" {
+		t.Errorf("Create() replaced the given ID with %q", preset.ID)
+	}
+	if _, err := repo.FindByID(ctx, preset.ID); err != nil {
+		t.Errorf("FindByID() of the given ID unexpected error = %v", err)
+	}
+
+	// A failed create leaves the user without an ID
+	duplicate := newUser(t, "duplicate", "first@example.com")
+	if err := repo.Create(ctx, duplicate); err == nil || duplicate.ID != "" {
+		t.Errorf("failed Create() = %v and set ID %q; want an error and no ID", err, duplicate.ID)
+	}
+}
+
+func testUserReturnsCopies(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	u := newUser(t, "testuser", "test@example.com")
+	mustCreate(t, repo, u)
+
+	// Changes to the created or found user are not stored until Update
+	u.Username = "changed"
+	found, _ := repo.FindByID(ctx, u.ID)
+	if found.Username != "testuser" {
+		t.Errorf("changing the created user changed the stored username to %q", found.Username)
+	}
+	found.Username = "changed"
+	again, _ := repo.FindByID(ctx, u.ID)
+	if again.Username != "testuser" {
+		t.Errorf("changing a found user changed the stored username to %q", again.Username)
+	}
+}
+
+func testUserNotFound(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	if _, err := repo.FindByEmail(ctx, "missing@example.com"); err != userRepo.ErrUserNotFound {
+		t.Errorf("FindByEmail() error = %v, want %v", err, userRepo.ErrUserNotFound)
+	}
+	if _, err := repo.FindByID(ctx, "missing"); err != userRepo.ErrUserNotFound {
+		t.Errorf("FindByID() error = %v, want %v", err, userRepo.ErrUserNotFound)
+	}
+
+	missing := newUser(t, "missing", "missing@example.com")
+	missing.ID = "missing"
+	if err := repo.Update(ctx, missing); err != userRepo.ErrUserNotFound {
+		t.Errorf("Update() error = %v, want %v", err, userRepo.ErrUserNotFound)
+	}
+	if _, err := repo.FindByEmail(ctx, "missing@example.com"); err != userRepo.ErrUserNotFound {
+		t.Errorf("Update() of a missing user stored it: FindByEmail() error = %v", err)
+	}
+	if _, err := repo.Anonymize(ctx, "missing", time.Now()); err != userRepo.ErrUserNotFound {
+		t.Errorf("Anonymize() error = %v, want %v", err, userRepo.ErrUserNotFound)
+	}
+}
+
+func testUserUniqueEmail(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	u := newUser(t, "testuser", "John@Example.com")
+	mustCreate(t, repo, u)
+
+	found, err := repo.FindByEmail(ctx, "JOHN@example.COM")
+	if err != nil || found.ID != u.ID {
+		t.Errorf("FindByEmail() in another case = %+v, %v; want the user", found, err)
+	}
+
+	for _, email := range []string{"John@Example.com", "john@example.com", "JOHN@EXAMPLE.COM"} {
+		// Stored as given, without NewUser canonicalizing it
+		other := &user.User{Username: "other", Email: email, PasswordHash: "hashedpassword", Role: user.DefaultRole}
+		if err := repo.Create(ctx, other); err != userRepo.ErrUserAlreadyExists {
+			t.Errorf("Create(%s) error = %v, want %v", email, err, userRepo.ErrUserAlreadyExists)
+		}
+	}
+}
+
+func testUserUpdateDuplicateEmail(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	u1 := newUser(t, "testuser1", "test1@example.com")
+	u2 := newUser(t, "testuser2", "test2@example.com")
+	mustCreate(t, repo, u1)
+	mustCreate(t, repo, u2)
+
+	u2.Email = "TEST1@example.com"
+	if err := repo.Update(ctx, u2); err != userRepo.ErrUserAlreadyExists {
+		t.Errorf("Update() error = %v, want %v", err, userRepo.ErrUserAlreadyExists)
+	}
+	found, _ := repo.FindByID(ctx, u2.ID)
+	if found.Email != "test2@example.com" {
+		t.Errorf("failed Update() changed the stored email to %v", found.Email)
+	}
+
+	// Keeping one's own email is not a clash
+	u1.Username = "renamed"
+	if err := repo.Update(ctx, u1); err != nil {
+		t.Errorf("Update() keeping the email unexpected error = %v", err)
+	}
+}
+
+func testUserConcurrentCreates(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	users := make([]*user.User, concurrency)
+	errs := make([]error, concurrency)
+	var wg sync.WaitGroup
+	for i := range users {
+		users[i] = newUser(t, fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.Create(ctx, users[i])
+		}(i)
+	}
+	wg.Wait()
+
+	ids := make(map[string]bool)
+	for i, u := range users {
+		if errs[i] != nil {
+			t.Errorf("Create(%s) unexpected error = %v", u.Email, errs[i])
+			continue
+		}
+		if ids[u.ID] {
+			t.Errorf("Create() generated the ID %q twice", u.ID)
+		}
+		ids[u.ID] = true
+
+		if found, err := repo.FindByID(ctx, u.ID); err != nil || found.Email != u.Email {
+			t.Errorf("FindByID(%s) = %+v, %v; want %s", u.ID, found, err, u.Email)
+		}
+	}
+}
+
+func testUserConcurrentCreatesSameEmail(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	errs := make([]error, concurrency)
+	var wg sync.WaitGroup
+	for i := range errs {
+		u := newUser(t, fmt.Sprintf("user%d", i), "same@example.com")
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.Create(ctx, u)
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch err {
+		case nil:
+			created++
+		case userRepo.ErrUserAlreadyExists:
+		default:
+			t.Errorf("Create() unexpected error = %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d concurrent creates with the same email succeeded, want 1", created)
+	}
+}
+
+func testUserVersioning(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	u := newUser(t, "testuser", "test@example.com")
+	mustCreate(t, repo, u)
+	if u.Version != 1 {
+		t.Fatalf("Create() set Version = %d, want 1", u.Version)
+	}
+
+	first, _ := repo.FindByID(ctx, u.ID)
+	second, _ := repo.FindByID(ctx, u.ID)
+	if first.Version != 1 {
+		t.Errorf("FindByID() Version = %d, want 1", first.Version)
+	}
+
+	first.Username = "first"
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Update() set Version = %d, want 2", first.Version)
+	}
+
+	// second was read before first was stored, so its change would be lost
+	second.Username = "second"
+	if err := repo.Update(ctx, second); err != user.ErrConcurrentModification {
+		t.Errorf("Update() from a stale version error = %v, want %v", err, user.ErrConcurrentModification)
+	}
+	if second.Version != 1 {
+		t.Errorf("failed Update() changed Version to %d", second.Version)
+	}
+	found, _ := repo.FindByID(ctx, u.ID)
+	if found.Username != "first" || found.Version != 2 {
+		t.Errorf("stored user = %q version %d, want %q version 2", found.Username, found.Version, "first")
+	}
+
+	// Updates from the same copy keep working, since Update advances it
+	first.Username = "again"
+	if err := repo.Update(ctx, first); err != nil || first.Version != 3 {
+		t.Errorf("second Update() = %v, Version %d; want nil, 3", err, first.Version)
+	}
+
+	first.RequestDeletion()
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if anonymized, err := repo.Anonymize(ctx, u.ID, time.Now().Add(time.Second)); err != nil || !anonymized {
+		t.Fatalf("Anonymize() = %v, %v; want true, nil", anonymized, err)
+	}
+	if err := repo.Update(ctx, first); err != user.ErrConcurrentModification {
+		t.Errorf("Update() after Anonymize() error = %v, want %v", err, user.ErrConcurrentModification)
+	}
+}
+
+func testUserConcurrentUpdates(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	u := newUser(t, "testuser", "test@example.com")
+	mustCreate(t, repo, u)
+
+	// Every copy is read before any update starts
+	copies := make([]*user.User, concurrency)
+	for i := range copies {
+		found, err := repo.FindByID(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("FindByID() unexpected error = %v", err)
+		}
+		found.Username = fmt.Sprintf("user%d", i)
+		copies[i] = found
+	}
+
+	errs := make([]error, concurrency)
+	var wg sync.WaitGroup
+	for i := range copies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.Update(ctx, copies[i])
+		}(i)
+	}
+	wg.Wait()
+
+	updated := 0
+	for _, err := range errs {
+		switch err {
+		case nil:
+			updated++
+		case user.ErrConcurrentModification:
+		default:
+			t.Errorf("Update() unexpected error = %v", err)
+		}
+	}
+	if updated != 1 {
+		t.Errorf("%d concurrent updates from one version succeeded, want 1", updated)
+	}
+	if found, _ := repo.FindByID(ctx, u.ID); found.Version != 2 {
+		t.Errorf("Version after the concurrent updates = %d, want 2", found.Version)
+	}
+}
+
+func testUserPendingDeletion(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	pending := newUser(t, "pending", "pending@example.com")
+	active := newUser(t, "active", "active@example.com")
+	mustCreate(t, repo, pending)
+	mustCreate(t, repo, active)
+	pending.RequestDeletion()
+	if err := repo.Update(ctx, pending); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+
+	if due, _ := repo.ListPendingDeletion(ctx, time.Now().Add(-time.Hour)); len(due) != 0 {
+		t.Errorf("ListPendingDeletion() before the grace period = %d users, want 0", len(due))
+	}
+	due, err := repo.ListPendingDeletion(ctx, time.Now().Add(time.Second))
+	if err != nil || len(due) != 1 || due[0].ID != pending.ID {
+		t.Fatalf("ListPendingDeletion() = %+v, %v; want the pending user", due, err)
+	}
+
+	if anonymized, err := repo.Anonymize(ctx, pending.ID, time.Now().Add(-time.Hour)); err != nil || anonymized {
+		t.Errorf("Anonymize() before the grace period = %v, %v; want false, nil", anonymized, err)
+	}
+	if anonymized, err := repo.Anonymize(ctx, active.ID, time.Now().Add(time.Second)); err != nil || anonymized {
+		t.Errorf("Anonymize() of an active user = %v, %v; want false, nil", anonymized, err)
+	}
+	if anonymized, err := repo.Anonymize(ctx, pending.ID, time.Now().Add(time.Second)); err != nil || !anonymized {
+		t.Fatalf("Anonymize() = %v, %v; want true, nil", anonymized, err)
+	}
+	if anonymized, _ := repo.Anonymize(ctx, pending.ID, time.Now().Add(time.Second)); anonymized {
+		t.Error("Anonymize() of an anonymized user should report false")
+	}
+	if _, err := repo.FindByEmail(ctx, "pending@example.com"); err != userRepo.ErrUserNotFound {
+		t.Errorf("FindByEmail() of an anonymized address error = %v, want %v", err, userRepo.ErrUserNotFound)
+	}
+	found, err := repo.FindByID(ctx, pending.ID)
+	if err != nil || !found.IsAnonymized() {
+		t.Errorf("FindByID() after Anonymize() = %+v, %v; want an anonymized user", found, err)
+	}
+	if due, _ := repo.ListPendingDeletion(ctx, time.Now().Add(time.Second)); len(due) != 0 {
+		t.Error("ListPendingDeletion() should skip anonymized users")
+	}
+	if found, _ := repo.FindByID(ctx, active.ID); found.IsAnonymized() || found.Email != "active@example.com" {
+		t.Errorf("Anonymize() of an active user changed it to %+v", found)
+	}
+}
+
+// testUserCancelledDeletion covers a sign-in cancelling the deletion
+// between the purge listing the account and anonymizing it
+func testUserCancelledDeletion(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+
+	u := newUser(t, "testuser", "test@example.com")
+	mustCreate(t, repo, u)
+	u.RequestDeletion()
+	if err := repo.Update(ctx, u); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+
+	cutoff := time.Now().Add(time.Second)
+	due, err := repo.ListPendingDeletion(ctx, cutoff)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("ListPendingDeletion() = %+v, %v; want the pending user", due, err)
+	}
+
+	u.CancelDeletion()
+	if err := repo.Update(ctx, u); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+
+	anonymized, err := repo.Anonymize(ctx, due[0].ID, cutoff)
+	if err != nil || anonymized {
+		t.Errorf("Anonymize() of a restored user = %v, %v; want false, nil", anonymized, err)
+	}
+	found, err := repo.FindByID(ctx, u.ID)
+	if err != nil || found.IsAnonymized() || found.IsPendingDeletion() || found.Email != "test@example.com" {
+		t.Errorf("FindByID() after Anonymize() = %+v, %v; want the restored user", found, err)
+	}
+	if found.Version != u.Version {
+		t.Errorf("Anonymize() of a restored user changed Version to %d, want %d", found.Version, u.Version)
+	}
+}
+
+func testUserContextCanceled(t *testing.T, repo user.Repository) {
+	u := newUser(t, "testuser", "test@example.com")
+	mustCreate(t, repo, u)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	created := newUser(t, "created", "created@example.com")
+	if err := repo.Create(ctx, created); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := repo.FindByEmail(ctx, u.Email); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindByEmail() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := repo.FindByID(ctx, u.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindByID() error = %v, want %v", err, context.Canceled)
+	}
+	u.Username = "canceled"
+	if err := repo.Update(ctx, u); !errors.Is(err, context.Canceled) {
+		t.Errorf("Update() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := repo.ListPendingDeletion(ctx, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListPendingDeletion() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := repo.Anonymize(ctx, u.ID, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Anonymize() error = %v, want %v", err, context.Canceled)
+	}
+
+	// None of the canceled calls took effect
+	background := context.Background()
+	if _, err := repo.FindByEmail(background, "created@example.com"); err != userRepo.ErrUserNotFound {
+		t.Errorf("canceled Create() stored the user: FindByEmail() error = %v", err)
+	}
+	found, err := repo.FindByID(background, u.ID)
+	if err != nil || found.Username != "testuser" || found.IsAnonymized() {
+		t.Errorf("canceled calls changed the user to %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/sqltx"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	"github.com/google/uuid"
+)
+
+const userColumns = `id, username, email, password_hash, role, created_at, updated_at,
+	email_verified, email_verified_at, mfa_required, totp_secret, totp_pending_secret,
+	totp_last_step, recovery_code_hashes, deletion_requested_at, anonymized_at, version`
+
+// UserRepository implements user.Repository on SQLite. It returns the same
+// errors as the in-memory repository.
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository creates a user repository on db, which must have been
+// migrated with migrations.Run
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Create adds a new user; the unique index on the canonical email rejects
+// duplicates
+func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
+	id := u.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	codes, err := json.Marshal(recoveryCodes(u))
+	if err != nil {
+		return err
+	}
+
+	_, err = sqltx.From(ctx, r.db).ExecContext(ctx, `INSERT INTO users (`+userColumns+`, email_canonical)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)`,
+		id, u.Username, u.Email, u.PasswordHash, string(u.Role), u.CreatedAt.UTC(), u.UpdatedAt.UTC(),
+		u.EmailVerified, utcPtr(u.EmailVerifiedAt), u.MFARequired, u.TOTPSecret, u.TOTPPendingSecret,
+		u.TOTPLastStep, string(codes), utcPtr(u.DeletionRequestedAt), utcPtr(u.AnonymizedAt),
+		user.CanonicalEmail(u.Email),
+	)
+	if isUniqueViolation(err) {
+		return userRepo.ErrUserAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	u.ID = id
+	u.Version = 1
+	return nil
+}
+
+// FindByEmail retrieves a user by email, comparing canonical forms
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	row := sqltx.From(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email_canonical = ?`, user.CanonicalEmail(email))
+	return scanUser(row)
+}
+
+// FindByID retrieves a user by ID
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*user.User, error) {
+	row := sqltx.From(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+	return scanUser(row)
+}
+
+// Update updates an existing user from its stored version. The email must
+// stay unique across users.
+func (r *UserRepository) Update(ctx context.Context, u *user.User) error {
+	return update(ctx, sqltx.From(ctx, r.db), u)
+}
+
+// ListPendingDeletion returns users whose deletion was requested before
+// requestedBefore and who have not been anonymized yet
+func (r *UserRepository) ListPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*user.User, error) {
+	rows, err := sqltx.From(ctx, r.db).QueryContext(ctx, `SELECT `+userColumns+` FROM users
+		WHERE deletion_requested_at < ? AND anonymized_at IS NULL`, requestedBefore.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*user.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Anonymize erases the personal data of the user if its deletion is still
+// due. The transaction holds the write lock from the start, so a
+// concurrent sign-in cannot cancel the deletion in between.
+func (r *UserRepository) Anonymize(ctx context.Context, id string, requestedBefore time.Time) (bool, error) {
+	anonymized := false
+	err := sqltx.WithinTx(ctx, r.db, func(ctx context.Context) error {
+		q := sqltx.From(ctx, r.db)
+		u, err := scanUser(q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+		if err != nil {
+			return err
+		}
+		if !u.IsDeletionDue(requestedBefore) {
+			return nil
+		}
+		u.Anonymize()
+		if err := update(ctx, q, u); err != nil {
+			return err
+		}
+		anonymized = true
+		return nil
+	})
+	return anonymized, err
+}
+
+func update(ctx context.Context, db sqltx.Querier, u *user.User) error {
+	codes, err := json.Marshal(recoveryCodes(u))
+	if err != nil {
+		return err
+	}
+
+	result, err := db.ExecContext(ctx, `UPDATE users SET
+		username = ?, email = ?, email_canonical = ?, password_hash = ?, role = ?,
+		created_at = ?, updated_at = ?, email_verified = ?, email_verified_at = ?,
+		mfa_required = ?, totp_secret = ?, totp_pending_secret = ?, totp_last_step = ?,
+		recovery_code_hashes = ?, deletion_requested_at = ?, anonymized_at = ?,
+		version = version + 1
+		WHERE id = ? AND version = ?`,
+		u.Username, u.Email, user.CanonicalEmail(u.Email), u.PasswordHash, string(u.Role),
+		u.CreatedAt.UTC(), u.UpdatedAt.UTC(), u.EmailVerified, utcPtr(u.EmailVerifiedAt),
+		u.MFARequired, u.TOTPSecret, u.TOTPPendingSecret, u.TOTPLastStep,
+		string(codes), utcPtr(u.DeletionRequestedAt), utcPtr(u.AnonymizedAt),
+		u.ID, u.Version,
+	)
+	if isUniqueViolation(err) {
+		return userRepo.ErrUserAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// Either the user is gone or its version moved on
+		var exists bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)`, u.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return user.ErrConcurrentModification
+		}
+		return userRepo.ErrUserNotFound
+	}
+
+	u.Version++
+	return nil
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (*user.User, error) {
+	var (
+		u                                                  user.User
+		role, codes                                        string
+		emailVerifiedAt, deletionRequestedAt, anonymizedAt sql.NullTime
+	)
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &role, &u.CreatedAt, &u.UpdatedAt,
+		&u.EmailVerified, &emailVerifiedAt, &u.MFARequired, &u.TOTPSecret, &u.TOTPPendingSecret,
+		&u.TOTPLastStep, &codes, &deletionRequestedAt, &anonymizedAt, &u.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, userRepo.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(codes), &u.RecoveryCodeHashes); err != nil {
+		return nil, err
+	}
+
+	u.Role = user.Role(role)
+	u.EmailVerifiedAt = timePtr(emailVerifiedAt)
+	u.DeletionRequestedAt = timePtr(deletionRequestedAt)
+	u.AnonymizedAt = timePtr(anonymizedAt)
+	if len(u.RecoveryCodeHashes) == 0 {
+		u.RecoveryCodeHashes = nil
+	}
+	return &u, nil
+}
+
+// recoveryCodes returns the user's recovery code hashes, never nil, so the
+// column always holds a JSON array
+func recoveryCodes(u *user.User) []string {
+	if u.RecoveryCodeHashes == nil {
+		return []string{}
+	}
+	return u.RecoveryCodeHashes
+}
+
+// utcPtr converts t to UTC, so stored times compare in chronological order
+func utcPtr(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
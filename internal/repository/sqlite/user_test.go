@@ -0,0 +1,96 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/migrations"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/repositorytest"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/sqlite"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/sqltx"
+)
+
+// openTestDB opens and migrates a database file in a temporary directory
+func openTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := migrations.Run(ctx, db, migrations.SQLite); err != nil {
+		t.Fatalf("migrations.Run() unexpected error = %v", err)
+	}
+	return db
+}
+
+func TestUserRepository_Suite(t *testing.T) {
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) user.Repository {
+		return sqlite.NewUserRepository(openTestDB(t, filepath.Join(t.TempDir(), "test.db")))
+	})
+}
+
+func TestUserRepository_TransactionSuite(t *testing.T) {
+	repositorytest.RunUserTransactionSuite(t, func(t *testing.T) (user.Repository, transaction.Manager) {
+		db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+		return sqlite.NewUserRepository(db), sqltx.NewManager(db)
+	})
+}
+
+func TestOpen_WAL(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v; want wal", mode, err)
+	}
+	var timeout int
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil || timeout != 5000 {
+		t.Errorf("busy_timeout = %d, %v; want 5000", timeout, err)
+	}
+}
+
+func TestMigrations_Idempotent(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+
+	applied, err := migrations.Run(context.Background(), db, migrations.SQLite)
+	if err != nil || applied != 0 {
+		t.Errorf("migrations.Run() again = %d, %v; want 0, nil", applied, err)
+	}
+}
+
+func TestUserRepository_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	u, _ := user.NewUser("testuser", "test@example.com", "hashedpassword")
+	u.RecoveryCodeHashes = []string{"a", "b"}
+	u.MarkEmailVerified()
+	db := openTestDB(t, path)
+	if err := sqlite.NewUserRepository(db).Create(ctx, u); err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	db.Close()
+
+	found, err := sqlite.NewUserRepository(openTestDB(t, path)).FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("FindByID() after reopening unexpected error = %v", err)
+	}
+	if found.Email != u.Email || found.Role != u.Role || !found.EmailVerified || found.EmailVerifiedAt == nil {
+		t.Errorf("FindByID() = %+v, want %+v", found, u)
+	}
+	if !found.CreatedAt.Equal(u.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want %v", found.CreatedAt, u.CreatedAt)
+	}
+	if len(found.RecoveryCodeHashes) != 2 || found.RecoveryCodeHashes[1] != "b" {
+		t.Errorf("RecoveryCodeHashes = %v, want [a b]", found.RecoveryCodeHashes)
+	}
+}
@@ -0,0 +1,68 @@
+// Package sqltx runs units of work as database/sql transactions. The
+// transaction travels in the context, so every repository on the same
+// *sql.DB that is called with that context takes part in it.
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is satisfied by *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// dbTx is a transaction on db
+type dbTx struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// Manager implements transaction.Manager on a database
+type Manager struct {
+	db *sql.DB
+}
+
+// NewManager creates a transaction manager for db
+func NewManager(db *sql.DB) *Manager {
+	return &Manager{db: db}
+}
+
+// WithinTx runs fn in a transaction on the manager's database; see
+// transaction.Manager
+func (m *Manager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithinTx(ctx, m.db, fn)
+}
+
+// WithinTx runs fn in a transaction on db, committing when fn returns nil.
+// If ctx already carries a transaction on db, fn joins it.
+func WithinTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if t, ok := ctx.Value(txKey{}).(*dbTx); ok && t.db == db {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &dbTx{db: db, tx: tx})); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// From returns the transaction on db carried by ctx, or db itself outside
+// a transaction
+func From(ctx context.Context, db *sql.DB) Querier {
+	if t, ok := ctx.Value(txKey{}).(*dbTx); ok && t.db == db {
+		return t.tx
+	}
+	return db
+}
@@ -3,85 +3,190 @@ package user
 import (
 	"context"
 	"errors"
-	"sync"
+	"time"
 
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
 	"github.com/google/uuid"
 )
 
 var (
-	ErrUserNotFound     = errors.New("user not found")
+	ErrUserNotFound      = errors.New("user not found")
 	ErrUserAlreadyExists = errors.New("user already exists")
 )
 
-// InMemoryRepository implements user.Repository interface using in-memory storage
+// InMemoryRepository implements user.Repository interface using in-memory
+// storage. It is the reference the other backends are tested against; see
+// repositorytest.RunUserRepositorySuite. It takes part in memtx
+// transactions.
 type InMemoryRepository struct {
-	users map[string]*user.User
-	mu    sync.RWMutex
+	users *memtx.Store[string, *user.User]
+	// emails maps canonical emails to user IDs, so a transaction checking
+	// that an email is free only conflicts with changes to that email
+	emails *memtx.Store[string, string]
 }
 
 // NewInMemoryRepository creates a new in-memory user repository
 func NewInMemoryRepository() *InMemoryRepository {
 	return &InMemoryRepository{
-		users: make(map[string]*user.User),
+		users:  memtx.NewStore[string, *user.User](),
+		emails: memtx.NewStore[string, string](),
 	}
 }
 
 // Create adds a new user to the repository
 func (r *InMemoryRepository) Create(ctx context.Context, u *user.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	// Check if user with email already exists
-	for _, existingUser := range r.users {
-		if existingUser.Email == u.Email {
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		// Check if user with email already exists
+		email := user.CanonicalEmail(u.Email)
+		if _, taken := r.emails.Get(ctx, email); taken {
 			return ErrUserAlreadyExists
 		}
-	}
 
-	// Generate ID if not present
-	if u.ID == "" {
-		u.ID = uuid.New().String()
-	}
+		// Generate ID if not present
+		if u.ID == "" {
+			u.ID = uuid.New().String()
+		}
+		u.Version = 1
 
-	r.users[u.ID] = u
-	return nil
+		stored := *u
+		r.users.Put(ctx, u.ID, &stored)
+		r.emails.Put(ctx, email, u.ID)
+		return nil
+	})
 }
 
-// FindByEmail retrieves a user by email
+// FindByEmail retrieves a user by email, comparing canonical forms
 func (r *InMemoryRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	for _, u := range r.users {
-		if u.Email == email {
-			return u, nil
+	var found *user.User
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		email := user.CanonicalEmail(email)
+		id, exists := r.emails.Get(ctx, email)
+		if !exists {
+			return ErrUserNotFound
 		}
+		u, exists := r.users.Get(ctx, id)
+		if !exists || user.CanonicalEmail(u.Email) != email {
+			return ErrUserNotFound
+		}
+		found = u
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrUserNotFound
+	copied := *found
+	return &copied, nil
 }
 
 // FindByID retrieves a user by ID
 func (r *InMemoryRepository) FindByID(ctx context.Context, id string) (*user.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	u, exists := r.users[id]
+	u, exists := r.users.Get(ctx, id)
 	if !exists {
 		return nil, ErrUserNotFound
 	}
-	return u, nil
+	found := *u
+	return &found, nil
 }
 
-// Update updates an existing user
+// Update updates an existing user from its stored version. The email must
+// stay unique across users.
 func (r *InMemoryRepository) Update(ctx context.Context, u *user.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	stored := *u
+	stored.Version++
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		current, exists := r.users.Get(ctx, u.ID)
+		if !exists {
+			return ErrUserNotFound
+		}
+		if current.Version != u.Version {
+			return user.ErrConcurrentModification
+		}
+		return r.replace(ctx, current, &stored)
+	})
+	if err != nil {
+		return err
+	}
+	u.Version = stored.Version
+	return nil
+}
 
-	if _, exists := r.users[u.ID]; !exists {
-		return ErrUserNotFound
+// ListPendingDeletion returns users whose deletion was requested before
+// requestedBefore and who have not been anonymized yet
+func (r *InMemoryRepository) ListPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	r.users[u.ID] = u
+	due := r.users.Select(ctx, func(_ string, u *user.User) bool {
+		return u.IsDeletionDue(requestedBefore)
+	})
+	pending := make([]*user.User, 0, len(due))
+	for _, u := range due {
+		found := *u
+		pending = append(pending, &found)
+	}
+	return pending, nil
+}
+
+// Anonymize erases the personal data of the user if its deletion is still due
+func (r *InMemoryRepository) Anonymize(ctx context.Context, id string, requestedBefore time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	anonymized := false
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		anonymized = false
+		u, exists := r.users.Get(ctx, id)
+		if !exists {
+			return ErrUserNotFound
+		}
+		// The deletion may have been cancelled since it was listed
+		if !u.IsDeletionDue(requestedBefore) {
+			return nil
+		}
+
+		updated := *u
+		updated.Anonymize()
+		updated.Version++
+		if err := r.replace(ctx, u, &updated); err != nil {
+			return err
+		}
+		anonymized = true
+		return nil
+	})
+	return anonymized, err
+}
+
+// replace stores updated in place of current, moving its email in the
+// index if it changed
+func (r *InMemoryRepository) replace(ctx context.Context, current, updated *user.User) error {
+	oldEmail := user.CanonicalEmail(current.Email)
+	newEmail := user.CanonicalEmail(updated.Email)
+	if newEmail != oldEmail {
+		if id, taken := r.emails.Get(ctx, newEmail); taken && id != updated.ID {
+			return ErrUserAlreadyExists
+		}
+		r.emails.Delete(ctx, oldEmail)
+		r.emails.Put(ctx, newEmail, updated.ID)
+	}
+	r.users.Put(ctx, updated.ID, updated)
 	return nil
 }
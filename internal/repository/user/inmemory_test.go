@@ -3,11 +3,27 @@ package user_test
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/repositorytest"
 	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
 )
 
+func TestInMemoryRepository_Suite(t *testing.T) {
+	repositorytest.RunUserRepositorySuite(t, func(t *testing.T) user.Repository {
+		return userRepo.NewInMemoryRepository()
+	})
+}
+
+func TestInMemoryRepository_TransactionSuite(t *testing.T) {
+	repositorytest.RunUserTransactionSuite(t, func(t *testing.T) (user.Repository, transaction.Manager) {
+		return userRepo.NewInMemoryRepository(), memtx.NewManager()
+	})
+}
+
 func TestInMemoryRepository_Create(t *testing.T) {
 	repo := userRepo.NewInMemoryRepository()
 	ctx := context.Background()
@@ -56,6 +72,24 @@ func TestInMemoryRepository_FindByEmail(t *testing.T) {
 	}
 }
 
+func TestInMemoryRepository_FindByEmail_CaseInsensitive(t *testing.T) {
+	repo := userRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	u, _ := user.NewUser("testuser", "John@Example.com", "hashedpassword")
+	_ = repo.Create(ctx, u)
+
+	if _, err := repo.FindByEmail(ctx, "JOHN@example.COM"); err != nil {
+		t.Errorf("FindByEmail() unexpected error = %v", err)
+	}
+
+	// A stored address that was not canonicalized still clashes
+	other := &user.User{Username: "other", Email: "JOHN@EXAMPLE.COM", PasswordHash: "hashedpassword"}
+	if err := repo.Create(ctx, other); err != userRepo.ErrUserAlreadyExists {
+		t.Errorf("Create() expected ErrUserAlreadyExists, got %v", err)
+	}
+}
+
 func TestInMemoryRepository_FindByEmailNotFound(t *testing.T) {
 	repo := userRepo.NewInMemoryRepository()
 	ctx := context.Background()
@@ -102,3 +136,56 @@ func TestInMemoryRepository_Update(t *testing.T) {
 		t.Errorf("Update() username = %v, want %v", found.Username, "updateduser")
 	}
 }
+
+func TestInMemoryRepository_UpdateDuplicateEmail(t *testing.T) {
+	repo := userRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	u1, _ := user.NewUser("testuser1", "test1@example.com", "hashedpassword")
+	u2, _ := user.NewUser("testuser2", "test2@example.com", "hashedpassword")
+	_ = repo.Create(ctx, u1)
+	_ = repo.Create(ctx, u2)
+
+	u2.Email = "test1@example.com"
+	if err := repo.Update(ctx, u2); err != userRepo.ErrUserAlreadyExists {
+		t.Errorf("Update() expected ErrUserAlreadyExists, got %v", err)
+	}
+
+	found, _ := repo.FindByID(ctx, u2.ID)
+	if found.Email != "test2@example.com" {
+		t.Errorf("failed Update() changed the stored email to %v", found.Email)
+	}
+}
+
+func TestInMemoryRepository_Anonymize(t *testing.T) {
+	repo := userRepo.NewInMemoryRepository()
+	ctx := context.Background()
+
+	pending, _ := user.NewUser("pending", "pending@example.com", "hashedpassword")
+	active, _ := user.NewUser("active", "active@example.com", "hashedpassword")
+	repo.Create(ctx, pending)
+	repo.Create(ctx, active)
+	pending.RequestDeletion()
+	repo.Update(ctx, pending)
+
+	if due, _ := repo.ListPendingDeletion(ctx, time.Now().Add(-time.Hour)); len(due) != 0 {
+		t.Errorf("ListPendingDeletion() before the grace period = %d users, want 0", len(due))
+	}
+	due, _ := repo.ListPendingDeletion(ctx, time.Now().Add(time.Second))
+	if len(due) != 1 || due[0].ID != pending.ID {
+		t.Fatalf("ListPendingDeletion() = %+v, want the pending user", due)
+	}
+
+	if anonymized, err := repo.Anonymize(ctx, pending.ID, time.Now().Add(time.Second)); err != nil || !anonymized {
+		t.Fatalf("Anonymize() = %v, %v; want true, nil", anonymized, err)
+	}
+	if _, err := repo.FindByEmail(ctx, "pending@example.com"); err != userRepo.ErrUserNotFound {
+		t.Errorf("FindByEmail() of an anonymized address error = %v, want %v", err, userRepo.ErrUserNotFound)
+	}
+	if due, _ := repo.ListPendingDeletion(ctx, time.Now().Add(time.Second)); len(due) != 0 {
+		t.Error("ListPendingDeletion() should skip anonymized users")
+	}
+	if _, err := repo.Anonymize(ctx, "missing", time.Now()); err != userRepo.ErrUserNotFound {
+		t.Errorf("Anonymize() of an unknown user error = %v, want %v", err, userRepo.ErrUserNotFound)
+	}
+}
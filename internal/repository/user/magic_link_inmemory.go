@@ -0,0 +1,112 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMagicLinkNotFound = errors.New("magic link not found")
+)
+
+// InMemoryMagicLinkRepository implements user.MagicLinkRepository using in-memory storage
+type InMemoryMagicLinkRepository struct {
+	links         map[string]*user.MagicLink
+	byHash        map[string]string
+	sweepInterval time.Duration
+	lastSweep     time.Time
+	mu            sync.RWMutex
+}
+
+// NewInMemoryMagicLinkRepository creates a new in-memory magic link repository
+func NewInMemoryMagicLinkRepository() *InMemoryMagicLinkRepository {
+	return &InMemoryMagicLinkRepository{
+		links:         make(map[string]*user.MagicLink),
+		byHash:        make(map[string]string),
+		sweepInterval: defaultSweepInterval,
+		lastSweep:     time.Now(),
+	}
+}
+
+// Create stores a new magic link
+func (r *InMemoryMagicLinkRepository) Create(ctx context.Context, l *user.MagicLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+
+	stored := *l
+	r.links[l.ID] = &stored
+	r.byHash[l.TokenHash] = l.ID
+	r.sweepLocked(time.Now())
+	return nil
+}
+
+// FindByHash retrieves a magic link by the hash of its token
+func (r *InMemoryMagicLinkRepository) FindByHash(ctx context.Context, tokenHash string) (*user.MagicLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, exists := r.links[r.byHash[tokenHash]]
+	if !exists {
+		return nil, ErrMagicLinkNotFound
+	}
+
+	found := *l
+	return &found, nil
+}
+
+// MarkUsed flags a magic link as used, reporting false if it already was
+func (r *InMemoryMagicLinkRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, exists := r.links[id]
+	if !exists {
+		return false, ErrMagicLinkNotFound
+	}
+	if l.UsedAt != nil {
+		return false, nil
+	}
+
+	l.UsedAt = &usedAt
+	return true, nil
+}
+
+// InvalidateAllForUser marks every unused link belonging to the user as used
+func (r *InMemoryMagicLinkRepository) InvalidateAllForUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, l := range r.links {
+		if l.UserID == userID && l.UsedAt == nil {
+			l.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// sweepLocked drops expired links; the caller must hold the write lock
+func (r *InMemoryMagicLinkRepository) sweepLocked(now time.Time) {
+	if now.Sub(r.lastSweep) < r.sweepInterval {
+		return
+	}
+
+	for id, l := range r.links {
+		if l.IsExpired(now) {
+			if r.byHash[l.TokenHash] == id {
+				delete(r.byHash, l.TokenHash)
+			}
+			delete(r.links, id)
+		}
+	}
+	r.lastSweep = now
+}
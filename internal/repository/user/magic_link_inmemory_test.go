@@ -0,0 +1,58 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+)
+
+func TestInMemoryMagicLinkRepository_MarkUsed(t *testing.T) {
+	repo := userRepo.NewInMemoryMagicLinkRepository()
+	ctx := context.Background()
+
+	link, _ := user.NewMagicLink("user123", "hash123", "nonce123", time.Minute)
+	_ = repo.Create(ctx, link)
+
+	ok, err := repo.MarkUsed(ctx, link.ID, time.Now())
+	if err != nil || !ok {
+		t.Fatalf("MarkUsed() first call = %v, %v; want true, nil", ok, err)
+	}
+
+	ok, err = repo.MarkUsed(ctx, link.ID, time.Now())
+	if err != nil || ok {
+		t.Errorf("MarkUsed() second call = %v, %v; want false, nil", ok, err)
+	}
+
+	if _, err := repo.MarkUsed(ctx, "missing", time.Now()); err != userRepo.ErrMagicLinkNotFound {
+		t.Errorf("MarkUsed() expected ErrMagicLinkNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryMagicLinkRepository_InvalidateAllForUser(t *testing.T) {
+	repo := userRepo.NewInMemoryMagicLinkRepository()
+	ctx := context.Background()
+
+	mine, _ := user.NewMagicLink("user123", "hash1", "nonce1", time.Minute)
+	other, _ := user.NewMagicLink("user456", "hash2", "nonce2", time.Minute)
+	_ = repo.Create(ctx, mine)
+	_ = repo.Create(ctx, other)
+
+	if err := repo.InvalidateAllForUser(ctx, "user123"); err != nil {
+		t.Fatalf("InvalidateAllForUser() unexpected error = %v", err)
+	}
+
+	found, _ := repo.FindByHash(ctx, "hash1")
+	if !found.IsUsed() {
+		t.Error("link of the user should be invalidated")
+	}
+	found, _ = repo.FindByHash(ctx, "hash2")
+	if found.IsUsed() {
+		t.Error("link of another user should not be invalidated")
+	}
+	if _, err := repo.FindByHash(ctx, "missing"); err != userRepo.ErrMagicLinkNotFound {
+		t.Errorf("FindByHash() expected ErrMagicLinkNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPasswordResetTokenNotFound = errors.New("password reset token not found")
+)
+
+// InMemoryPasswordResetTokenRepository implements user.PasswordResetTokenRepository using in-memory storage.
+// It takes part in memtx transactions.
+type InMemoryPasswordResetTokenRepository struct {
+	tokens *memtx.Store[string, *user.PasswordResetToken]
+	// byHash maps token hashes to token IDs
+	byHash        *memtx.Store[string, string]
+	sweepInterval time.Duration
+	lastSweep     time.Time
+	sweepMu       sync.Mutex
+}
+
+// NewInMemoryPasswordResetTokenRepository creates a new in-memory password reset token repository
+func NewInMemoryPasswordResetTokenRepository() *InMemoryPasswordResetTokenRepository {
+	return &InMemoryPasswordResetTokenRepository{
+		tokens:        memtx.NewStore[string, *user.PasswordResetToken](),
+		byHash:        memtx.NewStore[string, string](),
+		sweepInterval: defaultSweepInterval,
+		lastSweep:     time.Now(),
+	}
+}
+
+// Create stores a new password reset token
+func (r *InMemoryPasswordResetTokenRepository) Create(ctx context.Context, t *user.PasswordResetToken) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	stored := *t
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		r.tokens.Put(ctx, stored.ID, &stored)
+		r.byHash.Put(ctx, stored.TokenHash, stored.ID)
+		r.sweep(ctx, time.Now())
+		return nil
+	})
+}
+
+// FindByHash retrieves a password reset token by the hash of its value
+func (r *InMemoryPasswordResetTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*user.PasswordResetToken, error) {
+	var found *user.PasswordResetToken
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		id, _ := r.byHash.Get(ctx, tokenHash)
+		t, exists := r.tokens.Get(ctx, id)
+		if !exists {
+			return ErrPasswordResetTokenNotFound
+		}
+		found = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied := *found
+	return &copied, nil
+}
+
+// MarkUsed flags a password reset token as used, reporting false if it already was
+func (r *InMemoryPasswordResetTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	marked := false
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		marked = false
+		t, exists := r.tokens.Get(ctx, id)
+		if !exists {
+			return ErrPasswordResetTokenNotFound
+		}
+		if t.UsedAt != nil {
+			return nil
+		}
+
+		updated := *t
+		updated.UsedAt = &usedAt
+		r.tokens.Put(ctx, id, &updated)
+		marked = true
+		return nil
+	})
+	return marked, err
+}
+
+// InvalidateAllForUser marks every unused token belonging to the user as used
+func (r *InMemoryPasswordResetTokenRepository) InvalidateAllForUser(ctx context.Context, userID string) error {
+	now := time.Now()
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		unused := r.tokens.Select(ctx, func(_ string, t *user.PasswordResetToken) bool {
+			return t.UserID == userID && t.UsedAt == nil
+		})
+		for id, t := range unused {
+			updated := *t
+			updated.UsedAt = &now
+			r.tokens.Put(ctx, id, &updated)
+		}
+		return nil
+	})
+}
+
+// sweep drops expired tokens at most once per sweep interval
+func (r *InMemoryPasswordResetTokenRepository) sweep(ctx context.Context, now time.Time) {
+	r.sweepMu.Lock()
+	due := now.Sub(r.lastSweep) >= r.sweepInterval
+	if due {
+		r.lastSweep = now
+	}
+	r.sweepMu.Unlock()
+	if !due {
+		return
+	}
+
+	expired := r.tokens.Select(ctx, func(_ string, t *user.PasswordResetToken) bool {
+		return t.IsExpired(now)
+	})
+	for id, t := range expired {
+		if indexed, _ := r.byHash.Get(ctx, t.TokenHash); indexed == id {
+			r.byHash.Delete(ctx, t.TokenHash)
+		}
+		r.tokens.Delete(ctx, id)
+	}
+}
@@ -0,0 +1,55 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+)
+
+func TestInMemoryPasswordResetTokenRepository_MarkUsed(t *testing.T) {
+	repo := userRepo.NewInMemoryPasswordResetTokenRepository()
+	ctx := context.Background()
+
+	token, _ := user.NewPasswordResetToken("user123", "hash123", time.Hour)
+	_ = repo.Create(ctx, token)
+
+	ok, err := repo.MarkUsed(ctx, token.ID, time.Now())
+	if err != nil || !ok {
+		t.Fatalf("MarkUsed() first call = %v, %v; want true, nil", ok, err)
+	}
+
+	ok, err = repo.MarkUsed(ctx, token.ID, time.Now())
+	if err != nil || ok {
+		t.Errorf("MarkUsed() second call = %v, %v; want false, nil", ok, err)
+	}
+
+	if _, err := repo.MarkUsed(ctx, "missing", time.Now()); err != userRepo.ErrPasswordResetTokenNotFound {
+		t.Errorf("MarkUsed() expected ErrPasswordResetTokenNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryPasswordResetTokenRepository_InvalidateAllForUser(t *testing.T) {
+	repo := userRepo.NewInMemoryPasswordResetTokenRepository()
+	ctx := context.Background()
+
+	mine, _ := user.NewPasswordResetToken("user123", "hash1", time.Hour)
+	other, _ := user.NewPasswordResetToken("user456", "hash2", time.Hour)
+	_ = repo.Create(ctx, mine)
+	_ = repo.Create(ctx, other)
+
+	if err := repo.InvalidateAllForUser(ctx, "user123"); err != nil {
+		t.Fatalf("InvalidateAllForUser() unexpected error = %v", err)
+	}
+
+	found, _ := repo.FindByHash(ctx, "hash1")
+	if !found.IsUsed() {
+		t.Error("token of the user should be invalidated")
+	}
+	found, _ = repo.FindByHash(ctx, "hash2")
+	if found.IsUsed() {
+		t.Error("token of another user should not be invalidated")
+	}
+}
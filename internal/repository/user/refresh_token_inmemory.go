@@ -0,0 +1,150 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+)
+
+// defaultSweepInterval is how often the in-memory token stores drop expired entries
+const defaultSweepInterval = time.Minute
+
+// InMemoryRefreshTokenRepository implements user.RefreshTokenRepository using in-memory storage.
+// Tokens are kept until they expire, so that reuse of a rotated token is still detected.
+// It takes part in memtx transactions.
+type InMemoryRefreshTokenRepository struct {
+	tokens *memtx.Store[string, *user.RefreshToken]
+	// byHash maps token hashes to token IDs
+	byHash        *memtx.Store[string, string]
+	sweepInterval time.Duration
+	lastSweep     time.Time
+	sweepMu       sync.Mutex
+}
+
+// NewInMemoryRefreshTokenRepository creates a new in-memory refresh token repository
+func NewInMemoryRefreshTokenRepository() *InMemoryRefreshTokenRepository {
+	return &InMemoryRefreshTokenRepository{
+		tokens:        memtx.NewStore[string, *user.RefreshToken](),
+		byHash:        memtx.NewStore[string, string](),
+		sweepInterval: defaultSweepInterval,
+		lastSweep:     time.Now(),
+	}
+}
+
+// Create stores a new refresh token
+func (r *InMemoryRefreshTokenRepository) Create(ctx context.Context, t *user.RefreshToken) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	stored := *t
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		r.tokens.Put(ctx, stored.ID, &stored)
+		r.byHash.Put(ctx, stored.TokenHash, stored.ID)
+		r.sweep(ctx, time.Now())
+		return nil
+	})
+}
+
+// FindByHash retrieves a refresh token by the hash of its value
+func (r *InMemoryRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*user.RefreshToken, error) {
+	var found *user.RefreshToken
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		id, _ := r.byHash.Get(ctx, tokenHash)
+		t, exists := r.tokens.Get(ctx, id)
+		if !exists {
+			return ErrRefreshTokenNotFound
+		}
+		found = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied := *found
+	return &copied, nil
+}
+
+// MarkUsed flags a refresh token as used, reporting false if it already was
+func (r *InMemoryRefreshTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	marked := false
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		marked = false
+		t, exists := r.tokens.Get(ctx, id)
+		if !exists {
+			return ErrRefreshTokenNotFound
+		}
+		if t.UsedAt != nil {
+			return nil
+		}
+
+		updated := *t
+		updated.UsedAt = &usedAt
+		r.tokens.Put(ctx, id, &updated)
+		marked = true
+		return nil
+	})
+	return marked, err
+}
+
+// RevokeFamily revokes every token sharing the given family ID
+func (r *InMemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.revokeWhere(ctx, func(t *user.RefreshToken) bool {
+		return t.FamilyID == familyID
+	})
+}
+
+// RevokeAllForUser revokes every refresh token belonging to the user
+func (r *InMemoryRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	return r.revokeWhere(ctx, func(t *user.RefreshToken) bool {
+		return t.UserID == userID
+	})
+}
+
+// revokeWhere revokes every unrevoked token matching the predicate
+func (r *InMemoryRefreshTokenRepository) revokeWhere(ctx context.Context, match func(t *user.RefreshToken) bool) error {
+	now := time.Now()
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		matches := r.tokens.Select(ctx, func(_ string, t *user.RefreshToken) bool {
+			return t.RevokedAt == nil && match(t)
+		})
+		for id, t := range matches {
+			updated := *t
+			updated.RevokedAt = &now
+			r.tokens.Put(ctx, id, &updated)
+		}
+		return nil
+	})
+}
+
+// sweep drops expired tokens at most once per sweep interval
+func (r *InMemoryRefreshTokenRepository) sweep(ctx context.Context, now time.Time) {
+	r.sweepMu.Lock()
+	due := now.Sub(r.lastSweep) >= r.sweepInterval
+	if due {
+		r.lastSweep = now
+	}
+	r.sweepMu.Unlock()
+	if !due {
+		return
+	}
+
+	expired := r.tokens.Select(ctx, func(_ string, t *user.RefreshToken) bool {
+		return t.IsExpired(now)
+	})
+	for id, t := range expired {
+		if indexed, _ := r.byHash.Get(ctx, t.TokenHash); indexed == id {
+			r.byHash.Delete(ctx, t.TokenHash)
+		}
+		r.tokens.Delete(ctx, id)
+	}
+}
@@ -0,0 +1,111 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+)
+
+func TestInMemoryRefreshTokenRepository_CreateAndFindByHash(t *testing.T) {
+	repo := userRepo.NewInMemoryRefreshTokenRepository()
+	ctx := context.Background()
+
+	token, _ := user.NewRefreshToken("user123", "family123", "hash123", time.Hour)
+
+	if err := repo.Create(ctx, token); err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if token.ID == "" {
+		t.Error("Create() should generate ID for token")
+	}
+
+	found, err := repo.FindByHash(ctx, "hash123")
+	if err != nil {
+		t.Fatalf("FindByHash() unexpected error = %v", err)
+	}
+	if found.ID != token.ID {
+		t.Errorf("FindByHash() ID = %v, want %v", found.ID, token.ID)
+	}
+}
+
+func TestInMemoryRefreshTokenRepository_FindByHashNotFound(t *testing.T) {
+	repo := userRepo.NewInMemoryRefreshTokenRepository()
+
+	_, err := repo.FindByHash(context.Background(), "missing")
+	if err != userRepo.ErrRefreshTokenNotFound {
+		t.Errorf("FindByHash() expected ErrRefreshTokenNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryRefreshTokenRepository_FindByHashAmongMany(t *testing.T) {
+	repo := userRepo.NewInMemoryRefreshTokenRepository()
+	ctx := context.Background()
+
+	ids := make(map[string]string)
+	for _, hash := range []string{"hash-a", "hash-b", "hash-c"} {
+		token, _ := user.NewRefreshToken("user123", "family123", hash, time.Hour)
+		if err := repo.Create(ctx, token); err != nil {
+			t.Fatalf("Create() unexpected error = %v", err)
+		}
+		ids[hash] = token.ID
+	}
+
+	for hash, id := range ids {
+		found, err := repo.FindByHash(ctx, hash)
+		if err != nil {
+			t.Fatalf("FindByHash(%q) unexpected error = %v", hash, err)
+		}
+		if found.ID != id {
+			t.Errorf("FindByHash(%q) ID = %v, want %v", hash, found.ID, id)
+		}
+	}
+}
+
+func TestInMemoryRefreshTokenRepository_MarkUsed(t *testing.T) {
+	repo := userRepo.NewInMemoryRefreshTokenRepository()
+	ctx := context.Background()
+
+	token, _ := user.NewRefreshToken("user123", "family123", "hash123", time.Hour)
+	_ = repo.Create(ctx, token)
+
+	ok, err := repo.MarkUsed(ctx, token.ID, time.Now())
+	if err != nil || !ok {
+		t.Fatalf("MarkUsed() first call = %v, %v; want true, nil", ok, err)
+	}
+
+	ok, err = repo.MarkUsed(ctx, token.ID, time.Now())
+	if err != nil || ok {
+		t.Errorf("MarkUsed() second call = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestInMemoryRefreshTokenRepository_RevokeFamily(t *testing.T) {
+	repo := userRepo.NewInMemoryRefreshTokenRepository()
+	ctx := context.Background()
+
+	first, _ := user.NewRefreshToken("user123", "family123", "hash1", time.Hour)
+	second, _ := user.NewRefreshToken("user123", "family123", "hash2", time.Hour)
+	other, _ := user.NewRefreshToken("user123", "family456", "hash3", time.Hour)
+	_ = repo.Create(ctx, first)
+	_ = repo.Create(ctx, second)
+	_ = repo.Create(ctx, other)
+
+	if err := repo.RevokeFamily(ctx, "family123"); err != nil {
+		t.Fatalf("RevokeFamily() unexpected error = %v", err)
+	}
+
+	for _, hash := range []string{"hash1", "hash2"} {
+		found, _ := repo.FindByHash(ctx, hash)
+		if !found.IsRevoked() {
+			t.Errorf("RevokeFamily() token %s should be revoked", hash)
+		}
+	}
+
+	found, _ := repo.FindByHash(ctx, "hash3")
+	if found.IsRevoked() {
+		t.Error("RevokeFamily() should not revoke tokens of other families")
+	}
+}
@@ -0,0 +1,147 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// InMemorySessionRepository implements user.SessionRepository using in-memory storage.
+// It takes part in memtx transactions.
+type InMemorySessionRepository struct {
+	sessions *memtx.Store[string, *user.Session]
+}
+
+// NewInMemorySessionRepository creates a new in-memory session repository
+func NewInMemorySessionRepository() *InMemorySessionRepository {
+	return &InMemorySessionRepository{
+		sessions: memtx.NewStore[string, *user.Session](),
+	}
+}
+
+// Create stores a new session
+func (r *InMemorySessionRepository) Create(ctx context.Context, s *user.Session) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+
+	stored := *s
+	r.sessions.Put(ctx, stored.ID, &stored)
+	return nil
+}
+
+// FindByID retrieves a session by ID
+func (r *InMemorySessionRepository) FindByID(ctx context.Context, id string) (*user.Session, error) {
+	s, exists := r.sessions.Get(ctx, id)
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	found := *s
+	return &found, nil
+}
+
+// FindByFamily retrieves the session owning a refresh token family
+func (r *InMemorySessionRepository) FindByFamily(ctx context.Context, familyID string) (*user.Session, error) {
+	matches := r.sessions.Select(ctx, func(_ string, s *user.Session) bool {
+		return s.FamilyID == familyID
+	})
+	for _, s := range matches {
+		found := *s
+		return &found, nil
+	}
+	return nil, ErrSessionNotFound
+}
+
+// ListByUser returns the sessions of a user, most recently seen first
+func (r *InMemorySessionRepository) ListByUser(ctx context.Context, userID string) ([]*user.Session, error) {
+	var found []*user.Session
+	for _, s := range r.ofUser(ctx, userID) {
+		session := *s
+		found = append(found, &session)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].LastSeenAt.After(found[j].LastSeenAt)
+	})
+	return found, nil
+}
+
+// Touch updates the last seen time of a session
+func (r *InMemorySessionRepository) Touch(ctx context.Context, id string, seenAt time.Time) error {
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		s, exists := r.sessions.Get(ctx, id)
+		if !exists {
+			return ErrSessionNotFound
+		}
+		if seenAt.After(s.LastSeenAt) {
+			updated := *s
+			updated.LastSeenAt = seenAt
+			r.sessions.Put(ctx, id, &updated)
+		}
+		return nil
+	})
+}
+
+// Revoke signs a session out
+func (r *InMemorySessionRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		s, exists := r.sessions.Get(ctx, id)
+		if !exists {
+			return ErrSessionNotFound
+		}
+		if s.RevokedAt == nil {
+			r.sessions.Put(ctx, id, revoked(s, revokedAt))
+		}
+		return nil
+	})
+}
+
+// RevokeAllForUser signs out every session of the user
+func (r *InMemorySessionRepository) RevokeAllForUser(ctx context.Context, userID string, revokedAt time.Time) error {
+	return memtx.Atomically(ctx, func(ctx context.Context) error {
+		for id, s := range r.ofUser(ctx, userID) {
+			if s.RevokedAt == nil {
+				r.sessions.Put(ctx, id, revoked(s, revokedAt))
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteStale removes sessions last seen or revoked before cutoff
+func (r *InMemorySessionRepository) DeleteStale(ctx context.Context, cutoff time.Time) (int, error) {
+	deleted := 0
+	err := memtx.Atomically(ctx, func(ctx context.Context) error {
+		stale := r.sessions.Select(ctx, func(_ string, s *user.Session) bool {
+			return s.LastSeenAt.Before(cutoff) || (s.RevokedAt != nil && s.RevokedAt.Before(cutoff))
+		})
+		for id := range stale {
+			r.sessions.Delete(ctx, id)
+		}
+		deleted = len(stale)
+		return nil
+	})
+	return deleted, err
+}
+
+// ofUser returns the stored sessions of a user by ID
+func (r *InMemorySessionRepository) ofUser(ctx context.Context, userID string) map[string]*user.Session {
+	return r.sessions.Select(ctx, func(_ string, s *user.Session) bool {
+		return s.UserID == userID
+	})
+}
+
+// revoked returns a copy of s revoked at revokedAt
+func revoked(s *user.Session, revokedAt time.Time) *user.Session {
+	updated := *s
+	updated.RevokedAt = &revokedAt
+	return &updated
+}
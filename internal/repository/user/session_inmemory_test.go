@@ -0,0 +1,122 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+)
+
+func TestInMemorySessionRepository_CreateAndFind(t *testing.T) {
+	repo := userRepo.NewInMemorySessionRepository()
+	ctx := context.Background()
+
+	s, _ := user.NewSession("user-1", "family-1", "192.0.2.1", "curl/8.0")
+	if err := repo.Create(ctx, s); err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if s.ID == "" {
+		t.Fatal("Create() should set the session ID")
+	}
+
+	found, err := repo.FindByFamily(ctx, "family-1")
+	if err != nil || found.ID != s.ID {
+		t.Errorf("FindByFamily() = %v, %v", found, err)
+	}
+	if _, err := repo.FindByID(ctx, "missing"); err != userRepo.ErrSessionNotFound {
+		t.Errorf("FindByID() error = %v, want %v", err, userRepo.ErrSessionNotFound)
+	}
+}
+
+func TestInMemorySessionRepository_ListByUser(t *testing.T) {
+	repo := userRepo.NewInMemorySessionRepository()
+	ctx := context.Background()
+
+	older, _ := user.NewSession("user-1", "family-1", "", "")
+	newer, _ := user.NewSession("user-1", "family-2", "", "")
+	other, _ := user.NewSession("user-2", "family-3", "", "")
+	repo.Create(ctx, older)
+	repo.Create(ctx, newer)
+	repo.Create(ctx, other)
+	repo.Touch(ctx, newer.ID, time.Now().Add(time.Minute))
+
+	sessions, _ := repo.ListByUser(ctx, "user-1")
+	if len(sessions) != 2 || sessions[0].ID != newer.ID {
+		t.Errorf("ListByUser() should return the user's sessions, most recently seen first")
+	}
+}
+
+func TestInMemorySessionRepository_Revoke(t *testing.T) {
+	repo := userRepo.NewInMemorySessionRepository()
+	ctx := context.Background()
+
+	first, _ := user.NewSession("user-1", "family-1", "", "")
+	second, _ := user.NewSession("user-1", "family-2", "", "")
+	repo.Create(ctx, first)
+	repo.Create(ctx, second)
+
+	if err := repo.Revoke(ctx, first.ID, time.Now()); err != nil {
+		t.Fatalf("Revoke() unexpected error = %v", err)
+	}
+	if found, _ := repo.FindByID(ctx, first.ID); !found.IsRevoked() {
+		t.Error("Revoke() should revoke the session")
+	}
+	if found, _ := repo.FindByID(ctx, second.ID); found.IsRevoked() {
+		t.Error("Revoke() should leave other sessions alone")
+	}
+
+	repo.RevokeAllForUser(ctx, "user-1", time.Now())
+	if found, _ := repo.FindByID(ctx, second.ID); !found.IsRevoked() {
+		t.Error("RevokeAllForUser() should revoke every session of the user")
+	}
+}
+
+func TestInMemorySessionRepository_DeleteStale(t *testing.T) {
+	repo := userRepo.NewInMemorySessionRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	idle, _ := user.NewSession("user-1", "family-1", "", "")
+	idle.LastSeenAt = now.Add(-2 * time.Hour)
+	revoked, _ := user.NewSession("user-1", "family-2", "", "")
+	revokedAt := now.Add(-2 * time.Hour)
+	revoked.RevokedAt = &revokedAt
+	active, _ := user.NewSession("user-1", "family-3", "", "")
+	repo.Create(ctx, idle)
+	repo.Create(ctx, revoked)
+	repo.Create(ctx, active)
+
+	deleted, err := repo.DeleteStale(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteStale() unexpected error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteStale() = %d, want 2", deleted)
+	}
+	if _, err := repo.FindByID(ctx, active.ID); err != nil {
+		t.Error("DeleteStale() should keep active sessions")
+	}
+}
+
+func TestInMemorySessionRepository_Transaction(t *testing.T) {
+	repo := userRepo.NewInMemorySessionRepository()
+	tm := memtx.NewManager()
+	ctx := context.Background()
+
+	session, _ := user.NewSession("user-1", "family-1", "", "")
+	repo.Create(ctx, session)
+
+	tm.WithinTx(ctx, func(ctx context.Context) error {
+		repo.RevokeAllForUser(ctx, "user-1", time.Now())
+		return errors.New("roll back")
+	})
+
+	found, err := repo.FindByID(ctx, session.ID)
+	if err != nil || found.RevokedAt != nil {
+		t.Errorf("FindByID() = %+v, %v, want the session unrevoked after a rollback", found, err)
+	}
+}
@@ -0,0 +1,137 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+	ErrWebAuthnCredentialExists   = errors.New("webauthn credential already registered")
+)
+
+// InMemoryWebAuthnCredentialRepository implements user.WebAuthnCredentialRepository using in-memory storage
+type InMemoryWebAuthnCredentialRepository struct {
+	credentials map[string]*user.WebAuthnCredential
+	mu          sync.RWMutex
+}
+
+// NewInMemoryWebAuthnCredentialRepository creates a new in-memory WebAuthn credential repository
+func NewInMemoryWebAuthnCredentialRepository() *InMemoryWebAuthnCredentialRepository {
+	return &InMemoryWebAuthnCredentialRepository{
+		credentials: make(map[string]*user.WebAuthnCredential),
+	}
+}
+
+// Create stores a new credential
+func (r *InMemoryWebAuthnCredentialRepository) Create(ctx context.Context, c *user.WebAuthnCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.credentials {
+		if bytes.Equal(existing.CredentialID, c.CredentialID) {
+			return ErrWebAuthnCredentialExists
+		}
+	}
+
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+
+	r.credentials[c.ID] = copyCredential(c)
+	return nil
+}
+
+// FindByCredentialID retrieves a credential by the authenticator's credential ID
+func (r *InMemoryWebAuthnCredentialRepository) FindByCredentialID(ctx context.Context, credentialID []byte) (*user.WebAuthnCredential, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.credentials {
+		if bytes.Equal(c.CredentialID, credentialID) {
+			return copyCredential(c), nil
+		}
+	}
+	return nil, ErrWebAuthnCredentialNotFound
+}
+
+// ListByUser returns the credentials of a user, oldest first
+func (r *InMemoryWebAuthnCredentialRepository) ListByUser(ctx context.Context, userID string) ([]*user.WebAuthnCredential, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var credentials []*user.WebAuthnCredential
+	for _, c := range r.credentials {
+		if c.UserID == userID {
+			credentials = append(credentials, copyCredential(c))
+		}
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].CreatedAt.Before(credentials[j].CreatedAt)
+	})
+	return credentials, nil
+}
+
+// UpdateSignCount stores the new signature counter if the stored one still equals previous
+func (r *InMemoryWebAuthnCredentialRepository) UpdateSignCount(ctx context.Context, id string, previous, next uint32, usedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, exists := r.credentials[id]
+	if !exists {
+		return false, ErrWebAuthnCredentialNotFound
+	}
+	if c.SignCount != previous {
+		return false, nil
+	}
+
+	c.SignCount = next
+	c.LastUsedAt = &usedAt
+	return true, nil
+}
+
+// Delete removes a credential
+func (r *InMemoryWebAuthnCredentialRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.credentials[id]; !exists {
+		return ErrWebAuthnCredentialNotFound
+	}
+	delete(r.credentials, id)
+	return nil
+}
+
+// DeleteAllForUser removes every credential of a user
+func (r *InMemoryWebAuthnCredentialRepository) DeleteAllForUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.credentials {
+		if c.UserID == userID {
+			delete(r.credentials, id)
+		}
+	}
+	return nil
+}
+
+// copyCredential copies c including its byte slices, so callers cannot
+// modify stored credentials
+func copyCredential(c *user.WebAuthnCredential) *user.WebAuthnCredential {
+	copied := *c
+	copied.CredentialID = bytes.Clone(c.CredentialID)
+	copied.PublicKey = bytes.Clone(c.PublicKey)
+	copied.AAGUID = bytes.Clone(c.AAGUID)
+	if c.LastUsedAt != nil {
+		lastUsedAt := *c.LastUsedAt
+		copied.LastUsedAt = &lastUsedAt
+	}
+	return &copied
+}
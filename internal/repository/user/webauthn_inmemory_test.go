@@ -0,0 +1,95 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+)
+
+func TestInMemoryWebAuthnCredentialRepository_Create(t *testing.T) {
+	repo := userRepo.NewInMemoryWebAuthnCredentialRepository()
+	ctx := context.Background()
+
+	credential, _ := user.NewWebAuthnCredential("user123", []byte{1, 2, 3}, []byte{4}, 0, "Laptop")
+	if err := repo.Create(ctx, credential); err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if credential.ID == "" {
+		t.Error("Create() should assign an ID")
+	}
+
+	// Credential IDs are unique across users
+	duplicate, _ := user.NewWebAuthnCredential("user456", []byte{1, 2, 3}, []byte{5}, 0, "")
+	if err := repo.Create(ctx, duplicate); err != userRepo.ErrWebAuthnCredentialExists {
+		t.Errorf("Create() duplicate expected ErrWebAuthnCredentialExists, got %v", err)
+	}
+
+	found, err := repo.FindByCredentialID(ctx, []byte{1, 2, 3})
+	if err != nil || found.UserID != "user123" {
+		t.Fatalf("FindByCredentialID() = %+v, %v; want the stored credential", found, err)
+	}
+
+	// Stored credentials are copies
+	found.PublicKey[0] = 9
+	again, _ := repo.FindByCredentialID(ctx, []byte{1, 2, 3})
+	if again.PublicKey[0] != 4 {
+		t.Error("modifying a returned credential should not change the stored one")
+	}
+
+	if _, err := repo.FindByCredentialID(ctx, []byte{7}); err != userRepo.ErrWebAuthnCredentialNotFound {
+		t.Errorf("FindByCredentialID() expected ErrWebAuthnCredentialNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryWebAuthnCredentialRepository_UpdateSignCount(t *testing.T) {
+	repo := userRepo.NewInMemoryWebAuthnCredentialRepository()
+	ctx := context.Background()
+
+	credential, _ := user.NewWebAuthnCredential("user123", []byte{1}, []byte{2}, 5, "")
+	_ = repo.Create(ctx, credential)
+
+	ok, err := repo.UpdateSignCount(ctx, credential.ID, 5, 6, time.Now())
+	if err != nil || !ok {
+		t.Fatalf("UpdateSignCount() = %v, %v; want true, nil", ok, err)
+	}
+
+	// A concurrent update from the same counter loses
+	ok, err = repo.UpdateSignCount(ctx, credential.ID, 5, 6, time.Now())
+	if err != nil || ok {
+		t.Errorf("UpdateSignCount() stale counter = %v, %v; want false, nil", ok, err)
+	}
+
+	stored, _ := repo.FindByCredentialID(ctx, []byte{1})
+	if stored.SignCount != 6 || stored.LastUsedAt == nil {
+		t.Errorf("stored credential = %+v, want counter 6 and last use set", stored)
+	}
+
+	if _, err := repo.UpdateSignCount(ctx, "missing", 0, 1, time.Now()); err != userRepo.ErrWebAuthnCredentialNotFound {
+		t.Errorf("UpdateSignCount() expected ErrWebAuthnCredentialNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryWebAuthnCredentialRepository_DeleteAllForUser(t *testing.T) {
+	repo := userRepo.NewInMemoryWebAuthnCredentialRepository()
+	ctx := context.Background()
+
+	first, _ := user.NewWebAuthnCredential("user123", []byte{1}, []byte{1}, 0, "")
+	second, _ := user.NewWebAuthnCredential("user123", []byte{2}, []byte{1}, 0, "")
+	other, _ := user.NewWebAuthnCredential("user456", []byte{3}, []byte{1}, 0, "")
+	for _, c := range []*user.WebAuthnCredential{first, second, other} {
+		_ = repo.Create(ctx, c)
+	}
+
+	if err := repo.DeleteAllForUser(ctx, "user123"); err != nil {
+		t.Fatalf("DeleteAllForUser() unexpected error = %v", err)
+	}
+
+	mine, _ := repo.ListByUser(ctx, "user123")
+	theirs, _ := repo.ListByUser(ctx, "user456")
+	if len(mine) != 0 || len(theirs) != 1 {
+		t.Errorf("ListByUser() = %d and %d credentials, want 0 and 1", len(mine), len(theirs))
+	}
+}
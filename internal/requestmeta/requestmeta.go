@@ -0,0 +1,88 @@
+package requestmeta
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Meta describes the client behind a request
+type Meta struct {
+	IP        string
+	UserAgent string
+	RequestID string
+	// ActorID is the authenticated user, once known; see WithActor
+	ActorID string
+}
+
+// RequestIDHeader carries the request ID, both ways
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client supplied request IDs
+const maxRequestIDLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying meta
+func NewContext(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, contextKey{}, meta)
+}
+
+// FromContext returns the request metadata stored in ctx, or an empty Meta
+func FromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(contextKey{}).(Meta)
+	return meta
+}
+
+// WithActor returns a copy of ctx whose Meta names the authenticated user
+func WithActor(ctx context.Context, actorID string) context.Context {
+	meta := FromContext(ctx)
+	meta.ActorID = actorID
+	return NewContext(ctx, meta)
+}
+
+// FromRequest extracts the client metadata from r. When trustForwarded is
+// set the client IP is taken from the last X-Forwarded-For hop, which is the
+// one added by our own reverse proxy; otherwise the header is ignored since
+// clients can set it freely. The request ID is taken from X-Request-ID when
+// it looks sane and generated otherwise.
+func FromRequest(r *http.Request, trustForwarded bool) Meta {
+	return Meta{
+		IP:        clientIP(r, trustForwarded),
+		UserAgent: r.UserAgent(),
+		RequestID: requestID(r),
+	}
+}
+
+func requestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		return uuid.New().String()
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return uuid.New().String()
+		}
+	}
+	return id
+}
+
+func clientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
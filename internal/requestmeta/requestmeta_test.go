@@ -0,0 +1,80 @@
+package requestmeta_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+)
+
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		forwardedFor   string
+		trustForwarded bool
+		wantIP         string
+	}{
+		{name: "remote address", wantIP: "192.0.2.1"},
+		{name: "untrusted header is ignored", forwardedFor: "203.0.113.9", wantIP: "192.0.2.1"},
+		{name: "trusted header", forwardedFor: "203.0.113.9", trustForwarded: true, wantIP: "203.0.113.9"},
+		{name: "last hop wins", forwardedFor: "10.0.0.1, 203.0.113.9", trustForwarded: true, wantIP: "203.0.113.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("User-Agent", "test-agent")
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			meta := requestmeta.FromRequest(req, tt.trustForwarded)
+			if meta.IP != tt.wantIP {
+				t.Errorf("FromRequest() IP = %v, want %v", meta.IP, tt.wantIP)
+			}
+			if meta.UserAgent != "test-agent" {
+				t.Errorf("FromRequest() UserAgent = %v, want test-agent", meta.UserAgent)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if meta := requestmeta.FromContext(context.Background()); meta != (requestmeta.Meta{}) {
+		t.Errorf("FromContext() on empty context = %+v, want zero value", meta)
+	}
+
+	ctx := requestmeta.NewContext(context.Background(), requestmeta.Meta{IP: "192.0.2.1"})
+	if meta := requestmeta.FromContext(ctx); meta.IP != "192.0.2.1" {
+		t.Errorf("FromContext() IP = %v, want 192.0.2.1", meta.IP)
+	}
+}
+
+func TestFromRequest_RequestID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(requestmeta.RequestIDHeader, "req-123")
+	if id := requestmeta.FromRequest(req, false).RequestID; id != "req-123" {
+		t.Errorf("FromRequest() RequestID = %q, want req-123", id)
+	}
+
+	// Missing or unreasonable IDs are replaced
+	for _, header := range []string{"", "has spaces", strings.Repeat("x", 200)} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(requestmeta.RequestIDHeader, header)
+		if id := requestmeta.FromRequest(req, false).RequestID; id == "" || id == header {
+			t.Errorf("FromRequest() RequestID = %q for header %q, want a generated ID", id, header)
+		}
+	}
+}
+
+func TestWithActor(t *testing.T) {
+	ctx := requestmeta.NewContext(context.Background(), requestmeta.Meta{IP: "192.0.2.1"})
+	ctx = requestmeta.WithActor(ctx, "user-1")
+
+	meta := requestmeta.FromContext(ctx)
+	if meta.ActorID != "user-1" || meta.IP != "192.0.2.1" {
+		t.Errorf("WithActor() meta = %+v", meta)
+	}
+}
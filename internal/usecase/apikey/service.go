@@ -0,0 +1,225 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/securetoken"
+)
+
+const (
+	// PublicKeyPrefix starts the identifier of every key
+	PublicKeyPrefix = "pk_live_"
+	// SecretPrefix starts every secret, telling it apart from a JWT
+	SecretPrefix = "sk_live_"
+
+	publicKeyBytes = 12
+)
+
+var (
+	ErrAPIKeyNotFound  = errors.New("api key not found")
+	ErrInvalidAPIKey   = errors.New("invalid or expired api key")
+	ErrScopeNotAllowed = errors.New("scope exceeds your permissions")
+)
+
+// UseCase defines the interface for API key business logic
+type UseCase interface {
+	Create(ctx context.Context, userID, label string, scopes []string, expiresAt *time.Time) (*CreatedKey, error)
+	List(ctx context.Context, userID string) ([]*apikey.APIKey, error)
+	Rename(ctx context.Context, userID, id, label string) (*apikey.APIKey, error)
+	Revoke(ctx context.Context, userID, id string) error
+	RevokeAllForUser(ctx context.Context, userID string) error
+	Authenticate(ctx context.Context, secret string) (*apikey.APIKey, error)
+}
+
+// CreatedKey is a new key together with its secret, which is only
+// available at creation time
+type CreatedKey struct {
+	Key    *apikey.APIKey
+	Secret string
+}
+
+// Service implements UseCase interface
+type Service struct {
+	repo  apikey.Repository
+	users user.Repository
+}
+
+// Option configures optional collaborators of the Service
+type Option func(*Service)
+
+// WithOwnerRoles bounds every key by the current role of its owner: keys
+// cannot be created with scopes the role does not grant, and scopes the
+// role has since lost stop working.
+func WithOwnerRoles(users user.Repository) Option {
+	return func(s *Service) {
+		s.users = users
+	}
+}
+
+// NewService creates a new API key service
+func NewService(repo apikey.Repository, opts ...Option) *Service {
+	s := &Service{
+		repo: repo,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create issues a new API key for the user. A key can never do more than
+// the user creating it.
+func (s *Service) Create(ctx context.Context, userID, label string, scopes []string, expiresAt *time.Time) (*CreatedKey, error) {
+	if s.users != nil {
+		owner, err := s.users.FindByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, scope := range scopes {
+			if slices.Contains(apikey.Scopes, scope) && !owner.Role.Can(user.Permission(scope)) {
+				return nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+			}
+		}
+	}
+
+	publicID := make([]byte, publicKeyBytes)
+	if _, err := rand.Read(publicID); err != nil {
+		return nil, err
+	}
+
+	token, err := securetoken.Generate()
+	if err != nil {
+		return nil, err
+	}
+	secret := SecretPrefix + token
+
+	key, err := apikey.NewAPIKey(userID, label, PublicKeyPrefix+hex.EncodeToString(publicID), securetoken.Hash(secret), scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &CreatedKey{Key: key, Secret: secret}, nil
+}
+
+// List returns every key of the user, including revoked and expired ones
+func (s *Service) List(ctx context.Context, userID string) ([]*apikey.APIKey, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Rename changes the label of one of the user's keys
+func (s *Service) Rename(ctx context.Context, userID, id, label string) (*apikey.APIKey, error) {
+	key, err := s.findOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Label = label
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Revoke permanently disables one of the user's keys
+func (s *Service) Revoke(ctx context.Context, userID, id string) error {
+	key, err := s.findOwned(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	key.Revoke()
+	return s.repo.Update(ctx, key)
+}
+
+// RevokeAllForUser disables every key of the user, for example when the
+// account is deleted
+func (s *Service) RevokeAllForUser(ctx context.Context, userID string) error {
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.IsRevoked() {
+			continue
+		}
+		key.Revoke()
+		if err := s.repo.Update(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Authenticate resolves a secret to its active key and records the use
+func (s *Service) Authenticate(ctx context.Context, secret string) (*apikey.APIKey, error) {
+	if !strings.HasPrefix(secret, SecretPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindBySecretHash(ctx, securetoken.Hash(secret))
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err := s.limitToOwnerRole(ctx, key); err != nil {
+		return nil, err
+	}
+
+	// A failure to record usage must not break the request
+	if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+		log.Printf("Failed to record use of api key %s: %v", key.ID, err)
+	} else {
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// limitToOwnerRole drops the scopes of the key that its owner's current role
+// no longer grants, so that downgrading a user also downgrades their keys
+func (s *Service) limitToOwnerRole(ctx context.Context, key *apikey.APIKey) error {
+	if s.users == nil {
+		return nil
+	}
+
+	owner, err := s.users.FindByID(ctx, key.UserID)
+	if err != nil {
+		return ErrInvalidAPIKey
+	}
+
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		if owner.Role.Can(user.Permission(scope)) {
+			scopes = append(scopes, scope)
+		}
+	}
+	key.Scopes = scopes
+	return nil
+}
+
+// findOwned loads a key, treating keys of other users as missing
+func (s *Service) findOwned(ctx context.Context, userID, id string) (*apikey.APIKey, error) {
+	key, err := s.repo.FindByID(ctx, id)
+	if err != nil || key.UserID != userID {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
@@ -0,0 +1,164 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	apiKeyRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/apikey"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	apiKeyUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/apikey"
+)
+
+func TestService_CreateAndAuthenticate(t *testing.T) {
+	service := apiKeyUseCase.NewService(apiKeyRepo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, "user123", "backend", []string{apikey.ScopePaymentsWrite}, nil)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if !strings.HasPrefix(created.Secret, apiKeyUseCase.SecretPrefix) || !strings.HasPrefix(created.Key.PublicKey, apiKeyUseCase.PublicKeyPrefix) {
+		t.Errorf("Create() secret = %q, public key = %q", created.Secret, created.Key.PublicKey)
+	}
+	if strings.Contains(created.Key.SecretHash, created.Secret) {
+		t.Error("Create() should only store a hash of the secret")
+	}
+
+	key, err := service.Authenticate(ctx, created.Secret)
+	if err != nil {
+		t.Fatalf("Authenticate() unexpected error = %v", err)
+	}
+	if key.UserID != "user123" || key.LastUsedAt == nil {
+		t.Errorf("Authenticate() = %+v, want the user's key with LastUsedAt set", key)
+	}
+
+	keys, _ := service.List(ctx, "user123")
+	if len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Error("last use should be persisted")
+	}
+}
+
+func TestService_Authenticate_Invalid(t *testing.T) {
+	service := apiKeyUseCase.NewService(apiKeyRepo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	revoked, _ := service.Create(ctx, "user123", "revoked", []string{apikey.ScopePaymentsRead}, nil)
+	service.Revoke(ctx, "user123", revoked.Key.ID)
+
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{name: "unknown secret", secret: apiKeyUseCase.SecretPrefix + "unknown"},
+		{name: "missing prefix", secret: "unknown"},
+		{name: "revoked key", secret: revoked.Secret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Authenticate(ctx, tt.secret); !errors.Is(err, apiKeyUseCase.ErrInvalidAPIKey) {
+				t.Errorf("Authenticate() error = %v, want %v", err, apiKeyUseCase.ErrInvalidAPIKey)
+			}
+		})
+	}
+}
+
+func TestService_ForeignKeys(t *testing.T) {
+	service := apiKeyUseCase.NewService(apiKeyRepo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	created, _ := service.Create(ctx, "user123", "backend", []string{apikey.ScopePaymentsRead}, nil)
+
+	if _, err := service.Rename(ctx, "user456", created.Key.ID, "mine now"); !errors.Is(err, apiKeyUseCase.ErrAPIKeyNotFound) {
+		t.Errorf("Rename() foreign key error = %v, want %v", err, apiKeyUseCase.ErrAPIKeyNotFound)
+	}
+	if err := service.Revoke(ctx, "user456", created.Key.ID); !errors.Is(err, apiKeyUseCase.ErrAPIKeyNotFound) {
+		t.Errorf("Revoke() foreign key error = %v, want %v", err, apiKeyUseCase.ErrAPIKeyNotFound)
+	}
+
+	renamed, err := service.Rename(ctx, "user123", created.Key.ID, "renamed")
+	if err != nil || renamed.Label != "renamed" {
+		t.Errorf("Rename() = %v, %v; want label renamed", renamed, err)
+	}
+}
+
+func TestService_Authenticate_Expired(t *testing.T) {
+	service := apiKeyUseCase.NewService(apiKeyRepo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(20 * time.Millisecond)
+	created, err := service.Create(ctx, "user123", "short-lived", []string{apikey.ScopePaymentsRead}, &expiresAt)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := service.Authenticate(ctx, created.Secret); !errors.Is(err, apiKeyUseCase.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() expired key error = %v, want %v", err, apiKeyUseCase.ErrInvalidAPIKey)
+	}
+}
+
+func TestService_RevokeAllForUser(t *testing.T) {
+	service := apiKeyUseCase.NewService(apiKeyRepo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	first, _ := service.Create(ctx, "user123", "backend", []string{apikey.ScopePaymentsRead}, nil)
+	second, _ := service.Create(ctx, "user123", "worker", []string{apikey.ScopePaymentsRead}, nil)
+	other, _ := service.Create(ctx, "other", "backend", []string{apikey.ScopePaymentsRead}, nil)
+
+	if err := service.RevokeAllForUser(ctx, "user123"); err != nil {
+		t.Fatalf("RevokeAllForUser() unexpected error = %v", err)
+	}
+	for _, secret := range []string{first.Secret, second.Secret} {
+		if _, err := service.Authenticate(ctx, secret); !errors.Is(err, apiKeyUseCase.ErrInvalidAPIKey) {
+			t.Errorf("Authenticate() with a revoked key error = %v, want %v", err, apiKeyUseCase.ErrInvalidAPIKey)
+		}
+	}
+	if _, err := service.Authenticate(ctx, other.Secret); err != nil {
+		t.Errorf("RevokeAllForUser() should not touch keys of other users: %v", err)
+	}
+}
+
+func TestService_OwnerRoles(t *testing.T) {
+	users := userRepo.NewInMemoryRepository()
+	service := apiKeyUseCase.NewService(apiKeyRepo.NewInMemoryRepository(), apiKeyUseCase.WithOwnerRoles(users))
+	ctx := context.Background()
+
+	owner, _ := user.NewUser("merchant", "merchant@example.com", "hash")
+	owner.Role = user.RoleMerchantDeveloper
+	users.Create(ctx, owner)
+
+	if _, err := service.Create(ctx, owner.ID, "backend", []string{apikey.ScopePaymentsRefund}, nil); !errors.Is(err, apiKeyUseCase.ErrScopeNotAllowed) {
+		t.Fatalf("Create() with a scope beyond the role error = %v, want %v", err, apiKeyUseCase.ErrScopeNotAllowed)
+	}
+
+	created, err := service.Create(ctx, owner.ID, "backend", []string{apikey.ScopePaymentsRead, apikey.ScopePaymentsWrite}, nil)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+
+	// Downgrading the owner takes effect on the next request
+	stored, _ := users.FindByID(ctx, owner.ID)
+	stored.AssignRole(user.RoleFinanceReadOnly)
+	if err := users.Update(ctx, stored); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+
+	key, err := service.Authenticate(ctx, created.Secret)
+	if err != nil {
+		t.Fatalf("Authenticate() unexpected error = %v", err)
+	}
+	if !key.HasScope(apikey.ScopePaymentsRead) || key.HasScope(apikey.ScopePaymentsWrite) {
+		t.Errorf("Authenticate() scopes = %v, want only %s", key.Scopes, apikey.ScopePaymentsRead)
+	}
+
+	keys, _ := service.List(ctx, owner.ID)
+	if len(keys) != 1 || len(keys[0].Scopes) != 2 {
+		t.Error("the granted scopes should be kept in case the role is restored")
+	}
+}
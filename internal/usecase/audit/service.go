@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+)
+
+const (
+	// DefaultQueryLimit applies to queries that do not set a limit
+	DefaultQueryLimit = 100
+	// MaxQueryLimit caps a single query; use Export for more
+	MaxQueryLimit = 1000
+)
+
+// UseCase defines the interface for reading the audit log
+type UseCase interface {
+	Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, error)
+	Export(ctx context.Context, filter audit.Filter, w io.Writer) error
+	Verify(ctx context.Context) (int, error)
+}
+
+// Service implements UseCase and audit.Recorder
+type Service struct {
+	repo audit.Repository
+}
+
+// NewService creates a new audit service
+func NewService(repo audit.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// Record appends an event, filling the client IP, user agent, request ID
+// and actor from the request metadata in ctx unless already set. Failures
+// are logged: an audit outage must not block logins.
+func (s *Service) Record(ctx context.Context, event audit.Event) {
+	meta := requestmeta.FromContext(ctx)
+	if event.ActorID == "" {
+		event.ActorID = meta.ActorID
+	}
+	if event.IP == "" {
+		event.IP = meta.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = meta.UserAgent
+	}
+	if event.RequestID == "" {
+		event.RequestID = meta.RequestID
+	}
+	event.OccurredAt = time.Now().UTC().Round(0)
+
+	if err := s.repo.Append(ctx, &event); err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Type, err)
+	}
+}
+
+// Query returns matching events in the order they were recorded, at most
+// MaxQueryLimit of them
+func (s *Service) Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultQueryLimit
+	}
+	if filter.Limit > MaxQueryLimit {
+		filter.Limit = MaxQueryLimit
+	}
+	return s.repo.Query(ctx, filter)
+}
+
+// Export writes every matching event to w as JSON Lines, one event per
+// line. An unfiltered export can be checked offline with audit.VerifyChain.
+func (s *Service) Export(ctx context.Context, filter audit.Filter, w io.Writer) error {
+	events, err := s.repo.Query(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks the hash chain of the whole log and returns the number of
+// entries checked. A broken chain yields an error wrapping
+// audit.ErrChainBroken.
+func (s *Service) Verify(ctx context.Context) (int, error) {
+	events, err := s.repo.Query(ctx, audit.Filter{})
+	if err != nil {
+		return 0, err
+	}
+	return len(events), audit.VerifyChain(nil, events)
+}
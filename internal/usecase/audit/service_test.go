@@ -0,0 +1,89 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	auditRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+	auditUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/audit"
+)
+
+func TestService_Record_FillsRequestMeta(t *testing.T) {
+	service := auditUseCase.NewService(auditRepo.NewInMemoryRepository())
+	ctx := requestmeta.NewContext(context.Background(), requestmeta.Meta{
+		IP: "192.0.2.1", UserAgent: "curl/8.0", RequestID: "req-1",
+	})
+	ctx = requestmeta.WithActor(ctx, "admin-1")
+
+	service.Record(ctx, audit.Event{Type: audit.EventRoleChanged, SubjectID: "user-1"})
+	service.Record(ctx, audit.Event{Type: audit.EventPasswordReset, ActorID: "user-1", SubjectID: "user-1"})
+
+	events, _ := service.Query(context.Background(), audit.Filter{})
+	if len(events) != 2 {
+		t.Fatalf("Query() returned %d events, want 2", len(events))
+	}
+	e := events[0]
+	if e.ActorID != "admin-1" || e.IP != "192.0.2.1" || e.UserAgent != "curl/8.0" || e.RequestID != "req-1" {
+		t.Errorf("Record() event = %+v, want request metadata filled in", e)
+	}
+	if e.OccurredAt.IsZero() || e.OccurredAt.Location().String() != "UTC" {
+		t.Errorf("Record() OccurredAt = %v, want the current UTC time", e.OccurredAt)
+	}
+	if events[1].ActorID != "user-1" {
+		t.Errorf("Record() should keep an explicit actor, got %q", events[1].ActorID)
+	}
+}
+
+func TestService_Query_Limit(t *testing.T) {
+	service := auditUseCase.NewService(auditRepo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	for i := 0; i < auditUseCase.DefaultQueryLimit+5; i++ {
+		service.Record(ctx, audit.Event{Type: audit.EventLoginFailed})
+	}
+
+	events, _ := service.Query(ctx, audit.Filter{})
+	if len(events) != auditUseCase.DefaultQueryLimit {
+		t.Errorf("Query() without a limit returned %d events, want %d", len(events), auditUseCase.DefaultQueryLimit)
+	}
+	events, _ = service.Query(ctx, audit.Filter{Limit: 3})
+	if len(events) != 3 {
+		t.Errorf("Query() with limit 3 returned %d events", len(events))
+	}
+}
+
+func TestService_ExportAndVerify(t *testing.T) {
+	service := auditUseCase.NewService(auditRepo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	service.Record(ctx, audit.Event{Type: audit.EventUserRegistered, SubjectID: "user-1"})
+	service.Record(ctx, audit.Event{Type: audit.EventLoginSucceeded, SubjectID: "user-1"})
+	service.Record(ctx, audit.Event{Type: audit.EventLoginSucceeded, SubjectID: "user-2"})
+
+	var buf bytes.Buffer
+	if err := service.Export(ctx, audit.Filter{UserID: "user-1"}, &buf); err != nil {
+		t.Fatalf("Export() unexpected error = %v", err)
+	}
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.SubjectID != "user-1" {
+			t.Errorf("Export() line %q, err %v", scanner.Text(), err)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("Export() wrote %d lines, want 2", lines)
+	}
+
+	entries, err := service.Verify(ctx)
+	if err != nil || entries != 3 {
+		t.Errorf("Verify() = %d, %v, want 3 valid entries", entries, err)
+	}
+}
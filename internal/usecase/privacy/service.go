@@ -0,0 +1,216 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// UseCase defines the interface for data subject requests
+type UseCase interface {
+	Export(ctx context.Context, userID string) (*Archive, error)
+}
+
+// Archive is everything stored about a user. Credentials such as password
+// hashes, TOTP secrets and API key secret hashes are left out.
+type Archive struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Profile     Profile        `json:"profile"`
+	Sessions    []Session      `json:"sessions"`
+	APIKeys     []APIKey       `json:"api_keys"`
+	AuditEvents []*audit.Event `json:"audit_events"`
+}
+
+// Profile is the account part of an Archive
+type Profile struct {
+	ID                  string     `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	Role                string     `json:"role"`
+	EmailVerified       bool       `json:"email_verified"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty"`
+	MFAEnabled          bool       `json:"mfa_enabled"`
+	MFARequired         bool       `json:"mfa_required"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+}
+
+// Session is a login session in an Archive, including ended sessions that
+// have not been purged yet
+type Session struct {
+	ID         string     `json:"id"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKey is the metadata of an API key in an Archive
+type APIKey struct {
+	ID         string     `json:"id"`
+	Label      string     `json:"label"`
+	PublicKey  string     `json:"public_key"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Service implements UseCase
+type Service struct {
+	users    user.Repository
+	sessions user.SessionRepository
+	apiKeys  apikey.Repository
+	events   audit.Repository
+	auditLog audit.Recorder
+}
+
+// Option configures optional collaborators of the Service
+type Option func(*Service)
+
+// WithAuditLog records exports in the security audit log
+func WithAuditLog(recorder audit.Recorder) Option {
+	return func(s *Service) {
+		s.auditLog = recorder
+	}
+}
+
+// NewService creates a new privacy service reading from the repositories
+// that hold personal data
+func NewService(users user.Repository, sessions user.SessionRepository, apiKeys apikey.Repository, events audit.Repository, opts ...Option) *Service {
+	s := &Service{
+		users:    users,
+		sessions: sessions,
+		apiKeys:  apiKeys,
+		events:   events,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Export assembles the archive of everything stored about the user
+func (s *Service) Export(ctx context.Context, userID string) (*Archive, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	sessions, err := s.sessions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.apiKeys.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.events.Query(ctx, audit.Filter{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		GeneratedAt: time.Now().UTC(),
+		Profile:     newProfile(u),
+		Sessions:    make([]Session, 0, len(sessions)),
+		APIKeys:     make([]APIKey, 0, len(keys)),
+		AuditEvents: events,
+	}
+	if archive.AuditEvents == nil {
+		archive.AuditEvents = []*audit.Event{}
+	}
+	for _, session := range sessions {
+		archive.Sessions = append(archive.Sessions, Session{
+			ID:         session.ID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+	for _, key := range keys {
+		archive.APIKeys = append(archive.APIKeys, APIKey{
+			ID:         key.ID,
+			Label:      key.Label,
+			PublicKey:  key.PublicKey,
+			Scopes:     key.Scopes,
+			CreatedAt:  key.CreatedAt,
+			ExpiresAt:  key.ExpiresAt,
+			LastUsedAt: key.LastUsedAt,
+			RevokedAt:  key.RevokedAt,
+		})
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.Record(ctx, audit.Event{Type: audit.EventDataExported, SubjectID: userID})
+	}
+	return archive, nil
+}
+
+func newProfile(u *user.User) Profile {
+	return Profile{
+		ID:                  u.ID,
+		Username:            u.Username,
+		Email:               u.Email,
+		Role:                string(u.Role),
+		EmailVerified:       u.EmailVerified,
+		EmailVerifiedAt:     u.EmailVerifiedAt,
+		MFAEnabled:          u.MFAEnabled(),
+		MFARequired:         u.MFARequired,
+		CreatedAt:           u.CreatedAt,
+		UpdatedAt:           u.UpdatedAt,
+		DeletionRequestedAt: u.DeletionRequestedAt,
+	}
+}
+
+// WriteJSON writes the archive as a single JSON document
+func (a *Archive) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
+
+// WriteZIP writes the archive as a ZIP file with one JSON file per section
+func (a *Archive) WriteZIP(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", a.Profile},
+		{"sessions.json", a.Sessions},
+		{"api_keys.json", a.APIKeys},
+		{"audit_events.json", a.AuditEvents},
+	}
+	for _, file := range files {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: a.GeneratedAt,
+		})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
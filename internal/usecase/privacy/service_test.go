@@ -0,0 +1,105 @@
+package privacy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/apikey"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	apiKeyRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/apikey"
+	auditRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/audit"
+	userRepo "github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/privacy"
+)
+
+// seededService stores a user with a session, an API key and audit events,
+// plus data of another user that must not leak into the export
+func seededService(t *testing.T) (*privacy.Service, *user.User) {
+	t.Helper()
+	ctx := context.Background()
+
+	users := userRepo.NewInMemoryRepository()
+	sessions := userRepo.NewInMemorySessionRepository()
+	keys := apiKeyRepo.NewInMemoryRepository()
+	events := auditRepo.NewInMemoryRepository()
+
+	u, _ := user.NewUser("testuser", "test@example.com", "$argon2id$secret-hash")
+	users.Create(ctx, u)
+	other, _ := user.NewUser("other", "other@example.com", "$argon2id$other-hash")
+	users.Create(ctx, other)
+
+	session, _ := user.NewSession(u.ID, "family-1", "192.0.2.1", "Firefox")
+	sessions.Create(ctx, session)
+	key, _ := apikey.NewAPIKey(u.ID, "backend", "pk_live_abc", "secret-hash", []string{apikey.ScopePaymentsRead}, nil)
+	keys.Create(ctx, key)
+
+	events.Append(ctx, &audit.Event{Type: audit.EventUserRegistered, SubjectID: u.ID})
+	events.Append(ctx, &audit.Event{Type: audit.EventUserRegistered, SubjectID: other.ID})
+
+	return privacy.NewService(users, sessions, keys, events), u
+}
+
+func TestService_Export(t *testing.T) {
+	service, u := seededService(t)
+
+	archive, err := service.Export(context.Background(), u.ID)
+	if err != nil {
+		t.Fatalf("Export() unexpected error = %v", err)
+	}
+	if archive.Profile.ID != u.ID || archive.Profile.Email != "test@example.com" {
+		t.Errorf("Export() profile = %+v", archive.Profile)
+	}
+	if len(archive.Sessions) != 1 || archive.Sessions[0].IP != "192.0.2.1" {
+		t.Errorf("Export() sessions = %+v", archive.Sessions)
+	}
+	if len(archive.APIKeys) != 1 || archive.APIKeys[0].Label != "backend" {
+		t.Errorf("Export() api keys = %+v", archive.APIKeys)
+	}
+	if len(archive.AuditEvents) != 1 || archive.AuditEvents[0].SubjectID != u.ID {
+		t.Errorf("Export() audit events = %+v", archive.AuditEvents)
+	}
+
+	var buf bytes.Buffer
+	archive.WriteJSON(&buf)
+	if strings.Contains(buf.String(), "secret-hash") {
+		t.Error("Export() must not include password or API key secret hashes")
+	}
+
+	if _, err := service.Export(context.Background(), "missing"); err != privacy.ErrUserNotFound {
+		t.Errorf("Export() of an unknown user error = %v, want %v", err, privacy.ErrUserNotFound)
+	}
+}
+
+func TestArchive_WriteZIP(t *testing.T) {
+	service, u := seededService(t)
+	archive, _ := service.Export(context.Background(), u.ID)
+
+	var buf bytes.Buffer
+	if err := archive.WriteZIP(&buf); err != nil {
+		t.Fatalf("WriteZIP() unexpected error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("WriteZIP() did not produce a ZIP archive: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "profile.json" {
+			continue
+		}
+		rc, _ := f.Open()
+		var profile privacy.Profile
+		err := json.NewDecoder(rc).Decode(&profile)
+		rc.Close()
+		if err != nil || profile.ID != u.ID {
+			t.Errorf("profile.json = %+v, %v", profile, err)
+		}
+		return
+	}
+	t.Error("WriteZIP() archive has no profile.json")
+}
@@ -0,0 +1,31 @@
+package user
+
+import (
+	"context"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+)
+
+// WithAuditLog records account and authentication events
+func WithAuditLog(recorder audit.Recorder) Option {
+	return func(s *Service) {
+		s.auditLog = recorder
+	}
+}
+
+func (s *Service) audit(ctx context.Context, event audit.Event) {
+	if s.auditLog != nil {
+		s.auditLog.Record(ctx, event)
+	}
+}
+
+// auditLoginFailure records a failed login attempt. subjectID is empty when
+// the account does not exist.
+func (s *Service) auditLoginFailure(ctx context.Context, email, subjectID, reason string) {
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventLoginFailed,
+		SubjectID: subjectID,
+		Reason:    reason,
+		Details:   map[string]string{"email": email},
+	})
+}
@@ -0,0 +1,145 @@
+package user_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+)
+
+// captureRecorder keeps recorded audit events in memory
+type captureRecorder struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (r *captureRecorder) Record(ctx context.Context, event audit.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *captureRecorder) types() []audit.EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	types := make([]audit.EventType, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func (r *captureRecorder) last() audit.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[len(r.events)-1]
+}
+
+func newAuditedService(opts ...userUseCase.Option) (*userUseCase.Service, *captureRecorder) {
+	recorder := &captureRecorder{}
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	opts = append(opts, userUseCase.WithAuditLog(recorder))
+	return userUseCase.NewService(user.NewInMemoryRepository(), jwtService, opts...), recorder
+}
+
+func TestService_Audit_Login(t *testing.T) {
+	service, recorder := newAuditedService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	if e := recorder.last(); e.Type != audit.EventUserRegistered || e.SubjectID != u.ID || e.ActorID != u.ID {
+		t.Errorf("Register() audit event = %+v", e)
+	}
+
+	tests := []struct {
+		name      string
+		email     string
+		password  string
+		eventType audit.EventType
+		reason    string
+		subjectID string
+	}{
+		{name: "unknown account", email: "nobody@example.com", password: "password123",
+			eventType: audit.EventLoginFailed, reason: audit.ReasonUnknownAccount},
+		{name: "wrong password", email: "test@example.com", password: "wrongpassword",
+			eventType: audit.EventLoginFailed, reason: audit.ReasonInvalidPassword, subjectID: u.ID},
+		{name: "success", email: "test@example.com", password: "password123",
+			eventType: audit.EventLoginSucceeded, subjectID: u.ID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.Login(ctx, tt.email, tt.password)
+
+			e := recorder.last()
+			if e.Type != tt.eventType || e.Reason != tt.reason || e.SubjectID != tt.subjectID {
+				t.Errorf("Login() audit event = %+v, want %s (%q) for %q", e, tt.eventType, tt.reason, tt.subjectID)
+			}
+		})
+	}
+}
+
+func TestService_Audit_Lockout(t *testing.T) {
+	accounts := lockout.NewTracker(lockout.NewMemoryStore(), lockout.Policy{MaxAttempts: 2, LockoutDuration: time.Hour, Window: time.Hour})
+	service, recorder := newAuditedService(userUseCase.WithLoginThrottle(accounts, nil))
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	service.Login(ctx, "test@example.com", "wrongpassword")
+	service.Login(ctx, "test@example.com", "wrongpassword")
+	service.Login(ctx, "test@example.com", "password123")
+
+	want := []audit.EventType{
+		audit.EventUserRegistered,
+		audit.EventLoginFailed,
+		audit.EventLoginFailed,
+		audit.EventLoginLocked,
+		audit.EventLoginFailed,
+	}
+	got := recorder.types()
+	if len(got) != len(want) {
+		t.Fatalf("recorded %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("recorded %v, want %v", got, want)
+		}
+	}
+	if e := recorder.last(); e.Reason != audit.ReasonLockedOut {
+		t.Errorf("login while locked reason = %q, want %q", e.Reason, audit.ReasonLockedOut)
+	}
+}
+
+func TestService_Audit_AccountChanges(t *testing.T) {
+	service, recorder := newAuditedService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	service.ChangePassword(ctx, u.ID, "password123", "newpassword456")
+	if e := recorder.last(); e.Type != audit.EventAllTokensRevoked {
+		t.Errorf("ChangePassword() should end by revoking all tokens, got %+v", e)
+	}
+	if got := recorder.types(); got[len(got)-2] != audit.EventPasswordChanged {
+		t.Errorf("ChangePassword() should record %s, got %v", audit.EventPasswordChanged, got)
+	}
+
+	service.SetRole(ctx, u.ID, domainUser.RoleSupport)
+	e := recorder.last()
+	if e.Type != audit.EventRoleChanged || e.Details["role"] != "support" || e.Details["previous_role"] != "merchant_owner" {
+		t.Errorf("SetRole() audit event = %+v", e)
+	}
+
+	service.SetMFARequired(ctx, u.ID, true)
+	if e := recorder.last(); e.Type != audit.EventMFARequirementSet || e.Details["required"] != "true" {
+		t.Errorf("SetMFARequired() audit event = %+v", e)
+	}
+}
@@ -0,0 +1,121 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+)
+
+// defaultDeletionGracePeriod is how long a deleted account can still be
+// restored by signing in
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+// AccessRevoker revokes credentials another module issued to a user, such
+// as API keys, when the account is deleted
+type AccessRevoker interface {
+	RevokeAllForUser(ctx context.Context, userID string) error
+}
+
+// WithAccountDeletion sets how long deleted accounts wait before being
+// anonymized, and which other credentials of the user to revoke when
+// deletion is requested
+func WithAccountDeletion(gracePeriod time.Duration, revokers ...AccessRevoker) Option {
+	return func(s *Service) {
+		s.deletionGracePeriod = gracePeriod
+		s.accessRevokers = revokers
+	}
+}
+
+// DeleteAccount schedules the account for deletion after checking the
+// password, and signs the user out everywhere, in one transaction.
+// Signing in again before the returned time cancels the deletion;
+// afterwards PurgeDeletedAccounts anonymizes the account.
+func (s *Service) DeleteAccount(ctx context.Context, userID, pwd string) (time.Time, error) {
+	var dueAt time.Time
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		u, err := s.repo.FindByID(ctx, userID)
+		if err != nil || u.IsAnonymized() {
+			return ErrUserNotFound
+		}
+		if !password.Verify(pwd, u.PasswordHash) {
+			return ErrIncorrectPassword
+		}
+
+		u.RequestDeletion()
+		if err := s.repo.Update(ctx, u); err != nil {
+			return err
+		}
+		for _, revoker := range s.accessRevokers {
+			if err := revoker.RevokeAllForUser(ctx, u.ID); err != nil {
+				return err
+			}
+		}
+		if err := s.endAllSessions(ctx, u.ID); err != nil {
+			return err
+		}
+
+		dueAt = u.DeletionDueAt(s.deletionGracePeriod)
+		s.audit(ctx, audit.Event{
+			Type:      audit.EventDeletionRequested,
+			ActorID:   u.ID,
+			SubjectID: u.ID,
+			Details:   map[string]string{"due_at": dueAt.UTC().Format(time.RFC3339)},
+		})
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := s.jwtService.RevokeAllForUser(userID); err != nil {
+		return time.Time{}, err
+	}
+	return dueAt, nil
+}
+
+// cancelDeletion restores an account pending deletion when its owner signs
+// in during the grace period
+func (s *Service) cancelDeletion(ctx context.Context, u *user.User) error {
+	if !u.IsPendingDeletion() {
+		return nil
+	}
+
+	u.CancelDeletion()
+	if err := s.repo.Update(ctx, u); err != nil {
+		return err
+	}
+	s.audit(ctx, audit.Event{Type: audit.EventDeletionCancelled, ActorID: u.ID, SubjectID: u.ID})
+	return nil
+}
+
+// PurgeDeletedAccounts anonymizes accounts whose grace period has ended and
+// returns how many were anonymized
+func (s *Service) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.deletionGracePeriod)
+	due, err := s.repo.ListPendingDeletion(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, u := range due {
+		// Accounts restored by a sign-in since they were listed are skipped
+		anonymized, err := s.repo.Anonymize(ctx, u.ID, cutoff)
+		if err != nil {
+			return purged, err
+		}
+		if !anonymized {
+			continue
+		}
+		if s.webAuthnRepo != nil {
+			if err := s.webAuthnRepo.DeleteAllForUser(ctx, u.ID); err != nil {
+				return purged, err
+			}
+		}
+		purged++
+		s.audit(ctx, audit.Event{Type: audit.EventUserAnonymized, SubjectID: u.ID})
+	}
+	return purged, nil
+}
@@ -0,0 +1,101 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+// fakeRevoker records the users whose credentials were revoked
+type fakeRevoker struct {
+	revoked []string
+}
+
+func (r *fakeRevoker) RevokeAllForUser(ctx context.Context, userID string) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+func newDeletionService(gracePeriod time.Duration) (*userUseCase.Service, *fakeRevoker) {
+	revoker := &fakeRevoker{}
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+		userUseCase.WithAccountDeletion(gracePeriod, revoker),
+	)
+	return service, revoker
+}
+
+func TestService_DeleteAccount(t *testing.T) {
+	service, revoker := newDeletionService(time.Hour)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	result, _ := service.Login(ctx, "test@example.com", "password123")
+
+	if _, err := service.DeleteAccount(ctx, u.ID, "wrongpassword"); !errors.Is(err, userUseCase.ErrIncorrectPassword) {
+		t.Fatalf("DeleteAccount() with a wrong password error = %v, want %v", err, userUseCase.ErrIncorrectPassword)
+	}
+
+	dueAt, err := service.DeleteAccount(ctx, u.ID, "password123")
+	if err != nil {
+		t.Fatalf("DeleteAccount() unexpected error = %v", err)
+	}
+	if until := time.Until(dueAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("DeleteAccount() due at %v, want in one hour", dueAt)
+	}
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != u.ID {
+		t.Errorf("DeleteAccount() should revoke other credentials, revoked %v", revoker.revoked)
+	}
+	if _, err := service.Refresh(ctx, result.Tokens.RefreshToken); err == nil {
+		t.Error("DeleteAccount() should sign the user out everywhere")
+	}
+
+	// Nothing is due within the grace period
+	if purged, _ := service.PurgeDeletedAccounts(ctx); purged != 0 {
+		t.Errorf("PurgeDeletedAccounts() during the grace period = %d, want 0", purged)
+	}
+
+	// Signing in again cancels the deletion
+	if _, err := service.Login(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("Login() during the grace period unexpected error = %v", err)
+	}
+	profile, _ := service.GetProfile(ctx, u.ID)
+	if profile.IsPendingDeletion() {
+		t.Error("Login() should cancel a pending deletion")
+	}
+}
+
+func TestService_PurgeDeletedAccounts(t *testing.T) {
+	service, _ := newDeletionService(0)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	service.DeleteAccount(ctx, u.ID, "password123")
+
+	purged, err := service.PurgeDeletedAccounts(ctx)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedAccounts() = %d, %v, want 1", purged, err)
+	}
+
+	profile, _ := service.GetProfile(ctx, u.ID)
+	if !profile.IsAnonymized() || profile.Email == "test@example.com" {
+		t.Errorf("PurgeDeletedAccounts() should anonymize the account, got %+v", profile)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "password123"); !errors.Is(err, userUseCase.ErrInvalidCredentials) {
+		t.Errorf("Login() after anonymization error = %v, want %v", err, userUseCase.ErrInvalidCredentials)
+	}
+	if _, err := service.DeleteAccount(ctx, u.ID, "password123"); !errors.Is(err, userUseCase.ErrUserNotFound) {
+		t.Errorf("DeleteAccount() of an anonymized account error = %v, want %v", err, userUseCase.ErrUserNotFound)
+	}
+
+	// The address is free again
+	if _, err := service.Register(ctx, "newuser", "test@example.com", "password123"); err != nil {
+		t.Errorf("Register() with the address of an anonymized account unexpected error = %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package user
+
+import (
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+// WithEmailBlocklist rejects registrations and email changes to addresses
+// at blocked domains, typically disposable email providers
+func WithEmailBlocklist(blocklist *user.DomainBlocklist) Option {
+	return func(s *Service) {
+		s.emailBlocklist = blocklist
+	}
+}
+
+// parseEmail validates an address a user wants to register or switch to
+func (s *Service) parseEmail(raw string) (user.Email, error) {
+	address, err := user.ParseEmail(raw)
+	if err != nil {
+		return user.Email{}, err
+	}
+	if s.emailBlocklist != nil && s.emailBlocklist.Blocks(address) {
+		return user.Email{}, user.ErrDisposableEmail
+	}
+	return address, nil
+}
@@ -0,0 +1,60 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func TestService_Register_CanonicalEmail(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	service := userUseCase.NewService(repo, jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute))
+	ctx := context.Background()
+
+	u, err := service.Register(ctx, "john", "John@Example.com", "password123")
+	if err != nil {
+		t.Fatalf("Register() unexpected error = %v", err)
+	}
+	if u.Email != "john@example.com" {
+		t.Errorf("Register() email = %q, want john@example.com", u.Email)
+	}
+
+	if _, err := service.Register(ctx, "john2", "john@EXAMPLE.com", "password123"); err == nil {
+		t.Error("Register() should reject an address differing only in case")
+	}
+
+	if _, err := service.Register(ctx, "john3", "John <john3@example.com>", "password123"); !errors.Is(err, domainUser.ErrInvalidEmail) {
+		t.Errorf("Register() error = %v, want %v", err, domainUser.ErrInvalidEmail)
+	}
+
+	// Any case logs in
+	mustLogin(t, service, "JOHN@example.com", "password123")
+}
+
+func TestService_EmailBlocklist(t *testing.T) {
+	service := userUseCase.NewService(user.NewInMemoryRepository(),
+		jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute),
+		userUseCase.WithEmailBlocklist(domainUser.NewDomainBlocklist([]string{"mailinator.com"})),
+	)
+	ctx := context.Background()
+
+	if _, err := service.Register(ctx, "john", "john@mailinator.com", "password123"); !errors.Is(err, domainUser.ErrDisposableEmail) {
+		t.Fatalf("Register() error = %v, want %v", err, domainUser.ErrDisposableEmail)
+	}
+
+	u, err := service.Register(ctx, "john", "john@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Register() unexpected error = %v", err)
+	}
+
+	email := "john@eu.mailinator.com"
+	if _, err := service.UpdateProfile(ctx, u.ID, userUseCase.ProfileUpdate{Email: &email}); !errors.Is(err, domainUser.ErrDisposableEmail) {
+		t.Errorf("UpdateProfile() error = %v, want %v", err, domainUser.ErrDisposableEmail)
+	}
+}
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+const defaultImpersonationTTL = 15 * time.Minute
+
+var (
+	ErrImpersonationReasonRequired = errors.New("a reason for impersonating is required")
+	ErrCannotImpersonate           = errors.New("this account cannot be impersonated")
+)
+
+// Impersonation is a short-lived access token letting an administrator act
+// as another user
+type Impersonation struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+}
+
+// WithImpersonationTTL sets how long impersonation tokens stay valid. It is
+// capped at the access token duration.
+func WithImpersonationTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.impersonationTTL = ttl
+	}
+}
+
+// Impersonate issues a token for the target user carrying actor as the
+// real user. There is no refresh token, and the token is tied to the
+// actor's session, so signing the actor out ends the impersonation too.
+// Administrators cannot be impersonated and impersonations cannot be
+// nested. reason is recorded in the audit log.
+func (s *Service) Impersonate(ctx context.Context, actor *jwt.Claims, targetID, reason string) (*Impersonation, error) {
+	if actor == nil {
+		return nil, ErrMissingClaims
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrImpersonationReasonRequired
+	}
+	if actor.IsImpersonation() || actor.UserID == targetID {
+		return nil, ErrCannotImpersonate
+	}
+
+	target, err := s.repo.FindByID(ctx, targetID)
+	if err != nil || target.IsAnonymized() {
+		return nil, ErrUserNotFound
+	}
+	if target.Role == user.RoleAdmin {
+		return nil, ErrCannotImpersonate
+	}
+
+	ttl := min(s.impersonationTTL, s.jwtService.TokenDuration())
+	token, err := s.jwtService.GenerateImpersonationToken(jwt.Claims{
+		UserID:        target.ID,
+		Email:         target.Email,
+		EmailVerified: target.EmailVerified,
+		Role:          string(target.Role),
+		SessionID:     actor.SessionID,
+	}, jwt.Actor{UserID: actor.UserID, Email: actor.Email}, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventImpersonationStarted,
+		ActorID:   actor.UserID,
+		SubjectID: target.ID,
+		Reason:    reason,
+		Details:   map[string]string{"session_id": actor.SessionID, "expires_in": ttl.String()},
+	})
+	return &Impersonation{AccessToken: token, ExpiresIn: ttl}, nil
+}
@@ -0,0 +1,109 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func newImpersonationService() (*userUseCase.Service, *jwt.Service) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithSessions(user.NewInMemorySessionRepository(), time.Hour),
+		userUseCase.WithAdminEmails([]string{"admin@example.com", "admin2@example.com"}),
+	)
+	return service, jwtService
+}
+
+// loginAdmin registers an admin, logs in and returns the access token claims
+func loginAdmin(t *testing.T, service *userUseCase.Service, jwtService *jwt.Service) *jwt.Claims {
+	t.Helper()
+
+	service.Register(context.Background(), "admin", "admin@example.com", "password123")
+	tokens := mustLogin(t, service, "admin@example.com", "password123")
+	claims, err := jwtService.ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() unexpected error = %v", err)
+	}
+	return claims
+}
+
+func TestService_Impersonate(t *testing.T) {
+	service, jwtService := newImpersonationService()
+	ctx := context.Background()
+
+	actor := loginAdmin(t, service, jwtService)
+	target, _ := service.Register(ctx, "merchant", "merchant@example.com", "password123")
+
+	impersonation, err := service.Impersonate(ctx, actor, target.ID, "ticket #42")
+	if err != nil {
+		t.Fatalf("Impersonate() unexpected error = %v", err)
+	}
+	if impersonation.ExpiresIn > jwtService.TokenDuration() {
+		t.Errorf("Impersonate() ExpiresIn = %v, should not exceed the access token duration", impersonation.ExpiresIn)
+	}
+
+	claims, err := jwtService.ValidateToken(impersonation.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() unexpected error = %v", err)
+	}
+	if claims.UserID != target.ID || claims.Role != string(domainUser.RoleMerchantOwner) || claims.RealUserID() != actor.UserID {
+		t.Errorf("impersonation claims = %+v, actor %+v", claims, claims.Actor)
+	}
+	if claims.SessionID != actor.SessionID {
+		t.Errorf("impersonation sid = %q, want the actor's session %q", claims.SessionID, actor.SessionID)
+	}
+
+	// Impersonations cannot be nested
+	if _, err := service.Impersonate(ctx, claims, actor.UserID, "escalate"); !errors.Is(err, userUseCase.ErrCannotImpersonate) {
+		t.Errorf("Impersonate() from an impersonation error = %v, want %v", err, userUseCase.ErrCannotImpersonate)
+	}
+
+	// Logging out of the impersonation keeps the actor signed in
+	if err := service.Logout(ctx, claims, ""); err != nil {
+		t.Fatalf("Logout() unexpected error = %v", err)
+	}
+	if _, err := jwtService.ValidateToken(impersonation.AccessToken); !errors.Is(err, jwt.ErrRevokedToken) {
+		t.Errorf("ValidateToken() after logout error = %v, want %v", err, jwt.ErrRevokedToken)
+	}
+	if err := service.ValidateSession(ctx, actor.UserID, actor.SessionID); err != nil {
+		t.Errorf("ValidateSession() of the actor after ending the impersonation error = %v", err)
+	}
+}
+
+func TestService_Impersonate_Refused(t *testing.T) {
+	service, jwtService := newImpersonationService()
+	ctx := context.Background()
+
+	actor := loginAdmin(t, service, jwtService)
+	otherAdmin, _ := service.Register(ctx, "admin2", "admin2@example.com", "password123")
+	target, _ := service.Register(ctx, "merchant", "merchant@example.com", "password123")
+
+	tests := []struct {
+		name     string
+		targetID string
+		reason   string
+		wantErr  error
+	}{
+		{name: "missing reason", targetID: target.ID, reason: "  ", wantErr: userUseCase.ErrImpersonationReasonRequired},
+		{name: "oneself", targetID: actor.UserID, reason: "debug", wantErr: userUseCase.ErrCannotImpersonate},
+		{name: "another admin", targetID: otherAdmin.ID, reason: "debug", wantErr: userUseCase.ErrCannotImpersonate},
+		{name: "unknown user", targetID: "missing", reason: "debug", wantErr: userUseCase.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Impersonate(ctx, actor, tt.targetID, tt.reason); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Impersonate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/securetoken"
+)
+
+var (
+	ErrMissingClaims = errors.New("missing token claims")
+)
+
+// Logout revokes the access token described by claims, ends its session
+// and, if given, revokes the refresh token family it was issued with
+func (s *Service) Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error {
+	if claims == nil {
+		return ErrMissingClaims
+	}
+
+	if err := s.jwtService.RevokeToken(claims); err != nil {
+		return err
+	}
+	// An impersonation token shares the session of the impersonating user,
+	// which stays signed in
+	if s.sessionRepo != nil && claims.SessionID != "" && !claims.IsImpersonation() {
+		if err := s.sessionRepo.Revoke(ctx, claims.SessionID, time.Now()); err != nil {
+			log.Printf("Failed to end session %s: %v", claims.SessionID, err)
+		}
+	}
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventTokenRevoked,
+		ActorID:   claims.RealUserID(),
+		SubjectID: claims.UserID,
+		Reason:    "logout",
+		Details:   map[string]string{"jti": claims.ID, "session_id": claims.SessionID},
+	})
+
+	if s.refreshRepo == nil || refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.refreshRepo.FindByHash(ctx, securetoken.Hash(refreshToken))
+	if err != nil || stored.UserID != claims.UserID {
+		// Unknown or foreign refresh tokens are ignored; the access token is gone
+		return nil
+	}
+	return s.refreshRepo.RevokeFamily(ctx, stored.FamilyID)
+}
+
+// LogoutAll revokes every access and refresh token issued to the user so far
+// and ends all of their sessions
+func (s *Service) LogoutAll(ctx context.Context, userID string) error {
+	if err := s.jwtService.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	return s.withinTx(ctx, func(ctx context.Context) error {
+		return s.endAllSessions(ctx, userID)
+	})
+}
+
+// endAllSessions ends every session of the user and revokes their refresh
+// tokens. Access tokens are left to the caller, to revoke once the
+// transaction has committed, since their revocation cannot be rolled back.
+func (s *Service) endAllSessions(ctx context.Context, userID string) error {
+	if s.sessionRepo != nil {
+		if err := s.sessionRepo.RevokeAllForUser(ctx, userID, time.Now()); err != nil {
+			return err
+		}
+	}
+	s.audit(ctx, audit.Event{Type: audit.EventAllTokensRevoked, SubjectID: userID})
+
+	if s.refreshRepo == nil {
+		return nil
+	}
+	return s.refreshRepo.RevokeAllForUser(ctx, userID)
+}
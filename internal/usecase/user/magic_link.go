@@ -0,0 +1,140 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/securetoken"
+)
+
+const (
+	magicLinkPurpose = "magic_link"
+
+	defaultMagicLinkTTL = 15 * time.Minute
+)
+
+var (
+	ErrMagicLinksDisabled = errors.New("magic link login is not enabled")
+	ErrInvalidMagicLink   = errors.New("invalid or expired magic link")
+)
+
+// WithMagicLinks enables passwordless login links, stored in repo and valid
+// for ttl. Links are sent with the mailer set by WithMailer.
+func WithMagicLinks(repo user.MagicLinkRepository, ttl time.Duration) Option {
+	return func(s *Service) {
+		s.magicLinkRepo = repo
+		s.magicLinkTTL = ttl
+	}
+}
+
+// RequestMagicLink emails a single-use login link to the account owner and
+// returns a nonce the requesting browser must present when the link is
+// opened. Earlier links of the account are invalidated. A nonce is returned
+// for unknown addresses too, so the endpoint cannot be used to probe for
+// accounts.
+func (s *Service) RequestMagicLink(ctx context.Context, email string) (string, error) {
+	if s.magicLinkRepo == nil {
+		return "", ErrMagicLinksDisabled
+	}
+
+	nonce, err := securetoken.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return nonce, nil
+	}
+
+	token, err := s.jwtService.GeneratePurposeToken(u.ID, u.Email, magicLinkPurpose, s.magicLinkTTL)
+	if err != nil {
+		return "", err
+	}
+	link, err := user.NewMagicLink(u.ID, securetoken.Hash(token), securetoken.Hash(nonce), s.magicLinkTTL)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.magicLinkRepo.InvalidateAllForUser(ctx, u.ID); err != nil {
+		return "", err
+	}
+	if err := s.magicLinkRepo.Create(ctx, link); err != nil {
+		return "", err
+	}
+
+	if s.mailer == nil {
+		return nonce, nil
+	}
+	// Delivery failures are only logged; reporting them would reveal that
+	// the account exists
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below in the browser you requested it from to log in:\n\n%s\n\nThe link works once and expires in %s. If you did not ask for it, you can ignore this email.",
+			u.Username, s.baseURL+"/api/login/magic/confirm?token="+url.QueryEscape(token), s.magicLinkTTL),
+	})
+	if err != nil {
+		log.Printf("Failed to send magic link email to user %s: %v", u.ID, err)
+	}
+	return nonce, nil
+}
+
+// ConfirmMagicLink exchanges a magic link token and the nonce of the
+// browser that requested it for the same result as Login. Opening the link
+// proves ownership of the address, so it also verifies the email. A link
+// opened in another browser is refused without being consumed.
+func (s *Service) ConfirmMagicLink(ctx context.Context, token, nonce string) (*LoginResult, error) {
+	if s.magicLinkRepo == nil || token == "" || nonce == "" {
+		return nil, ErrInvalidMagicLink
+	}
+
+	claims, err := s.jwtService.ValidatePurposeToken(token, magicLinkPurpose)
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	link, err := s.magicLinkRepo.FindByHash(ctx, securetoken.Hash(token))
+	if err != nil || link.UserID != claims.UserID {
+		return nil, ErrInvalidMagicLink
+	}
+
+	now := time.Now()
+	if link.IsUsed() || link.IsExpired(now) {
+		return nil, ErrInvalidMagicLink
+	}
+	if !link.MatchesNonce(securetoken.Hash(nonce)) {
+		s.auditLoginFailure(ctx, claims.Email, link.UserID, audit.ReasonOtherBrowser)
+		return nil, ErrInvalidMagicLink
+	}
+
+	// Links sent before an email change stop working
+	u, err := s.repo.FindByID(ctx, link.UserID)
+	if err != nil || u.Email != claims.Email {
+		return nil, ErrInvalidMagicLink
+	}
+
+	// Consume the link first so two concurrent confirmations cannot both succeed
+	marked, err := s.magicLinkRepo.MarkUsed(ctx, link.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, ErrInvalidMagicLink
+	}
+
+	if !u.EmailVerified {
+		u.MarkEmailVerified()
+		if err := s.repo.Update(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+	return s.completeLogin(ctx, u, "magic_link", false)
+}
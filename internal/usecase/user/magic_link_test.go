@@ -0,0 +1,123 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+)
+
+func newMagicLinkService() (*userUseCase.Service, *captureMailer, *captureRecorder) {
+	mailer := &captureMailer{}
+	service, recorder := newAuditedService(
+		userUseCase.WithMailer(mailer, "http://localhost:8080"),
+		userUseCase.WithMagicLinks(user.NewInMemoryMagicLinkRepository(), time.Minute),
+	)
+	return service, mailer, recorder
+}
+
+func TestService_MagicLink(t *testing.T) {
+	service, mailer, _ := newMagicLinkService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	nonce, err := service.RequestMagicLink(ctx, "Test@Example.com")
+	if err != nil || nonce == "" {
+		t.Fatalf("RequestMagicLink() = %q, %v; want a nonce", nonce, err)
+	}
+	token := mailer.lastToken(t)
+
+	result, err := service.ConfirmMagicLink(ctx, token, nonce)
+	if err != nil || result.Tokens == nil {
+		t.Fatalf("ConfirmMagicLink() = %+v, %v; want tokens", result, err)
+	}
+
+	// Opening the link proves ownership of the address
+	profile, _ := service.GetProfile(ctx, u.ID)
+	if !profile.EmailVerified {
+		t.Error("ConfirmMagicLink() should verify the email")
+	}
+
+	// The link is single-use
+	if _, err := service.ConfirmMagicLink(ctx, token, nonce); !errors.Is(err, userUseCase.ErrInvalidMagicLink) {
+		t.Errorf("ConfirmMagicLink() reused link error = %v, want %v", err, userUseCase.ErrInvalidMagicLink)
+	}
+}
+
+func TestService_MagicLink_OtherBrowser(t *testing.T) {
+	service, mailer, recorder := newMagicLinkService()
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	nonce, _ := service.RequestMagicLink(ctx, "test@example.com")
+	token := mailer.lastToken(t)
+
+	for _, other := range []string{"", "forged-nonce"} {
+		if _, err := service.ConfirmMagicLink(ctx, token, other); !errors.Is(err, userUseCase.ErrInvalidMagicLink) {
+			t.Errorf("ConfirmMagicLink(nonce %q) error = %v, want %v", other, err, userUseCase.ErrInvalidMagicLink)
+		}
+	}
+	if e := recorder.last(); e.Type != audit.EventLoginFailed || e.Reason != audit.ReasonOtherBrowser {
+		t.Errorf("last event = %+v, want login failure from another browser", e)
+	}
+
+	// Opening the link elsewhere, or an email scanner prefetching it, does
+	// not consume it
+	if _, err := service.ConfirmMagicLink(ctx, token, nonce); err != nil {
+		t.Errorf("ConfirmMagicLink() from the requesting browser unexpected error = %v", err)
+	}
+}
+
+func TestService_MagicLink_NewRequestInvalidatesOldLink(t *testing.T) {
+	service, mailer, _ := newMagicLinkService()
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	oldNonce, _ := service.RequestMagicLink(ctx, "test@example.com")
+	oldToken := mailer.lastToken(t)
+
+	service.RequestMagicLink(ctx, "test@example.com")
+
+	if _, err := service.ConfirmMagicLink(ctx, oldToken, oldNonce); !errors.Is(err, userUseCase.ErrInvalidMagicLink) {
+		t.Errorf("ConfirmMagicLink() superseded link error = %v, want %v", err, userUseCase.ErrInvalidMagicLink)
+	}
+}
+
+func TestService_MagicLink_UnknownEmail(t *testing.T) {
+	service, mailer, _ := newMagicLinkService()
+
+	nonce, err := service.RequestMagicLink(context.Background(), "nobody@example.com")
+	if err != nil || nonce == "" {
+		t.Fatalf("RequestMagicLink() unknown email = %q, %v; want a nonce", nonce, err)
+	}
+	if mailer.count() != 0 {
+		t.Error("no email should be sent for an unknown address")
+	}
+}
+
+func TestService_MagicLink_MFA(t *testing.T) {
+	service, mailer, _ := newMagicLinkService()
+	ctx := context.Background()
+
+	userID, _, _ := enrollTOTP(t, service)
+	nonce, _ := service.RequestMagicLink(ctx, "test@example.com")
+
+	// The link replaces the password, not the second factor
+	result, err := service.ConfirmMagicLink(ctx, mailer.lastToken(t), nonce)
+	if err != nil || result.MFAToken == "" || result.Tokens != nil {
+		t.Fatalf("ConfirmMagicLink() for user %s = %+v, %v; want an MFA challenge", userID, result, err)
+	}
+}
+
+func TestService_MagicLink_Disabled(t *testing.T) {
+	service := newMFAService()
+
+	if _, err := service.RequestMagicLink(context.Background(), "test@example.com"); !errors.Is(err, userUseCase.ErrMagicLinksDisabled) {
+		t.Errorf("RequestMagicLink() error = %v, want %v", err, userUseCase.ErrMagicLinksDisabled)
+	}
+}
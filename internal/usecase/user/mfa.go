@@ -0,0 +1,244 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/securetoken"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/totp"
+)
+
+const (
+	mfaTokenPurpose = "mfa"
+
+	defaultTOTPIssuer      = "Crypto Payment Gateway"
+	defaultMFAChallengeTTL = 5 * time.Minute
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10
+)
+
+var (
+	ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+	ErrInvalidMFACode  = errors.New("invalid two-factor code")
+	ErrMFARequired     = errors.New("two-factor authentication is required for this account")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// LoginResult is the outcome of a password login. Either Tokens is set, or
+// the account has a second factor and MFAToken must be exchanged together
+// with a code through VerifyMFA.
+type LoginResult struct {
+	Tokens   *Tokens
+	MFAToken string
+}
+
+// TOTPEnrollment holds what an authenticator app needs to be set up
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// WithTOTPIssuer sets the issuer name shown in authenticator apps
+func WithTOTPIssuer(issuer string) Option {
+	return func(s *Service) {
+		s.totpIssuer = issuer
+	}
+}
+
+// VerifyMFA completes a two-step login by exchanging the MFA token returned
+// from Login and a TOTP or recovery code for access tokens. MFA tokens are
+// single-use, so a wrong code means logging in with the password again.
+func (s *Service) VerifyMFA(ctx context.Context, mfaToken, code string) (*Tokens, error) {
+	claims, err := s.jwtService.ValidatePurposeToken(mfaToken, mfaTokenPurpose)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	u, err := s.repo.FindByID(ctx, claims.UserID)
+	if err != nil || !u.MFAEnabled() {
+		return nil, ErrInvalidMFAToken
+	}
+
+	// Wrong codes count towards the same limits as wrong passwords
+	if err := s.checkLoginThrottle(ctx, u.Email); err != nil {
+		s.auditLoginFailure(ctx, u.Email, u.ID, audit.ReasonLockedOut)
+		return nil, err
+	}
+
+	if err := s.jwtService.RevokeToken(claims); err != nil {
+		return nil, err
+	}
+
+	if !s.verifySecondFactor(u, code) {
+		s.auditLoginFailure(ctx, u.Email, u.ID, audit.ReasonInvalidMFACode)
+		s.recordLoginFailure(ctx, u.Email)
+		return nil, ErrInvalidMFACode
+	}
+
+	// Persist the consumed TOTP step or recovery code
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startLogin(ctx, u, "mfa")
+	if err != nil {
+		return nil, err
+	}
+	s.resetLoginFailures(ctx, u.Email)
+	return tokens, nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. It only becomes
+// active once confirmed with ConfirmTOTP.
+func (s *Service) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.StartTOTPEnrollment(secret); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.totpIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP activates the pending TOTP secret using a first code from the
+// authenticator app and returns freshly generated recovery codes. The codes
+// are only stored hashed, so this is the only time they can be shown.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPPendingSecret == "" {
+		return nil, user.ErrNoPendingEnrollment
+	}
+
+	step, ok := totp.Validate(u.TOTPPendingSecret, strings.TrimSpace(code), time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.ConfirmTOTPEnrollment(step, hashes); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP removes the user's second factor after checking a current
+// TOTP or recovery code. Accounts required to use MFA cannot disable it.
+func (s *Service) DisableTOTP(ctx context.Context, userID, code string) error {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.MFAEnabled() {
+		return user.ErrMFANotEnabled
+	}
+	if u.MFARequired {
+		return ErrMFARequired
+	}
+
+	if !s.verifySecondFactor(u, code) {
+		return ErrInvalidMFACode
+	}
+
+	u.DisableTOTP()
+	return s.repo.Update(ctx, u)
+}
+
+// SetMFARequired lets an administrator require a second factor for an account
+func (s *Service) SetMFARequired(ctx context.Context, userID string, required bool) error {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	u.MFARequired = required
+	u.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, u); err != nil {
+		return err
+	}
+
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventMFARequirementSet,
+		SubjectID: u.ID,
+		Details:   map[string]string{"required": strconv.FormatBool(required)},
+	})
+	return nil
+}
+
+// verifySecondFactor checks a TOTP code or, failing that, a recovery code.
+// On success the user is mutated to prevent reuse and must be persisted.
+func (s *Service) verifySecondFactor(u *user.User, code string) bool {
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(u.TOTPSecret, code, time.Now(), u.TOTPLastStep); ok {
+		u.TOTPLastStep = step
+		return true
+	}
+
+	return u.ConsumeRecoveryCode(securetoken.Hash(normalizeRecoveryCode(code)))
+}
+
+// generateRecoveryCodes returns recovery codes formatted for display along
+// with the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+
+		// Group as xxxx-xxxx-xxxx-xxxx for readability
+		groups := make([]string, 0, len(raw)/4)
+		for j := 0; j < len(raw); j += 4 {
+			groups = append(groups, raw[j:j+4])
+		}
+
+		codes = append(codes, strings.Join(groups, "-"))
+		hashes = append(hashes, securetoken.Hash(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips the separators users may or may not type
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
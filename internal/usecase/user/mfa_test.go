@@ -0,0 +1,207 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/totp"
+)
+
+func newMFAService() *userUseCase.Service {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	return userUseCase.NewService(user.NewInMemoryRepository(), jwtService)
+}
+
+// enrollTOTP registers a user with TOTP enabled and returns its ID, secret
+// and recovery codes
+func enrollTOTP(t *testing.T, service *userUseCase.Service) (string, string, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	enrollment, err := service.EnrollTOTP(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() unexpected error = %v", err)
+	}
+
+	code, _ := totp.Code(enrollment.Secret, time.Now())
+	recoveryCodes, err := service.ConfirmTOTP(ctx, u.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() unexpected error = %v", err)
+	}
+
+	return u.ID, enrollment.Secret, recoveryCodes
+}
+
+// nextCode returns a code for the following time step, since the current
+// one was consumed by the enrollment confirmation
+func nextCode(secret string) string {
+	code, _ := totp.Code(secret, time.Now().Add(totp.Period))
+	return code
+}
+
+func TestService_EnrollTOTP(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	enrollment, err := service.EnrollTOTP(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() unexpected error = %v", err)
+	}
+
+	if enrollment.Secret == "" || enrollment.ProvisioningURI == "" {
+		t.Errorf("EnrollTOTP() = %+v, want secret and provisioning URI", enrollment)
+	}
+
+	// Until confirmed, login still works with the password alone
+	if tokens := mustLogin(t, service, "test@example.com", "password123"); tokens.AccessToken == "" {
+		t.Error("Login() should not require MFA before enrollment is confirmed")
+	}
+}
+
+func TestService_ConfirmTOTP_InvalidCode(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	_, _ = service.EnrollTOTP(ctx, u.ID)
+
+	if _, err := service.ConfirmTOTP(ctx, u.ID, "000000"); err != userUseCase.ErrInvalidMFACode {
+		t.Errorf("ConfirmTOTP() expected ErrInvalidMFACode, got %v", err)
+	}
+}
+
+func TestService_Login_WithTOTP(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+	_, secret, recoveryCodes := enrollTOTP(t, service)
+
+	if len(recoveryCodes) != 10 {
+		t.Errorf("ConfirmTOTP() returned %d recovery codes, want 10", len(recoveryCodes))
+	}
+
+	result, err := service.Login(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+	if result.Tokens != nil || result.MFAToken == "" {
+		t.Fatalf("Login() = %+v, want only an MFA token", result)
+	}
+
+	tokens, err := service.VerifyMFA(ctx, result.MFAToken, nextCode(secret))
+	if err != nil {
+		t.Fatalf("VerifyMFA() unexpected error = %v", err)
+	}
+	if tokens.AccessToken == "" {
+		t.Error("VerifyMFA() should return an access token")
+	}
+
+	// MFA tokens are single-use
+	if _, err := service.VerifyMFA(ctx, result.MFAToken, nextCode(secret)); err != userUseCase.ErrInvalidMFAToken {
+		t.Errorf("VerifyMFA() reusing MFA token expected ErrInvalidMFAToken, got %v", err)
+	}
+}
+
+func TestService_VerifyMFA_RejectsReplayedCode(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+	_, secret, _ := enrollTOTP(t, service)
+	code := nextCode(secret)
+
+	first, _ := service.Login(ctx, "test@example.com", "password123")
+	if _, err := service.VerifyMFA(ctx, first.MFAToken, code); err != nil {
+		t.Fatalf("VerifyMFA() unexpected error = %v", err)
+	}
+
+	second, _ := service.Login(ctx, "test@example.com", "password123")
+	if _, err := service.VerifyMFA(ctx, second.MFAToken, code); err != userUseCase.ErrInvalidMFACode {
+		t.Errorf("VerifyMFA() replayed code expected ErrInvalidMFACode, got %v", err)
+	}
+}
+
+func TestService_VerifyMFA_RecoveryCodeIsSingleUse(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+	_, _, recoveryCodes := enrollTOTP(t, service)
+
+	first, _ := service.Login(ctx, "test@example.com", "password123")
+	if _, err := service.VerifyMFA(ctx, first.MFAToken, recoveryCodes[0]); err != nil {
+		t.Fatalf("VerifyMFA() with recovery code unexpected error = %v", err)
+	}
+
+	second, _ := service.Login(ctx, "test@example.com", "password123")
+	if _, err := service.VerifyMFA(ctx, second.MFAToken, recoveryCodes[0]); err != userUseCase.ErrInvalidMFACode {
+		t.Errorf("VerifyMFA() reused recovery code expected ErrInvalidMFACode, got %v", err)
+	}
+}
+
+func TestService_VerifyMFA_WrongCodeInvalidatesChallenge(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+	_, secret, _ := enrollTOTP(t, service)
+
+	result, _ := service.Login(ctx, "test@example.com", "password123")
+
+	if _, err := service.VerifyMFA(ctx, result.MFAToken, "000000"); err != userUseCase.ErrInvalidMFACode {
+		t.Fatalf("VerifyMFA() expected ErrInvalidMFACode, got %v", err)
+	}
+
+	if _, err := service.VerifyMFA(ctx, result.MFAToken, nextCode(secret)); err != userUseCase.ErrInvalidMFAToken {
+		t.Errorf("VerifyMFA() after a wrong code expected ErrInvalidMFAToken, got %v", err)
+	}
+}
+
+func TestService_Login_MFARequiredWithoutEnrollment(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	if err := service.SetMFARequired(ctx, u.ID, true); err != nil {
+		t.Fatalf("SetMFARequired() unexpected error = %v", err)
+	}
+
+	if _, err := service.Login(ctx, "test@example.com", "password123"); err != userUseCase.ErrMFARequired {
+		t.Errorf("Login() expected ErrMFARequired, got %v", err)
+	}
+}
+
+func TestService_SetMFARequired_UnknownUser(t *testing.T) {
+	service := newMFAService()
+
+	if err := service.SetMFARequired(context.Background(), "missing", true); err != userUseCase.ErrUserNotFound {
+		t.Errorf("SetMFARequired() expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestService_DisableTOTP(t *testing.T) {
+	service := newMFAService()
+	ctx := context.Background()
+	userID, secret, _ := enrollTOTP(t, service)
+
+	if err := service.DisableTOTP(ctx, userID, "000000"); err != userUseCase.ErrInvalidMFACode {
+		t.Fatalf("DisableTOTP() with wrong code expected ErrInvalidMFACode, got %v", err)
+	}
+
+	_ = service.SetMFARequired(ctx, userID, true)
+	if err := service.DisableTOTP(ctx, userID, nextCode(secret)); err != userUseCase.ErrMFARequired {
+		t.Fatalf("DisableTOTP() on a required account expected ErrMFARequired, got %v", err)
+	}
+
+	_ = service.SetMFARequired(ctx, userID, false)
+	if err := service.DisableTOTP(ctx, userID, nextCode(secret)); err != nil {
+		t.Fatalf("DisableTOTP() unexpected error = %v", err)
+	}
+
+	if tokens := mustLogin(t, service, "test@example.com", "password123"); tokens.AccessToken == "" {
+		t.Error("Login() should not require MFA once disabled")
+	}
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+	"log"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+)
+
+// WithPasswordParams sets the Argon2id parameters used for new password
+// hashes. Stored hashes made with other parameters, or with bcrypt, are
+// upgraded on the next successful login.
+func WithPasswordParams(params password.Params) Option {
+	return func(s *Service) {
+		s.passwordParams = params
+	}
+}
+
+func (s *Service) hashPassword(pwd string) (string, error) {
+	return password.HashWithParams(pwd, s.passwordParams)
+}
+
+// upgradePasswordHash rehashes a just verified password when its stored
+// hash is outdated. Failures are logged rather than failing the login; the
+// upgrade is retried next time.
+func (s *Service) upgradePasswordHash(ctx context.Context, u *user.User, pwd string) {
+	if !password.NeedsRehash(u.PasswordHash, s.passwordParams) {
+		return
+	}
+
+	hashedPassword, err := s.hashPassword(pwd)
+	if err == nil {
+		err = u.ChangePasswordHash(hashedPassword)
+	}
+	if err == nil {
+		err = s.repo.Update(ctx, u)
+	}
+	if err != nil {
+		log.Printf("user: upgrading password hash for %s: %v", u.ID, err)
+	}
+}
@@ -0,0 +1,78 @@
+package user_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestService_Login_UpgradesLegacyHash(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	service := userUseCase.NewService(repo, jwtService)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	// Simulate an account created before the switch to argon2id
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	stored, _ := repo.FindByID(ctx, u.ID)
+	stored.PasswordHash = string(legacy)
+	repo.Update(ctx, stored)
+
+	// A failed login must not touch the hash
+	service.Login(ctx, "test@example.com", "wrongpassword")
+	if got, _ := repo.FindByID(ctx, u.ID); got.PasswordHash != string(legacy) {
+		t.Fatal("failed Login() should not rehash the password")
+	}
+
+	mustLogin(t, service, "test@example.com", "password123")
+
+	upgraded, _ := repo.FindByID(ctx, u.ID)
+	if !strings.HasPrefix(upgraded.PasswordHash, "$argon2id$") {
+		t.Fatalf("Login() left hash %q, want argon2id", upgraded.PasswordHash)
+	}
+	if !password.Verify("password123", upgraded.PasswordHash) {
+		t.Error("upgraded hash does not verify")
+	}
+
+	// The upgraded hash keeps working
+	mustLogin(t, service, "test@example.com", "password123")
+}
+
+func TestService_Login_UpgradesOutdatedParams(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	ctx := context.Background()
+
+	old := userUseCase.NewService(repo, jwtService)
+	u, _ := old.Register(ctx, "testuser", "test@example.com", "password123")
+	before, _ := repo.FindByID(ctx, u.ID)
+
+	stronger := password.DefaultParams()
+	stronger.Iterations = 3
+	service := userUseCase.NewService(repo, jwtService, userUseCase.WithPasswordParams(stronger))
+
+	mustLogin(t, service, "test@example.com", "password123")
+
+	after, _ := repo.FindByID(ctx, u.ID)
+	if after.PasswordHash == before.PasswordHash {
+		t.Fatal("Login() should rehash a password made with outdated params")
+	}
+	if password.NeedsRehash(after.PasswordHash, stronger) {
+		t.Error("rehashed password should use the configured params")
+	}
+
+	// Logging in again leaves an up to date hash alone
+	mustLogin(t, service, "test@example.com", "password123")
+	if again, _ := repo.FindByID(ctx, u.ID); again.PasswordHash != after.PasswordHash {
+		t.Error("Login() rehashed an up to date password")
+	}
+}
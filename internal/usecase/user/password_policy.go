@@ -0,0 +1,22 @@
+package user
+
+import (
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+)
+
+// WithPasswordPolicy enforces a policy on passwords chosen at registration,
+// reset and change. Failures are returned as *password.PolicyError.
+func WithPasswordPolicy(policy password.Policy) Option {
+	return func(s *Service) {
+		s.passwordPolicy = &policy
+	}
+}
+
+// checkPasswordPolicy validates a new password for an account with the
+// given username and email
+func (s *Service) checkPasswordPolicy(pwd, username, email string) error {
+	if s.passwordPolicy == nil {
+		return nil
+	}
+	return s.passwordPolicy.Check(pwd, username, email)
+}
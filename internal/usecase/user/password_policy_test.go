@@ -0,0 +1,86 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+)
+
+func newPolicyService() (*userUseCase.Service, *captureMailer) {
+	mailer := &captureMailer{}
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithMailer(mailer, "http://localhost:8080"),
+		userUseCase.WithPasswordReset(user.NewInMemoryPasswordResetTokenRepository(), time.Hour),
+		userUseCase.WithPasswordPolicy(password.DefaultPolicy()),
+	)
+	return service, mailer
+}
+
+func assertPolicyViolation(t *testing.T, err error, want password.Rule) {
+	t.Helper()
+
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("error = %v, want a password policy violation", err)
+	}
+	for _, v := range policyErr.Violations {
+		if v.Rule == want {
+			return
+		}
+	}
+	t.Errorf("violations = %v, want rule %q", policyErr.Violations, want)
+}
+
+func TestService_Register_PasswordPolicy(t *testing.T) {
+	service, _ := newPolicyService()
+	ctx := context.Background()
+
+	_, err := service.Register(ctx, "testuser", "test@example.com", "a")
+	assertPolicyViolation(t, err, password.RuleMinLength)
+
+	_, err = service.Register(ctx, "testuser", "test@example.com", "testuser-rocks")
+	assertPolicyViolation(t, err, password.RuleIdentity)
+
+	if _, err := service.Register(ctx, "testuser", "test@example.com", "password123"); err != nil {
+		t.Errorf("Register() unexpected error = %v", err)
+	}
+}
+
+func TestService_ChangePassword_PasswordPolicy(t *testing.T) {
+	service, _ := newPolicyService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	err := service.ChangePassword(ctx, u.ID, "password123", "short")
+	assertPolicyViolation(t, err, password.RuleMinLength)
+
+	// The old password still works
+	mustLogin(t, service, "test@example.com", "password123")
+}
+
+func TestService_ResetPassword_PasswordPolicy(t *testing.T) {
+	service, mailer := newPolicyService()
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	service.RequestPasswordReset(ctx, "test@example.com")
+	token := mailer.lastResetToken(t)
+
+	err := service.ResetPassword(ctx, token, "test@example.com!")
+	assertPolicyViolation(t, err, password.RuleIdentity)
+
+	// A rejected password does not consume the token
+	if err := service.ResetPassword(ctx, token, "newpassword456"); err != nil {
+		t.Errorf("ResetPassword() unexpected error = %v", err)
+	}
+}
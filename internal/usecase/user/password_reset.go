@@ -0,0 +1,139 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/securetoken"
+)
+
+const defaultPasswordResetTTL = 30 * time.Minute
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
+
+// WithPasswordReset enables password reset tokens, stored in repo and valid for ttl
+func WithPasswordReset(repo user.PasswordResetTokenRepository, ttl time.Duration) Option {
+	return func(s *Service) {
+		s.resetRepo = repo
+		s.resetTTL = ttl
+	}
+}
+
+// RequestPasswordReset emails a single-use reset token to the account owner.
+// Earlier tokens of the account are invalidated. Unknown addresses are
+// silently ignored so the endpoint cannot be used to probe for accounts.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	if s.resetRepo == nil {
+		return nil
+	}
+
+	u, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := securetoken.Generate()
+	if err != nil {
+		return err
+	}
+
+	resetToken, err := user.NewPasswordResetToken(u.ID, securetoken.Hash(token), s.resetTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.resetRepo.InvalidateAllForUser(ctx, u.ID); err != nil {
+		return err
+	}
+	if err := s.resetRepo.Create(ctx, resetToken); err != nil {
+		return err
+	}
+
+	if s.mailer == nil {
+		return nil
+	}
+	// Delivery failures are only logged; reporting them would reveal that
+	// the account exists
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. Submit the token below with your new password to %s/api/password/reset:\n\n%s\n\nThe token expires in %s. If you did not ask for this, you can ignore this email.",
+			u.Username, s.baseURL, token, s.resetTTL),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %s: %v", u.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and signs the user
+// out of every existing session
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if s.resetRepo == nil || token == "" {
+		return ErrInvalidResetToken
+	}
+
+	stored, err := s.resetRepo.FindByHash(ctx, securetoken.Hash(token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	now := time.Now()
+	if stored.IsUsed() || stored.IsExpired(now) {
+		return ErrInvalidResetToken
+	}
+
+	u, err := s.repo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.checkPasswordPolicy(newPassword, u.Username, u.Email); err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	// The token is only spent if the password change commits with it
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		// Consume the token first so two concurrent resets cannot both succeed
+		marked, err := s.resetRepo.MarkUsed(ctx, stored.ID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return ErrInvalidResetToken
+		}
+
+		changed, err := s.repo.FindByID(ctx, stored.UserID)
+		if err != nil {
+			return ErrInvalidResetToken
+		}
+		if err := changed.ChangePasswordHash(hashedPassword); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, changed); err != nil {
+			return err
+		}
+
+		if err := s.resetRepo.InvalidateAllForUser(ctx, u.ID); err != nil {
+			return err
+		}
+		s.audit(ctx, audit.Event{Type: audit.EventPasswordReset, ActorID: u.ID, SubjectID: u.ID})
+		return s.endAllSessions(ctx, u.ID)
+	})
+	if err != nil {
+		return err
+	}
+	return s.jwtService.RevokeAllForUser(u.ID)
+}
@@ -0,0 +1,119 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func newPasswordResetService() (*userUseCase.Service, *jwt.Service, *captureMailer) {
+	mailer := &captureMailer{}
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+		userUseCase.WithMailer(mailer, "http://localhost:8080"),
+		userUseCase.WithPasswordReset(user.NewInMemoryPasswordResetTokenRepository(), time.Hour),
+	)
+	return service, jwtService, mailer
+}
+
+// lastResetToken extracts the reset token, which sits on its own line in
+// the reset email
+func (m *captureMailer) lastResetToken(t *testing.T) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) == 0 {
+		t.Fatal("no message was sent")
+	}
+	paragraphs := strings.Split(m.messages[len(m.messages)-1].Body, "\n\n")
+	if len(paragraphs) < 3 {
+		t.Fatalf("unexpected reset email: %q", m.messages[len(m.messages)-1].Body)
+	}
+	return paragraphs[2]
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	service, jwtService, mailer := newPasswordResetService()
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	session := mustLogin(t, service, "test@example.com", "password123")
+
+	if err := service.RequestPasswordReset(ctx, "test@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() unexpected error = %v", err)
+	}
+	token := mailer.lastResetToken(t)
+
+	if err := service.ResetPassword(ctx, token, "newpassword456"); err != nil {
+		t.Fatalf("ResetPassword() unexpected error = %v", err)
+	}
+
+	// The new password works and the old one no longer does
+	if _, err := service.Login(ctx, "test@example.com", "password123"); !errors.Is(err, userUseCase.ErrInvalidCredentials) {
+		t.Errorf("Login() with old password error = %v, want %v", err, userUseCase.ErrInvalidCredentials)
+	}
+	if _, err := service.Login(ctx, "test@example.com", "newpassword456"); err != nil {
+		t.Errorf("Login() with new password unexpected error = %v", err)
+	}
+
+	// Existing sessions are revoked
+	if _, err := jwtService.ValidateToken(session.AccessToken); !errors.Is(err, jwt.ErrRevokedToken) {
+		t.Errorf("ValidateToken() old access token error = %v, want %v", err, jwt.ErrRevokedToken)
+	}
+	if _, err := service.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Error("Refresh() with old refresh token should fail")
+	}
+
+	// The token is single-use
+	if err := service.ResetPassword(ctx, token, "another789"); !errors.Is(err, userUseCase.ErrInvalidResetToken) {
+		t.Errorf("ResetPassword() reused token error = %v, want %v", err, userUseCase.ErrInvalidResetToken)
+	}
+}
+
+func TestService_RequestPasswordReset_InvalidatesEarlierTokens(t *testing.T) {
+	service, _, mailer := newPasswordResetService()
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	service.RequestPasswordReset(ctx, "test@example.com")
+	first := mailer.lastResetToken(t)
+	service.RequestPasswordReset(ctx, "test@example.com")
+
+	if err := service.ResetPassword(ctx, first, "newpassword456"); !errors.Is(err, userUseCase.ErrInvalidResetToken) {
+		t.Errorf("ResetPassword() superseded token error = %v, want %v", err, userUseCase.ErrInvalidResetToken)
+	}
+	if err := service.ResetPassword(ctx, mailer.lastResetToken(t), "newpassword456"); err != nil {
+		t.Errorf("ResetPassword() latest token unexpected error = %v", err)
+	}
+}
+
+func TestService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	service, _, mailer := newPasswordResetService()
+
+	if err := service.RequestPasswordReset(context.Background(), "unknown@example.com"); err != nil {
+		t.Errorf("RequestPasswordReset() error = %v, want nil", err)
+	}
+	if mailer.count() != 0 {
+		t.Errorf("messages sent = %d, want 0", mailer.count())
+	}
+}
+
+func TestService_ResetPassword_InvalidToken(t *testing.T) {
+	service, _, _ := newPasswordResetService()
+
+	err := service.ResetPassword(context.Background(), "bogus", "newpassword456")
+	if !errors.Is(err, userUseCase.ErrInvalidResetToken) {
+		t.Errorf("ResetPassword() error = %v, want %v", err, userUseCase.ErrInvalidResetToken)
+	}
+}
@@ -0,0 +1,122 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+)
+
+var (
+	ErrEmailTaken        = errors.New("email is already in use")
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+)
+
+// ProfileUpdate holds the profile fields to change. Nil fields are left as they are.
+type ProfileUpdate struct {
+	Username *string
+	Email    *string
+	// Version, when set, is the version of the profile the change was made
+	// to; the update fails with user.ErrConcurrentModification if the
+	// profile has changed since
+	Version int64
+}
+
+// GetProfile returns the account of the given user
+func (s *Service) GetProfile(ctx context.Context, userID string) (*user.User, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// UpdateProfile changes the username and/or email of the user. A new email
+// address must be verified again, so a verification email is sent to it.
+func (s *Service) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (*user.User, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if update.Version != 0 && update.Version != u.Version {
+		return nil, user.ErrConcurrentModification
+	}
+
+	if update.Username != nil {
+		if err := u.ChangeUsername(*update.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	emailChanged := update.Email != nil && user.CanonicalEmail(*update.Email) != u.Email
+	if emailChanged {
+		address, err := s.parseEmail(*update.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing, _ := s.repo.FindByEmail(ctx, address.String()); existing != nil {
+			return nil, ErrEmailTaken
+		}
+		if err := u.ChangeEmail(address.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, u); err != nil {
+		if emailChanged && !errors.Is(err, user.ErrConcurrentModification) {
+			// The repository enforces uniqueness too, in case of a concurrent change
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	if emailChanged {
+		s.notifyVerification(ctx, u)
+	}
+	return u, nil
+}
+
+// ChangePassword replaces the password after checking the current one and
+// signs the user out of every session, including the current one
+func (s *Service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if !password.Verify(currentPassword, u.PasswordHash) {
+		return ErrIncorrectPassword
+	}
+
+	if err := s.checkPasswordPolicy(newPassword, u.Username, u.Email); err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		changed, err := s.repo.FindByID(ctx, userID)
+		if err != nil {
+			return ErrUserNotFound
+		}
+		// The password checked above must still be the current one
+		if changed.PasswordHash != u.PasswordHash && !password.Verify(currentPassword, changed.PasswordHash) {
+			return ErrIncorrectPassword
+		}
+		if err := changed.ChangePasswordHash(hashedPassword); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, changed); err != nil {
+			return err
+		}
+		s.audit(ctx, audit.Event{Type: audit.EventPasswordChanged, ActorID: u.ID, SubjectID: u.ID})
+		return s.endAllSessions(ctx, u.ID)
+	})
+	if err != nil {
+		return err
+	}
+	return s.jwtService.RevokeAllForUser(u.ID)
+}
@@ -0,0 +1,128 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func TestService_UpdateProfile(t *testing.T) {
+	service, repo, mailer := newVerificationService(false)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	service.VerifyEmail(ctx, mailer.lastToken(t))
+	oldLink := mailer.lastToken(t)
+
+	username, email := "renamed", "new@example.com"
+	updated, err := service.UpdateProfile(ctx, u.ID, userUseCase.ProfileUpdate{Username: &username, Email: &email})
+	if err != nil {
+		t.Fatalf("UpdateProfile() unexpected error = %v", err)
+	}
+	if updated.Username != username || updated.Email != email || updated.EmailVerified {
+		t.Errorf("UpdateProfile() = %+v, want renamed, new email and unverified", updated)
+	}
+	if !updated.UpdatedAt.After(u.UpdatedAt) {
+		t.Error("UpdateProfile() should bump UpdatedAt")
+	}
+
+	// A verification email goes to the new address, and old links stop working
+	if mailer.count() != 2 {
+		t.Fatalf("messages sent = %d, want 2", mailer.count())
+	}
+	if err := service.VerifyEmail(ctx, oldLink); !errors.Is(err, userUseCase.ErrInvalidVerificationToken) {
+		t.Errorf("VerifyEmail() old link error = %v, want %v", err, userUseCase.ErrInvalidVerificationToken)
+	}
+	if err := service.VerifyEmail(ctx, mailer.lastToken(t)); err != nil {
+		t.Errorf("VerifyEmail() new link unexpected error = %v", err)
+	}
+
+	stored, _ := repo.FindByEmail(ctx, email)
+	if stored == nil || !stored.EmailVerified {
+		t.Error("new email should be stored and verified")
+	}
+}
+
+func TestService_UpdateProfile_EmailTaken(t *testing.T) {
+	service, repo, _ := newVerificationService(false)
+	ctx := context.Background()
+
+	service.Register(ctx, "other", "other@example.com", "password123")
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	email := "other@example.com"
+	_, err := service.UpdateProfile(ctx, u.ID, userUseCase.ProfileUpdate{Email: &email})
+	if !errors.Is(err, userUseCase.ErrEmailTaken) {
+		t.Fatalf("UpdateProfile() error = %v, want %v", err, userUseCase.ErrEmailTaken)
+	}
+
+	stored, _ := repo.FindByID(ctx, u.ID)
+	if stored.Email != "test@example.com" {
+		t.Errorf("email = %v, want it unchanged", stored.Email)
+	}
+}
+
+func TestService_UpdateProfile_Version(t *testing.T) {
+	service, _, _ := newVerificationService(false)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	read, _ := service.GetProfile(ctx, u.ID)
+
+	first := "first"
+	updated, err := service.UpdateProfile(ctx, u.ID, userUseCase.ProfileUpdate{Username: &first, Version: read.Version})
+	if err != nil {
+		t.Fatalf("UpdateProfile() unexpected error = %v", err)
+	}
+	if updated.Version != read.Version+1 {
+		t.Errorf("UpdateProfile() Version = %d, want %d", updated.Version, read.Version+1)
+	}
+
+	// A second edit made to the same version would overwrite the first
+	second := "second"
+	_, err = service.UpdateProfile(ctx, u.ID, userUseCase.ProfileUpdate{Username: &second, Version: read.Version})
+	if !errors.Is(err, domainUser.ErrConcurrentModification) {
+		t.Fatalf("UpdateProfile() from a stale version error = %v, want %v", err, domainUser.ErrConcurrentModification)
+	}
+	if stored, _ := service.GetProfile(ctx, u.ID); stored.Username != first {
+		t.Errorf("username = %v, want %v", stored.Username, first)
+	}
+
+	// Without a version the latest one is updated
+	if _, err := service.UpdateProfile(ctx, u.ID, userUseCase.ProfileUpdate{Username: &second}); err != nil {
+		t.Errorf("UpdateProfile() without a version unexpected error = %v", err)
+	}
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	session := mustLogin(t, service, "test@example.com", "password123")
+
+	err := service.ChangePassword(ctx, u.ID, "wrongpassword", "newpassword456")
+	if !errors.Is(err, userUseCase.ErrIncorrectPassword) {
+		t.Fatalf("ChangePassword() error = %v, want %v", err, userUseCase.ErrIncorrectPassword)
+	}
+
+	if err := service.ChangePassword(ctx, u.ID, "password123", "newpassword456"); err != nil {
+		t.Fatalf("ChangePassword() unexpected error = %v", err)
+	}
+
+	if _, err := service.Login(ctx, "test@example.com", "newpassword456"); err != nil {
+		t.Errorf("Login() with new password unexpected error = %v", err)
+	}
+	if _, err := jwtService.ValidateToken(session.AccessToken); !errors.Is(err, jwt.ErrRevokedToken) {
+		t.Errorf("ValidateToken() old access token error = %v, want %v", err, jwt.ErrRevokedToken)
+	}
+}
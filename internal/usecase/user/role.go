@@ -0,0 +1,55 @@
+package user
+
+import (
+	"context"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+// WithAdminEmails gives the admin role to accounts registered with one of
+// the given addresses, bootstrapping the first administrators
+func WithAdminEmails(emails []string) Option {
+	return func(s *Service) {
+		s.adminEmails = make(map[string]bool, len(emails))
+		for _, email := range emails {
+			s.adminEmails[user.CanonicalEmail(email)] = true
+		}
+	}
+}
+
+// SetRole changes the role of a user. Access tokens carrying the old role
+// are revoked; refresh tokens keep working and pick up the new role.
+func (s *Service) SetRole(ctx context.Context, userID string, role user.Role) error {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if u.Role == role {
+		return nil
+	}
+	previous := u.Role
+	if err := u.AssignRole(role); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, u); err != nil {
+		return err
+	}
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventRoleChanged,
+		SubjectID: u.ID,
+		Details:   map[string]string{"role": string(role), "previous_role": string(previous)},
+	})
+
+	return s.jwtService.RevokeAllForUser(u.ID)
+}
+
+// bootstrapRole promotes the user to admin if their address is listed in
+// WithAdminEmails. It reports whether the role changed.
+func (s *Service) bootstrapRole(u *user.User) bool {
+	if u.Role == user.RoleAdmin || !s.adminEmails[user.CanonicalEmail(u.Email)] {
+		return false
+	}
+	return u.AssignRole(user.RoleAdmin) == nil
+}
@@ -0,0 +1,60 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainUser "github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func TestService_Register_AdminBootstrap(t *testing.T) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithAdminEmails([]string{" Admin@Example.com "}),
+	)
+	ctx := context.Background()
+
+	admin, _ := service.Register(ctx, "admin", "admin@example.com", "password123")
+	merchant, _ := service.Register(ctx, "merchant", "merchant@example.com", "password123")
+
+	if admin.Role != domainUser.RoleAdmin {
+		t.Errorf("listed address role = %v, want %v", admin.Role, domainUser.RoleAdmin)
+	}
+	if merchant.Role != domainUser.DefaultRole {
+		t.Errorf("other address role = %v, want %v", merchant.Role, domainUser.DefaultRole)
+	}
+
+	session := mustLogin(t, service, "admin@example.com", "password123")
+	claims, _ := jwtService.ValidateToken(session.AccessToken)
+	if claims.Role != string(domainUser.RoleAdmin) {
+		t.Errorf("role claim = %v, want %v", claims.Role, domainUser.RoleAdmin)
+	}
+}
+
+func TestService_SetRole(t *testing.T) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	session := mustLogin(t, service, "test@example.com", "password123")
+
+	if err := service.SetRole(ctx, "missing", domainUser.RoleSupport); !errors.Is(err, userUseCase.ErrUserNotFound) {
+		t.Errorf("SetRole() unknown user error = %v, want %v", err, userUseCase.ErrUserNotFound)
+	}
+	if err := service.SetRole(ctx, u.ID, domainUser.RoleSupport); err != nil {
+		t.Fatalf("SetRole() unexpected error = %v", err)
+	}
+
+	// Tokens carrying the old role stop working
+	if _, err := jwtService.ValidateToken(session.AccessToken); !errors.Is(err, jwt.ErrRevokedToken) {
+		t.Errorf("ValidateToken() old access token error = %v, want %v", err, jwt.ErrRevokedToken)
+	}
+}
@@ -3,46 +3,150 @@ package user
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
 	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
 	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
 	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUserNotFound       = errors.New("user not found")
 )
 
 // UseCase defines the interface for user business logic
 type UseCase interface {
 	Register(ctx context.Context, username, email, pwd string) (*user.User, error)
-	Login(ctx context.Context, email, pwd string) (string, error)
+	Login(ctx context.Context, email, pwd string) (*LoginResult, error)
+	VerifyMFA(ctx context.Context, mfaToken, code string) (*Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error
+	LogoutAll(ctx context.Context, userID string) error
+	EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID, code string) error
+	SetMFARequired(ctx context.Context, userID string, required bool) error
+	SetRole(ctx context.Context, userID string, role user.Role) error
+	Impersonate(ctx context.Context, actor *jwt.Claims, targetID, reason string) (*Impersonation, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	RequestMagicLink(ctx context.Context, email string) (string, error)
+	ConfirmMagicLink(ctx context.Context, token, nonce string) (*LoginResult, error)
+	BeginWebAuthnRegistration(ctx context.Context, userID string) (*WebAuthnRegistration, error)
+	FinishWebAuthnRegistration(ctx context.Context, userID, ceremonyToken, name string, resp *webauthn.RegistrationResponse) (*user.WebAuthnCredential, error)
+	ListWebAuthnCredentials(ctx context.Context, userID string) ([]*user.WebAuthnCredential, error)
+	DeleteWebAuthnCredential(ctx context.Context, userID, credentialID string) error
+	BeginWebAuthnLogin(ctx context.Context, mfaToken string) (*WebAuthnLogin, error)
+	FinishWebAuthnLogin(ctx context.Context, ceremonyToken string, resp *webauthn.AssertionResponse) (*LoginResult, error)
+	GetProfile(ctx context.Context, userID string) (*user.User, error)
+	UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (*user.User, error)
+	ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error
+	ListSessions(ctx context.Context, userID string) ([]*user.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	DeleteAccount(ctx context.Context, userID, pwd string) (time.Time, error)
 }
 
 // Service implements UseCase interface
 type Service struct {
-	repo       user.Repository
-	jwtService *jwt.Service
+	repo        user.Repository
+	jwtService  *jwt.Service
+	refreshRepo user.RefreshTokenRepository
+	refreshTTL  time.Duration
+	totpIssuer  string
+	mfaTTL      time.Duration
+
+	mailer               mail.Mailer
+	baseURL              string
+	verificationTTL      time.Duration
+	requireVerifiedLogin bool
+
+	resetRepo user.PasswordResetTokenRepository
+	resetTTL  time.Duration
+
+	magicLinkRepo user.MagicLinkRepository
+	magicLinkTTL  time.Duration
+
+	webAuthn     *webauthn.RelyingParty
+	webAuthnRepo user.WebAuthnCredentialRepository
+
+	impersonationTTL time.Duration
+
+	sessionRepo        user.SessionRepository
+	sessionIdleTimeout time.Duration
+
+	accountThrottle *lockout.Tracker
+	ipThrottle      *lockout.Tracker
+
+	adminEmails    map[string]bool
+	emailBlocklist *user.DomainBlocklist
+
+	auditLog audit.Recorder
+
+	deletionGracePeriod time.Duration
+	accessRevokers      []AccessRevoker
+
+	passwordParams password.Params
+	passwordPolicy *password.Policy
+
+	txManager transaction.Manager
+}
+
+// Option configures optional collaborators of the Service
+type Option func(*Service)
+
+// WithRefreshTokens enables issuing rotating refresh tokens alongside access tokens
+func WithRefreshTokens(repo user.RefreshTokenRepository, ttl time.Duration) Option {
+	return func(s *Service) {
+		s.refreshRepo = repo
+		s.refreshTTL = ttl
+	}
 }
 
 // NewService creates a new user service
-func NewService(repo user.Repository, jwtService *jwt.Service) *Service {
-	return &Service{
-		repo:       repo,
-		jwtService: jwtService,
+func NewService(repo user.Repository, jwtService *jwt.Service, opts ...Option) *Service {
+	s := &Service{
+		repo:             repo,
+		jwtService:       jwtService,
+		totpIssuer:       defaultTOTPIssuer,
+		mfaTTL:           defaultMFAChallengeTTL,
+		verificationTTL:  defaultEmailVerificationTTL,
+		resetTTL:         defaultPasswordResetTTL,
+		magicLinkTTL:     defaultMagicLinkTTL,
+		impersonationTTL: defaultImpersonationTTL,
+		passwordParams:   password.DefaultParams(),
+
+		deletionGracePeriod: defaultDeletionGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// Register creates a new user account
+// Register creates a new user account. The check for an existing account
+// and the insert run in one transaction.
 func (s *Service) Register(ctx context.Context, username, email, pwd string) (*user.User, error) {
-	// Check if user already exists
-	existingUser, _ := s.repo.FindByEmail(ctx, email)
-	if existingUser != nil {
-		return nil, errors.New("user with this email already exists")
+	address, err := s.parseEmail(email)
+	if err != nil {
+		return nil, err
 	}
+	email = address.String()
 
-	// Hash password
-	hashedPassword, err := password.Hash(pwd)
+	if err := s.checkPasswordPolicy(pwd, username, email); err != nil {
+		return nil, err
+	}
+
+	// Hash password before taking the transaction, which it would hold for
+	// the duration of the hash
+	hashedPassword, err := s.hashPassword(pwd)
 	if err != nil {
 		return nil, err
 	}
@@ -52,33 +156,101 @@ func (s *Service) Register(ctx context.Context, username, email, pwd string) (*u
 	if err != nil {
 		return nil, err
 	}
+	s.bootstrapRole(newUser)
+
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		// Check if user already exists
+		existingUser, _ := s.repo.FindByEmail(ctx, email)
+		if existingUser != nil {
+			return errors.New("user with this email already exists")
+		}
 
-	// Save to repository
-	if err := s.repo.Create(ctx, newUser); err != nil {
+		// Save to repository
+		return s.repo.Create(ctx, newUser)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventUserRegistered,
+		ActorID:   newUser.ID,
+		SubjectID: newUser.ID,
+		Details:   map[string]string{"email": newUser.Email, "role": string(newUser.Role)},
+	})
+	s.notifyVerification(ctx, newUser)
+
 	return newUser, nil
 }
 
-// Login authenticates a user and returns a JWT token
-func (s *Service) Login(ctx context.Context, email, pwd string) (string, error) {
+// Login authenticates a user and returns an access token and, when
+// enabled, a refresh token starting a new token family. Accounts with a
+// second factor get an MFA token to complete the login with instead.
+// Repeated failures are throttled per account and client IP, returning a
+// *lockout.LockedError.
+func (s *Service) Login(ctx context.Context, email, pwd string) (*LoginResult, error) {
+	if err := s.checkLoginThrottle(ctx, email); err != nil {
+		s.auditLoginFailure(ctx, email, "", audit.ReasonLockedOut)
+		return nil, err
+	}
+
 	// Find user by email
 	u, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
-		return "", ErrInvalidCredentials
+		s.auditLoginFailure(ctx, email, "", audit.ReasonUnknownAccount)
+		s.recordLoginFailure(ctx, email)
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if !password.Verify(pwd, u.PasswordHash) {
-		return "", ErrInvalidCredentials
+		s.auditLoginFailure(ctx, email, u.ID, audit.ReasonInvalidPassword)
+		s.recordLoginFailure(ctx, email)
+		return nil, ErrInvalidCredentials
+	}
+	s.upgradePasswordHash(ctx, u, pwd)
+
+	return s.completeLogin(ctx, u, "password", false)
+}
+
+// completeLogin applies the checks that follow a successful first factor,
+// then either starts the session or, for accounts with a second factor,
+// returns an MFA token to continue with. method names the first factor;
+// multiFactor is set when it already proved more than one factor.
+func (s *Service) completeLogin(ctx context.Context, u *user.User, method string, multiFactor bool) (*LoginResult, error) {
+	if s.requireVerifiedLogin && !u.EmailVerified {
+		s.auditLoginFailure(ctx, u.Email, u.ID, audit.ReasonEmailNotVerified)
+		return nil, ErrEmailNotVerified
 	}
 
-	// Generate JWT token
-	token, err := s.jwtService.GenerateToken(u.ID, u.Email)
+	// Accounts that existed before being listed in ADMIN_EMAILS are promoted
+	// on their next login
+	if s.bootstrapRole(u) {
+		if err := s.repo.Update(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+
+	hasPasskeys, err := s.hasPasskeys(ctx, u.ID)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if !multiFactor && (u.MFAEnabled() || hasPasskeys) {
+		mfaToken, err := s.jwtService.GeneratePurposeToken(u.ID, u.Email, mfaTokenPurpose, s.mfaTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFAToken: mfaToken}, nil
+	}
+	if u.MFARequired && !multiFactor && !hasPasskeys {
+		s.auditLoginFailure(ctx, u.Email, u.ID, audit.ReasonMFANotEnrolled)
+		return nil, ErrMFARequired
 	}
 
-	return token, nil
+	tokens, err := s.startLogin(ctx, u, method)
+	if err != nil {
+		return nil, err
+	}
+	s.resetLoginFailures(ctx, u.Email)
+	return &LoginResult{Tokens: tokens}, nil
 }
@@ -12,7 +12,7 @@ import (
 
 func TestService_Register(t *testing.T) {
 	repo := user.NewInMemoryRepository()
-	jwtService := jwt.NewService("test-secret", 24*time.Hour)
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
 	service := userUseCase.NewService(repo, jwtService)
 
 	ctx := context.Background()
@@ -37,7 +37,7 @@ func TestService_Register(t *testing.T) {
 
 func TestService_Register_DuplicateEmail(t *testing.T) {
 	repo := user.NewInMemoryRepository()
-	jwtService := jwt.NewService("test-secret", 24*time.Hour)
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
 	service := userUseCase.NewService(repo, jwtService)
 
 	ctx := context.Background()
@@ -52,7 +52,7 @@ func TestService_Register_DuplicateEmail(t *testing.T) {
 
 func TestService_Login_Success(t *testing.T) {
 	repo := user.NewInMemoryRepository()
-	jwtService := jwt.NewService("test-secret", 24*time.Hour)
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
 	service := userUseCase.NewService(repo, jwtService)
 
 	ctx := context.Background()
@@ -61,19 +61,24 @@ func TestService_Login_Success(t *testing.T) {
 	_, _ = service.Register(ctx, "testuser", "test@example.com", "password123")
 
 	// Try to login
-	token, err := service.Login(ctx, "test@example.com", "password123")
+	result, err := service.Login(ctx, "test@example.com", "password123")
 	if err != nil {
-		t.Errorf("Login() unexpected error = %v", err)
+		t.Fatalf("Login() unexpected error = %v", err)
 	}
+	tokens := result.Tokens
 
-	if token == "" {
+	if tokens.AccessToken == "" {
 		t.Error("Login() should return a token")
 	}
+
+	if tokens.RefreshToken != "" {
+		t.Error("Login() should not return a refresh token when refresh tokens are disabled")
+	}
 }
 
 func TestService_Login_InvalidEmail(t *testing.T) {
 	repo := user.NewInMemoryRepository()
-	jwtService := jwt.NewService("test-secret", 24*time.Hour)
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
 	service := userUseCase.NewService(repo, jwtService)
 
 	ctx := context.Background()
@@ -87,7 +92,7 @@ func TestService_Login_InvalidEmail(t *testing.T) {
 
 func TestService_Login_InvalidPassword(t *testing.T) {
 	repo := user.NewInMemoryRepository()
-	jwtService := jwt.NewService("test-secret", 24*time.Hour)
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
 	service := userUseCase.NewService(repo, jwtService)
 
 	ctx := context.Background()
@@ -102,3 +107,145 @@ func TestService_Login_InvalidPassword(t *testing.T) {
 		t.Errorf("Login() expected ErrInvalidCredentials, got %v", err)
 	}
 }
+
+func newServiceWithRefreshTokens() *userUseCase.Service {
+	repo := user.NewInMemoryRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	return userUseCase.NewService(repo, jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+	)
+}
+
+func TestService_Login_ReturnsRefreshToken(t *testing.T) {
+	service := newServiceWithRefreshTokens()
+	ctx := context.Background()
+
+	_, _ = service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	result, err := service.Login(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+	tokens := result.Tokens
+
+	if tokens.RefreshToken == "" {
+		t.Error("Login() should return a refresh token")
+	}
+
+	if tokens.ExpiresIn != 15*time.Minute {
+		t.Errorf("Login() ExpiresIn = %v, want %v", tokens.ExpiresIn, 15*time.Minute)
+	}
+}
+
+func TestService_Refresh_RotatesToken(t *testing.T) {
+	service := newServiceWithRefreshTokens()
+	ctx := context.Background()
+
+	_, _ = service.Register(ctx, "testuser", "test@example.com", "password123")
+	tokens := mustLogin(t, service, "test@example.com", "password123")
+
+	refreshed, err := service.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() unexpected error = %v", err)
+	}
+
+	if refreshed.AccessToken == "" {
+		t.Error("Refresh() should return an access token")
+	}
+
+	if refreshed.RefreshToken == "" || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Error("Refresh() should rotate the refresh token")
+	}
+
+	// The rotated token can be used in turn
+	if _, err := service.Refresh(ctx, refreshed.RefreshToken); err != nil {
+		t.Errorf("Refresh() with rotated token unexpected error = %v", err)
+	}
+}
+
+func TestService_Refresh_ReuseRevokesFamily(t *testing.T) {
+	service := newServiceWithRefreshTokens()
+	ctx := context.Background()
+
+	_, _ = service.Register(ctx, "testuser", "test@example.com", "password123")
+	tokens := mustLogin(t, service, "test@example.com", "password123")
+
+	rotated, _ := service.Refresh(ctx, tokens.RefreshToken)
+
+	// Replaying the original token must be detected
+	_, err := service.Refresh(ctx, tokens.RefreshToken)
+	if err != userUseCase.ErrRefreshTokenReused {
+		t.Fatalf("Refresh() expected ErrRefreshTokenReused, got %v", err)
+	}
+
+	// ...and must revoke the legitimate descendant as well
+	_, err = service.Refresh(ctx, rotated.RefreshToken)
+	if err != userUseCase.ErrInvalidRefreshToken {
+		t.Errorf("Refresh() expected ErrInvalidRefreshToken after reuse, got %v", err)
+	}
+}
+
+func TestService_Refresh_InvalidToken(t *testing.T) {
+	service := newServiceWithRefreshTokens()
+
+	_, err := service.Refresh(context.Background(), "not-a-real-token")
+
+	if err != userUseCase.ErrInvalidRefreshToken {
+		t.Errorf("Refresh() expected ErrInvalidRefreshToken, got %v", err)
+	}
+}
+
+func TestService_Logout_RevokesAccessAndRefreshTokens(t *testing.T) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+	)
+	ctx := context.Background()
+
+	_, _ = service.Register(ctx, "testuser", "test@example.com", "password123")
+	tokens := mustLogin(t, service, "test@example.com", "password123")
+	claims, _ := jwtService.ValidateToken(tokens.AccessToken)
+
+	if err := service.Logout(ctx, claims, tokens.RefreshToken); err != nil {
+		t.Fatalf("Logout() unexpected error = %v", err)
+	}
+
+	if _, err := jwtService.ValidateToken(tokens.AccessToken); err != jwt.ErrRevokedToken {
+		t.Errorf("ValidateToken() after Logout() expected ErrRevokedToken, got %v", err)
+	}
+
+	if _, err := service.Refresh(ctx, tokens.RefreshToken); err != userUseCase.ErrInvalidRefreshToken {
+		t.Errorf("Refresh() after Logout() expected ErrInvalidRefreshToken, got %v", err)
+	}
+}
+
+func TestService_LogoutAll_RevokesEveryRefreshToken(t *testing.T) {
+	service := newServiceWithRefreshTokens()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	first := mustLogin(t, service, "test@example.com", "password123")
+	second := mustLogin(t, service, "test@example.com", "password123")
+
+	if err := service.LogoutAll(ctx, u.ID); err != nil {
+		t.Fatalf("LogoutAll() unexpected error = %v", err)
+	}
+
+	for _, tokens := range []*userUseCase.Tokens{first, second} {
+		if _, err := service.Refresh(ctx, tokens.RefreshToken); err != userUseCase.ErrInvalidRefreshToken {
+			t.Errorf("Refresh() after LogoutAll() expected ErrInvalidRefreshToken, got %v", err)
+		}
+	}
+}
+
+func mustLogin(t *testing.T, service *userUseCase.Service, email, pwd string) *userUseCase.Tokens {
+	t.Helper()
+
+	result, err := service.Login(context.Background(), email, pwd)
+	if err != nil || result.Tokens == nil {
+		t.Fatalf("Login() = %+v, %v; want tokens", result, err)
+	}
+	return result.Tokens
+}
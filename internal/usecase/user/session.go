@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionEnded    = errors.New("session has been signed out or expired")
+)
+
+// sessionTouchInterval limits how often requests update LastSeenAt
+const sessionTouchInterval = time.Minute
+
+// WithSessions records a session for every login so users can list their
+// signed-in devices and sign them out remotely. Sessions unused for longer
+// than idleTimeout end.
+func WithSessions(repo user.SessionRepository, idleTimeout time.Duration) Option {
+	return func(s *Service) {
+		s.sessionRepo = repo
+		s.sessionIdleTimeout = idleTimeout
+	}
+}
+
+// startSession records a new session for a successful login, returning its
+// ID or "" when sessions are disabled
+func (s *Service) startSession(ctx context.Context, u *user.User, familyID string) (string, error) {
+	if s.sessionRepo == nil {
+		return "", nil
+	}
+
+	meta := requestmeta.FromContext(ctx)
+	session, err := user.NewSession(u.ID, familyID, meta.IP, meta.UserAgent)
+	if err != nil {
+		return "", err
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+// refreshSession returns the ID of the session a refresh token family
+// belongs to, recording the activity, or "" when sessions are disabled.
+// Once sessions are enabled every family must have one: a family whose
+// session was purged must not go on minting tokens outside of any session.
+func (s *Service) refreshSession(ctx context.Context, familyID string) (string, error) {
+	if s.sessionRepo == nil {
+		return "", nil
+	}
+
+	session, err := s.sessionRepo.FindByFamily(ctx, familyID)
+	if err != nil {
+		return "", ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if !session.IsActive(now, s.sessionIdleTimeout) {
+		return "", ErrInvalidRefreshToken
+	}
+	if err := s.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+// ValidateSession reports whether an access token issued for sessionID may
+// still be used, recording the activity. It returns ErrSessionEnded for
+// sessions that were signed out or have been idle for too long.
+func (s *Service) ValidateSession(ctx context.Context, userID, sessionID string) error {
+	if s.sessionRepo == nil {
+		return nil
+	}
+
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil || session.UserID != userID {
+		return ErrSessionEnded
+	}
+
+	now := time.Now()
+	if !session.IsActive(now, s.sessionIdleTimeout) {
+		return ErrSessionEnded
+	}
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+			log.Printf("Failed to record session activity: %v", err)
+		}
+	}
+	return nil
+}
+
+// ListSessions returns the active sessions of the user, most recently used first
+func (s *Service) ListSessions(ctx context.Context, userID string) ([]*user.Session, error) {
+	if s.sessionRepo == nil {
+		return nil, nil
+	}
+
+	sessions, err := s.sessionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := sessions[:0]
+	for _, session := range sessions {
+		if session.IsActive(now, s.sessionIdleTimeout) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSession signs out one of the user's sessions: its access tokens
+// stop validating and its refresh tokens are revoked
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if s.sessionRepo == nil {
+		return ErrSessionNotFound
+	}
+
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive(time.Now(), s.sessionIdleTimeout) {
+		return ErrSessionNotFound
+	}
+
+	if err := s.sessionRepo.Revoke(ctx, session.ID, time.Now()); err != nil {
+		return err
+	}
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventSessionRevoked,
+		SubjectID: userID,
+		Details:   map[string]string{"session_id": session.ID},
+	})
+	if s.refreshRepo == nil {
+		return nil
+	}
+	return s.refreshRepo.RevokeFamily(ctx, session.FamilyID)
+}
+
+// PurgeSessions deletes sessions that have been idle, or signed out, for
+// longer than the idle timeout and returns how many were removed
+func (s *Service) PurgeSessions(ctx context.Context) (int, error) {
+	if s.sessionRepo == nil || s.sessionIdleTimeout <= 0 {
+		return 0, nil
+	}
+	return s.sessionRepo.DeleteStale(ctx, time.Now().Add(-s.sessionIdleTimeout))
+}
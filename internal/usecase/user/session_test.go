@@ -0,0 +1,174 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func newSessionService(idleTimeout time.Duration) (*userUseCase.Service, *jwt.Service) {
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+		userUseCase.WithSessions(user.NewInMemorySessionRepository(), idleTimeout),
+	)
+	return service, jwtService
+}
+
+func TestService_Login_StartsSession(t *testing.T) {
+	service, jwtService := newSessionService(time.Hour)
+	ctx := requestmeta.NewContext(context.Background(), requestmeta.Meta{IP: "192.0.2.1", UserAgent: "Firefox"})
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	result, err := service.Login(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+
+	claims, _ := jwtService.ValidateToken(result.Tokens.AccessToken)
+	if claims.SessionID == "" {
+		t.Fatal("access token should carry the sid claim")
+	}
+
+	sessions, _ := service.ListSessions(ctx, u.ID)
+	if len(sessions) != 1 {
+		t.Fatalf("ListSessions() returned %d sessions, want 1", len(sessions))
+	}
+	s := sessions[0]
+	if s.ID != claims.SessionID || s.IP != "192.0.2.1" || s.UserAgent != "Firefox" {
+		t.Errorf("session = %+v, want sid %s from 192.0.2.1 with Firefox", s, claims.SessionID)
+	}
+
+	// Refreshing keeps the session
+	refreshed, err := service.Refresh(ctx, result.Tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() unexpected error = %v", err)
+	}
+	refreshedClaims, _ := jwtService.ValidateToken(refreshed.AccessToken)
+	if refreshedClaims.SessionID != claims.SessionID {
+		t.Errorf("Refresh() sid = %s, want %s", refreshedClaims.SessionID, claims.SessionID)
+	}
+}
+
+func TestService_RevokeSession(t *testing.T) {
+	service, jwtService := newSessionService(time.Hour)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	laptop := mustLogin(t, service, "test@example.com", "password123")
+	phone := mustLogin(t, service, "test@example.com", "password123")
+
+	phoneClaims, _ := jwtService.ValidateToken(phone.AccessToken)
+	laptopClaims, _ := jwtService.ValidateToken(laptop.AccessToken)
+
+	if err := service.RevokeSession(ctx, "someone-else", phoneClaims.SessionID); !errors.Is(err, userUseCase.ErrSessionNotFound) {
+		t.Fatalf("RevokeSession() foreign user error = %v, want %v", err, userUseCase.ErrSessionNotFound)
+	}
+	if err := service.RevokeSession(ctx, u.ID, phoneClaims.SessionID); err != nil {
+		t.Fatalf("RevokeSession() unexpected error = %v", err)
+	}
+
+	if err := service.ValidateSession(ctx, u.ID, phoneClaims.SessionID); !errors.Is(err, userUseCase.ErrSessionEnded) {
+		t.Errorf("ValidateSession() error = %v, want %v", err, userUseCase.ErrSessionEnded)
+	}
+	if _, err := service.Refresh(ctx, phone.RefreshToken); err == nil {
+		t.Error("Refresh() should fail for a signed out session")
+	}
+
+	// The other device is unaffected
+	if err := service.ValidateSession(ctx, u.ID, laptopClaims.SessionID); err != nil {
+		t.Errorf("ValidateSession() unexpected error = %v", err)
+	}
+	if sessions, _ := service.ListSessions(ctx, u.ID); len(sessions) != 1 {
+		t.Errorf("ListSessions() returned %d sessions, want 1", len(sessions))
+	}
+
+	// Revoking twice reports the session as gone
+	if err := service.RevokeSession(ctx, u.ID, phoneClaims.SessionID); !errors.Is(err, userUseCase.ErrSessionNotFound) {
+		t.Errorf("RevokeSession() second call error = %v, want %v", err, userUseCase.ErrSessionNotFound)
+	}
+}
+
+func TestService_Session_IdleTimeout(t *testing.T) {
+	service, jwtService := newSessionService(20 * time.Millisecond)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	tokens := mustLogin(t, service, "test@example.com", "password123")
+	claims, _ := jwtService.ValidateToken(tokens.AccessToken)
+
+	time.Sleep(30 * time.Millisecond)
+
+	if err := service.ValidateSession(ctx, u.ID, claims.SessionID); !errors.Is(err, userUseCase.ErrSessionEnded) {
+		t.Errorf("ValidateSession() error = %v, want %v", err, userUseCase.ErrSessionEnded)
+	}
+	if _, err := service.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, userUseCase.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() error = %v, want %v", err, userUseCase.ErrInvalidRefreshToken)
+	}
+
+	purged, err := service.PurgeSessions(ctx)
+	if err != nil || purged != 1 {
+		t.Errorf("PurgeSessions() = %d, %v, want 1", purged, err)
+	}
+}
+
+func TestService_Refresh_PurgedSession(t *testing.T) {
+	service, _ := newSessionService(20 * time.Millisecond)
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	tokens := mustLogin(t, service, "test@example.com", "password123")
+
+	time.Sleep(30 * time.Millisecond)
+	if purged, err := service.PurgeSessions(ctx); err != nil || purged != 1 {
+		t.Fatalf("PurgeSessions() = %d, %v, want 1", purged, err)
+	}
+
+	if _, err := service.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, userUseCase.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() after the session was purged error = %v, want %v", err, userUseCase.ErrInvalidRefreshToken)
+	}
+}
+
+func TestService_LogoutAll_EndsSessions(t *testing.T) {
+	service, jwtService := newSessionService(time.Hour)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	tokens := mustLogin(t, service, "test@example.com", "password123")
+	claims, _ := jwtService.ValidateToken(tokens.AccessToken)
+
+	if err := service.LogoutAll(ctx, u.ID); err != nil {
+		t.Fatalf("LogoutAll() unexpected error = %v", err)
+	}
+	if err := service.ValidateSession(ctx, u.ID, claims.SessionID); !errors.Is(err, userUseCase.ErrSessionEnded) {
+		t.Errorf("ValidateSession() error = %v, want %v", err, userUseCase.ErrSessionEnded)
+	}
+}
+
+func TestService_LogoutAll_LoginAgain(t *testing.T) {
+	service, jwtService := newSessionService(time.Hour)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	old := mustLogin(t, service, "test@example.com", "password123")
+	if err := service.LogoutAll(ctx, u.ID); err != nil {
+		t.Fatalf("LogoutAll() unexpected error = %v", err)
+	}
+
+	// A login right after, likely within the same second, is not revoked
+	fresh := mustLogin(t, service, "test@example.com", "password123")
+	if _, err := jwtService.ValidateToken(fresh.AccessToken); err != nil {
+		t.Errorf("ValidateToken() of the new access token error = %v", err)
+	}
+	if _, err := jwtService.ValidateToken(old.AccessToken); err != jwt.ErrRevokedToken {
+		t.Errorf("ValidateToken() of the old access token error = %v, want %v", err, jwt.ErrRevokedToken)
+	}
+}
@@ -0,0 +1,83 @@
+package user
+
+import (
+	"context"
+	"log"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+)
+
+// WithLoginThrottle limits failed login attempts per account and per client
+// IP. Either tracker may be nil to disable that dimension.
+func WithLoginThrottle(accounts, ips *lockout.Tracker) Option {
+	return func(s *Service) {
+		s.accountThrottle = accounts
+		s.ipThrottle = ips
+	}
+}
+
+// checkLoginThrottle returns a *lockout.LockedError if the account or the
+// client IP must wait before trying again
+func (s *Service) checkLoginThrottle(ctx context.Context, email string) error {
+	if s.accountThrottle != nil {
+		if err := s.accountThrottle.Check(ctx, accountThrottleKey(email)); err != nil {
+			return err
+		}
+	}
+	if ip := requestmeta.FromContext(ctx).IP; s.ipThrottle != nil && ip != "" {
+		if err := s.ipThrottle.Check(ctx, ipThrottleKey(ip)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against the account and the
+// client IP. Unknown accounts are counted too so lockouts do not reveal
+// which addresses are registered.
+func (s *Service) recordLoginFailure(ctx context.Context, email string) {
+	if s.accountThrottle != nil {
+		s.recordThrottleFailure(ctx, s.accountThrottle, "account", accountThrottleKey(email))
+	}
+	if ip := requestmeta.FromContext(ctx).IP; s.ipThrottle != nil && ip != "" {
+		s.recordThrottleFailure(ctx, s.ipThrottle, "ip", ipThrottleKey(ip))
+	}
+}
+
+func (s *Service) recordThrottleFailure(ctx context.Context, tracker *lockout.Tracker, scope, key string) {
+	locked, err := tracker.RecordFailure(ctx, key)
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %v", key, err)
+		return
+	}
+	if locked {
+		s.audit(ctx, audit.Event{
+			Type:    audit.EventLoginLocked,
+			Reason:  scope,
+			Details: map[string]string{"key": key},
+		})
+	}
+}
+
+// resetLoginFailures clears the failures of the account after a successful
+// login. The IP counter is left alone so that one valid account cannot be
+// used to keep guessing the passwords of others.
+func (s *Service) resetLoginFailures(ctx context.Context, email string) {
+	if s.accountThrottle == nil {
+		return
+	}
+	if err := s.accountThrottle.Reset(ctx, accountThrottleKey(email)); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "login:account:" + user.CanonicalEmail(email)
+}
+
+func ipThrottleKey(ip string) string {
+	return "login:ip:" + ip
+}
@@ -0,0 +1,86 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/requestmeta"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+)
+
+// newThrottledService locks accounts after 3 failures and IPs after 5,
+// without backoff in between
+func newThrottledService() *userUseCase.Service {
+	store := lockout.NewMemoryStore()
+	accounts := lockout.NewTracker(store, lockout.Policy{MaxAttempts: 3, LockoutDuration: time.Hour, Window: time.Hour})
+	ips := lockout.NewTracker(store, lockout.Policy{MaxAttempts: 5, LockoutDuration: time.Hour, Window: time.Hour})
+
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	return userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithLoginThrottle(accounts, ips),
+	)
+}
+
+func TestService_Login_AccountLockout(t *testing.T) {
+	service := newThrottledService()
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.Login(ctx, "test@example.com", "wrongpassword"); !errors.Is(err, userUseCase.ErrInvalidCredentials) {
+			t.Fatalf("Login() attempt %d error = %v, want %v", i+1, err, userUseCase.ErrInvalidCredentials)
+		}
+	}
+
+	// Even the right password is refused while locked
+	_, err := service.Login(ctx, "test@example.com", "password123")
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("Login() error = %v, want a *lockout.LockedError", err)
+	}
+}
+
+func TestService_Login_SuccessResetsFailures(t *testing.T) {
+	service := newThrottledService()
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	for round := 0; round < 2; round++ {
+		service.Login(ctx, "test@example.com", "wrongpassword")
+		service.Login(ctx, "test@example.com", "wrongpassword")
+		if _, err := service.Login(ctx, "test@example.com", "password123"); err != nil {
+			t.Fatalf("Login() round %d unexpected error = %v", round+1, err)
+		}
+	}
+}
+
+func TestService_Login_IPLockout(t *testing.T) {
+	service := newThrottledService()
+	ctx := requestmeta.NewContext(context.Background(), requestmeta.Meta{IP: "192.0.2.1"})
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	// Spread the guesses over several accounts so only the IP limit applies
+	for i, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		if _, err := service.Login(ctx, email, "guess"); !errors.Is(err, userUseCase.ErrInvalidCredentials) {
+			t.Fatalf("Login() attempt %d error = %v, want %v", i+1, err, userUseCase.ErrInvalidCredentials)
+		}
+	}
+
+	if _, err := service.Login(ctx, "test@example.com", "password123"); !errors.Is(err, lockout.ErrLocked) {
+		t.Errorf("Login() from locked IP error = %v, want %v", err, lockout.ErrLocked)
+	}
+
+	// Other clients are unaffected
+	other := requestmeta.NewContext(context.Background(), requestmeta.Meta{IP: "192.0.2.2"})
+	if _, err := service.Login(other, "test@example.com", "password123"); err != nil {
+		t.Errorf("Login() from another IP unexpected error = %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/securetoken"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// Tokens is the credential set returned after a successful authentication
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// Refresh exchanges a refresh token for a new access token and a rotated
+// refresh token. Presenting a token that was already exchanged revokes its
+// whole family, since it means the token has leaked.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	if s.refreshRepo == nil || refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.refreshRepo.FindByHash(ctx, securetoken.Hash(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if stored.IsExpired(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.IsUsed() {
+		return nil, s.handleReuse(ctx, stored)
+	}
+	if stored.IsRevoked() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Guard against two concurrent exchanges of the same token
+	marked, err := s.refreshRepo.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, s.handleReuse(ctx, stored)
+	}
+
+	u, err := s.repo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	sessionID, err := s.refreshSession(ctx, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, u, stored.FamilyID, sessionID)
+}
+
+// handleReuse revokes the family of a replayed refresh token
+func (s *Service) handleReuse(ctx context.Context, stored *user.RefreshToken) error {
+	if err := s.refreshRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventRefreshReuse,
+		SubjectID: stored.UserID,
+		Details:   map[string]string{"family_id": stored.FamilyID},
+	})
+	return ErrRefreshTokenReused
+}
+
+// startLogin opens a new session for a fully authenticated user and issues
+// its first tokens, restoring the account if its deletion is pending.
+// method names how the user authenticated, for the audit log.
+func (s *Service) startLogin(ctx context.Context, u *user.User, method string) (*Tokens, error) {
+	if err := s.cancelDeletion(ctx, u); err != nil {
+		return nil, err
+	}
+
+	familyID := uuid.New().String()
+	sessionID, err := s.startSession(ctx, u, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(ctx, u, familyID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventLoginSucceeded,
+		ActorID:   u.ID,
+		SubjectID: u.ID,
+		Details:   map[string]string{"method": method, "session_id": sessionID},
+	})
+	return tokens, nil
+}
+
+// issueTokens creates an access token for the user's session and, when
+// refresh tokens are enabled, a refresh token in the given family
+func (s *Service) issueTokens(ctx context.Context, u *user.User, familyID, sessionID string) (*Tokens, error) {
+	accessToken, err := s.jwtService.GenerateAccessToken(jwt.Claims{
+		UserID:        u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Role:          string(u.Role),
+		SessionID:     sessionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := &Tokens{
+		AccessToken: accessToken,
+		ExpiresIn:   s.jwtService.TokenDuration(),
+	}
+	if s.refreshRepo == nil {
+		return tokens, nil
+	}
+
+	raw, err := securetoken.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := user.NewRefreshToken(u.ID, familyID, securetoken.Hash(raw), s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshRepo.Create(ctx, refresh); err != nil {
+		return nil, err
+	}
+
+	tokens.RefreshToken = raw
+	return tokens, nil
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+)
+
+// maxTxAttempts bounds how often a unit of work is run when its
+// transaction keeps losing races with concurrent ones
+const maxTxAttempts = 3
+
+// WithTransactions runs multi-step work, such as registration, in
+// transactions of manager, which must manage the storage of the
+// repositories
+func WithTransactions(manager transaction.Manager) Option {
+	return func(s *Service) {
+		s.txManager = manager
+	}
+}
+
+// withinTx runs fn in a transaction, or directly when none is configured.
+// fn is run again when the transaction conflicts with a concurrent one or
+// a user it updates has changed since it was read, so it must start over
+// from what it reads through ctx and leave no trace outside the
+// transaction.
+func (s *Service) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.txManager == nil {
+		return fn(ctx)
+	}
+
+	var err error
+	for range maxTxAttempts {
+		err = s.txManager.WithinTx(ctx, fn)
+		if !errors.Is(err, transaction.ErrConflict) && !errors.Is(err, user.ErrConcurrentModification) {
+			return err
+		}
+	}
+	return err
+}
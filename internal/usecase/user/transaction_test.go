@@ -0,0 +1,236 @@
+package user_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/transaction"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/memtx"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+// conflictingManager runs the work in transactions that then fail to
+// commit, as if they had raced other ones, while conflicts is above zero
+type conflictingManager struct {
+	memtx     *memtx.Manager
+	conflicts *int
+}
+
+func (m conflictingManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.memtx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		if *m.conflicts > 0 {
+			*m.conflicts--
+			return transaction.ErrConflict
+		}
+		return nil
+	})
+}
+
+// racingManager runs race once, outside the transaction, after the first
+// attempt of the work and before it commits, so the commit conflicts
+type racingManager struct {
+	memtx *memtx.Manager
+	race  func()
+}
+
+func (m *racingManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.memtx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		if m.race != nil {
+			m.race()
+			m.race = nil
+		}
+		return nil
+	})
+}
+
+// renameUser changes the stored user as a concurrent request would
+func renameUser(t *testing.T, repo *user.InMemoryRepository, id string) {
+	t.Helper()
+
+	u, err := repo.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindByID() unexpected error = %v", err)
+	}
+	u.Username = "renamed"
+	if err := repo.Update(context.Background(), u); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+}
+
+func TestService_Register_RetriesConflicts(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
+	conflicts := 1
+	service := userUseCase.NewService(repo, jwtService,
+		userUseCase.WithTransactions(conflictingManager{memtx: memtx.NewManager(), conflicts: &conflicts}),
+	)
+	ctx := context.Background()
+
+	u, err := service.Register(ctx, "testuser", "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Register() unexpected error = %v", err)
+	}
+	if _, err := repo.FindByID(ctx, u.ID); err != nil {
+		t.Errorf("FindByID() of the registered user error = %v", err)
+	}
+}
+
+func TestService_Register_RollsBack(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
+	conflicts := 100
+	service := userUseCase.NewService(repo, jwtService,
+		userUseCase.WithTransactions(conflictingManager{memtx: memtx.NewManager(), conflicts: &conflicts}),
+	)
+	ctx := context.Background()
+
+	if _, err := service.Register(ctx, "testuser", "test@example.com", "password123"); err != transaction.ErrConflict {
+		t.Fatalf("Register() error = %v, want %v", err, transaction.ErrConflict)
+	}
+	if _, err := repo.FindByEmail(ctx, "test@example.com"); err != user.ErrUserNotFound {
+		t.Errorf("a rolled back registration stored the user: FindByEmail() error = %v", err)
+	}
+}
+
+func TestService_ResetPassword_RollsBack(t *testing.T) {
+	mailer := &captureMailer{}
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	conflicts := 0
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithRefreshTokens(user.NewInMemoryRefreshTokenRepository(), time.Hour),
+		userUseCase.WithSessions(user.NewInMemorySessionRepository(), time.Hour),
+		userUseCase.WithMailer(mailer, "http://localhost:8080"),
+		userUseCase.WithPasswordReset(user.NewInMemoryPasswordResetTokenRepository(), time.Hour),
+		userUseCase.WithTransactions(conflictingManager{memtx: memtx.NewManager(), conflicts: &conflicts}),
+	)
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+	session := mustLogin(t, service, "test@example.com", "password123")
+	service.RequestPasswordReset(ctx, "test@example.com")
+	token := mailer.lastResetToken(t)
+
+	conflicts = 100
+	if err := service.ResetPassword(ctx, token, "newpassword456"); err != transaction.ErrConflict {
+		t.Fatalf("ResetPassword() error = %v, want %v", err, transaction.ErrConflict)
+	}
+
+	// Nothing of the failed reset is left behind
+	conflicts = 0
+	if _, err := service.Refresh(ctx, session.RefreshToken); err != nil {
+		t.Errorf("Refresh() after a rolled back reset error = %v", err)
+	}
+	mustLogin(t, service, "test@example.com", "password123")
+	if err := service.ResetPassword(ctx, token, "newpassword456"); err != nil {
+		t.Errorf("ResetPassword() with the token of the rolled back reset error = %v", err)
+	}
+}
+
+func TestService_ResetPassword_RetriesConflicts(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	mailer := &captureMailer{}
+	manager := &racingManager{memtx: memtx.NewManager()}
+	service := userUseCase.NewService(repo, jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute),
+		userUseCase.WithMailer(mailer, "http://localhost:8080"),
+		userUseCase.WithPasswordReset(user.NewInMemoryPasswordResetTokenRepository(), time.Hour),
+		userUseCase.WithTransactions(manager),
+	)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	service.RequestPasswordReset(ctx, "test@example.com")
+	token := mailer.lastResetToken(t)
+
+	// The retry must start from the renamed user, not the one read first
+	manager.race = func() { renameUser(t, repo, u.ID) }
+	if err := service.ResetPassword(ctx, token, "newpassword456"); err != nil {
+		t.Fatalf("ResetPassword() unexpected error = %v", err)
+	}
+	mustLogin(t, service, "test@example.com", "newpassword456")
+	if found, _ := repo.FindByID(ctx, u.ID); found.Username != "renamed" {
+		t.Errorf("ResetPassword() overwrote the concurrent change: Username = %q", found.Username)
+	}
+}
+
+func TestService_ChangePassword_RetriesConflicts(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	manager := &racingManager{memtx: memtx.NewManager()}
+	service := userUseCase.NewService(repo, jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute),
+		userUseCase.WithTransactions(manager),
+	)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	manager.race = func() { renameUser(t, repo, u.ID) }
+	if err := service.ChangePassword(ctx, u.ID, "password123", "newpassword456"); err != nil {
+		t.Fatalf("ChangePassword() unexpected error = %v", err)
+	}
+	mustLogin(t, service, "test@example.com", "newpassword456")
+	if found, _ := repo.FindByID(ctx, u.ID); found.Username != "renamed" {
+		t.Errorf("ChangePassword() overwrote the concurrent change: Username = %q", found.Username)
+	}
+}
+
+func TestService_DeleteAccount_RetriesConflicts(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	manager := &racingManager{memtx: memtx.NewManager()}
+	service := userUseCase.NewService(repo, jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute),
+		userUseCase.WithTransactions(manager),
+	)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	manager.race = func() { renameUser(t, repo, u.ID) }
+	if _, err := service.DeleteAccount(ctx, u.ID, "password123"); err != nil {
+		t.Fatalf("DeleteAccount() unexpected error = %v", err)
+	}
+	found, _ := repo.FindByID(ctx, u.ID)
+	if !found.IsPendingDeletion() || found.Username != "renamed" {
+		t.Errorf("DeleteAccount() stored %+v, want the renamed user pending deletion", found)
+	}
+}
+
+func TestService_Register_Concurrent(t *testing.T) {
+	repo := user.NewInMemoryRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 24*time.Hour)
+	service := userUseCase.NewService(repo, jwtService,
+		userUseCase.WithTransactions(memtx.NewManager()),
+	)
+
+	const attempts = 4
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = service.Register(context.Background(), fmt.Sprintf("user%d", i), "test@example.com", "password123")
+		}(i)
+	}
+	wg.Wait()
+
+	registered := 0
+	for _, err := range errs {
+		if err == nil {
+			registered++
+		}
+	}
+	if registered != 1 {
+		t.Errorf("%d concurrent registrations of one email succeeded, want 1", registered)
+	}
+}
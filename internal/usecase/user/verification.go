@@ -0,0 +1,102 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
+)
+
+const (
+	emailVerificationPurpose = "email_verification"
+
+	defaultEmailVerificationTTL = 24 * time.Hour
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailNotVerified         = errors.New("email address has not been verified")
+)
+
+// WithMailer sets the mailer used for account emails. baseURL is the
+// public address of the API that links in those emails point to.
+func WithMailer(mailer mail.Mailer, baseURL string) Option {
+	return func(s *Service) {
+		s.mailer = mailer
+		s.baseURL = baseURL
+	}
+}
+
+// WithEmailVerification configures how long verification links stay valid
+// and whether unverified accounts are refused at login
+func WithEmailVerification(ttl time.Duration, requiredForLogin bool) Option {
+	return func(s *Service) {
+		s.verificationTTL = ttl
+		s.requireVerifiedLogin = requiredForLogin
+	}
+}
+
+// VerifyEmail marks the email address carried by the token as verified. The
+// token only matches while the account still has that address, so links sent
+// before an email change stop working.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.jwtService.ValidatePurposeToken(token, emailVerificationPurpose)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	u, err := s.repo.FindByID(ctx, claims.UserID)
+	if err != nil || u.Email != claims.Email {
+		return ErrInvalidVerificationToken
+	}
+	if u.EmailVerified {
+		return nil
+	}
+
+	u.MarkEmailVerified()
+	return s.repo.Update(ctx, u)
+}
+
+// ResendVerification sends a new verification email. It reports success for
+// unknown or already verified addresses so it cannot be used to probe for
+// accounts.
+func (s *Service) ResendVerification(ctx context.Context, email string) error {
+	u, err := s.repo.FindByEmail(ctx, email)
+	if err != nil || u.EmailVerified {
+		return nil
+	}
+	return s.sendVerificationEmail(ctx, u)
+}
+
+// sendVerificationEmail mails a signed, expiring verification link to the user
+func (s *Service) sendVerificationEmail(ctx context.Context, u *user.User) error {
+	if s.mailer == nil {
+		return nil
+	}
+
+	token, err := s.jwtService.GeneratePurposeToken(u.ID, u.Email, emailVerificationPurpose, s.verificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.baseURL + "/api/verify-email?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.",
+			u.Username, link, s.verificationTTL),
+	})
+}
+
+// notifyVerification sends the verification email after a state change that
+// must not fail because of mail delivery
+func (s *Service) notifyVerification(ctx context.Context, u *user.User) {
+	if err := s.sendVerificationEmail(ctx, u); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", u.ID, err)
+	}
+}
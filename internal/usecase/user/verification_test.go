@@ -0,0 +1,177 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
+)
+
+// captureMailer records sent messages instead of delivering them
+type captureMailer struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (m *captureMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *captureMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.messages)
+}
+
+// lastToken extracts the token from the link in the most recent message
+func (m *captureMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) == 0 {
+		t.Fatal("no message was sent")
+	}
+	body := m.messages[len(m.messages)-1].Body
+	i := strings.Index(body, "token=")
+	if i < 0 {
+		t.Fatalf("message has no token link: %q", body)
+	}
+	raw := strings.Fields(body[i+len("token="):])[0]
+	token, err := url.QueryUnescape(raw)
+	if err != nil {
+		t.Fatalf("QueryUnescape() unexpected error = %v", err)
+	}
+	return token
+}
+
+func newVerificationService(requiredForLogin bool) (*userUseCase.Service, *user.InMemoryRepository, *captureMailer) {
+	mailer := &captureMailer{}
+	repo := user.NewInMemoryRepository()
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute)
+	service := userUseCase.NewService(repo, jwtService,
+		userUseCase.WithMailer(mailer, "http://localhost:8080"),
+		userUseCase.WithEmailVerification(time.Hour, requiredForLogin),
+	)
+	return service, repo, mailer
+}
+
+func TestService_VerifyEmail(t *testing.T) {
+	service, repo, mailer := newVerificationService(false)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	if mailer.count() != 1 {
+		t.Fatalf("Register() sent %d messages, want 1", mailer.count())
+	}
+
+	if err := service.VerifyEmail(ctx, mailer.lastToken(t)); err != nil {
+		t.Fatalf("VerifyEmail() unexpected error = %v", err)
+	}
+
+	verified, _ := repo.FindByID(ctx, u.ID)
+	if !verified.EmailVerified || verified.EmailVerifiedAt == nil {
+		t.Error("VerifyEmail() should mark the email as verified")
+	}
+
+	// Verifying twice is harmless
+	if err := service.VerifyEmail(ctx, mailer.lastToken(t)); err != nil {
+		t.Errorf("VerifyEmail() second call unexpected error = %v", err)
+	}
+}
+
+func TestService_VerifyEmail_InvalidToken(t *testing.T) {
+	service, repo, _ := newVerificationService(false)
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	// An access token must not be accepted as a verification token
+	tokens, _ := service.Login(ctx, "test@example.com", "password123")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "garbage", token: "not-a-token"},
+		{name: "access token", token: tokens.Tokens.AccessToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.VerifyEmail(ctx, tt.token)
+			if !errors.Is(err, userUseCase.ErrInvalidVerificationToken) {
+				t.Errorf("VerifyEmail() error = %v, want %v", err, userUseCase.ErrInvalidVerificationToken)
+			}
+		})
+	}
+
+	got, _ := repo.FindByID(ctx, u.ID)
+	if got.EmailVerified {
+		t.Error("email should not be verified")
+	}
+}
+
+func TestService_ResendVerification(t *testing.T) {
+	service, _, mailer := newVerificationService(false)
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	if err := service.ResendVerification(ctx, "test@example.com"); err != nil {
+		t.Fatalf("ResendVerification() unexpected error = %v", err)
+	}
+	if mailer.count() != 2 {
+		t.Fatalf("ResendVerification() sent %d messages in total, want 2", mailer.count())
+	}
+
+	// Unknown addresses succeed without sending anything
+	if err := service.ResendVerification(ctx, "unknown@example.com"); err != nil {
+		t.Errorf("ResendVerification() unknown email error = %v, want nil", err)
+	}
+
+	// Verified addresses are not mailed again
+	service.VerifyEmail(ctx, mailer.lastToken(t))
+	if err := service.ResendVerification(ctx, "test@example.com"); err != nil {
+		t.Errorf("ResendVerification() verified email error = %v, want nil", err)
+	}
+
+	if mailer.count() != 2 {
+		t.Errorf("messages sent = %d, want 2", mailer.count())
+	}
+}
+
+func TestService_Login_RequiresVerifiedEmail(t *testing.T) {
+	service, _, mailer := newVerificationService(true)
+	ctx := context.Background()
+
+	service.Register(ctx, "testuser", "test@example.com", "password123")
+
+	_, err := service.Login(ctx, "test@example.com", "password123")
+	if !errors.Is(err, userUseCase.ErrEmailNotVerified) {
+		t.Fatalf("Login() error = %v, want %v", err, userUseCase.ErrEmailNotVerified)
+	}
+
+	service.VerifyEmail(ctx, mailer.lastToken(t))
+
+	result, err := service.Login(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Login() unexpected error = %v", err)
+	}
+
+	claims, _ := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute).ValidateToken(result.Tokens.AccessToken)
+	if !claims.EmailVerified {
+		t.Error("access token should carry the email_verified claim")
+	}
+}
@@ -0,0 +1,332 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
+)
+
+const (
+	webAuthnRegisterPurpose = "webauthn_register"
+	webAuthnLoginPurpose    = "webauthn_login"
+	webAuthnMFAPurpose      = "webauthn_mfa"
+
+	webAuthnCeremonyTTL = 5 * time.Minute
+)
+
+var (
+	ErrWebAuthnDisabled        = errors.New("passkeys are not enabled")
+	ErrInvalidWebAuthnCeremony = errors.New("invalid or expired passkey ceremony")
+	ErrInvalidPasskey          = errors.New("passkey could not be verified")
+	ErrPasskeyNotFound         = errors.New("passkey not found")
+)
+
+// WebAuthnRegistration holds the options to pass to
+// navigator.credentials.create and the ceremony token to send back with
+// the new credential
+type WebAuthnRegistration struct {
+	Options       *webauthn.CreationOptions
+	CeremonyToken string
+}
+
+// WebAuthnLogin holds the options to pass to navigator.credentials.get and
+// the ceremony token to send back with the assertion
+type WebAuthnLogin struct {
+	Options       *webauthn.RequestOptions
+	CeremonyToken string
+}
+
+// WithWebAuthn enables passkeys for rp, stored in repo. Passkeys can be
+// used to sign in without a password, or as a second factor after it.
+func WithWebAuthn(rp *webauthn.RelyingParty, repo user.WebAuthnCredentialRepository) Option {
+	return func(s *Service) {
+		s.webAuthn = rp
+		s.webAuthnRepo = repo
+	}
+}
+
+// ceremonyChallenge derives the WebAuthn challenge from the signed ceremony
+// token, so no challenge state has to be kept between the two requests
+func ceremonyChallenge(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// BeginWebAuthnRegistration starts registering a new passkey for the user
+func (s *Service) BeginWebAuthnRegistration(ctx context.Context, userID string) (*WebAuthnRegistration, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webAuthnRepo.ListByUser(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([][]byte, 0, len(existing))
+	for _, c := range existing {
+		exclude = append(exclude, c.CredentialID)
+	}
+
+	token, err := s.jwtService.GeneratePurposeToken(u.ID, u.Email, webAuthnRegisterPurpose, webAuthnCeremonyTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := webauthn.User{ID: []byte(u.ID), Name: u.Email, DisplayName: u.Username}
+	return &WebAuthnRegistration{
+		Options:       s.webAuthn.CreationOptions(owner, ceremonyChallenge(token), exclude, webauthn.UserVerificationPreferred),
+		CeremonyToken: token,
+	}, nil
+}
+
+// FinishWebAuthnRegistration verifies the credential created for the
+// ceremony started by BeginWebAuthnRegistration and stores it under name.
+// Ceremony tokens are single-use.
+func (s *Service) FinishWebAuthnRegistration(ctx context.Context, userID, ceremonyToken, name string, resp *webauthn.RegistrationResponse) (*user.WebAuthnCredential, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	claims, err := s.jwtService.ValidatePurposeToken(ceremonyToken, webAuthnRegisterPurpose)
+	if err != nil || claims.UserID != userID {
+		return nil, ErrInvalidWebAuthnCeremony
+	}
+	if err := s.jwtService.RevokeToken(claims); err != nil {
+		return nil, err
+	}
+
+	verified, err := s.webAuthn.VerifyRegistration(resp, ceremonyChallenge(ceremonyToken), false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	credential, err := user.NewWebAuthnCredential(userID, verified.ID, verified.PublicKey, verified.SignCount, name)
+	if err != nil {
+		return nil, err
+	}
+	credential.AAGUID = verified.AAGUID
+	credential.AttestationFormat = verified.AttestationFormat
+
+	if err := s.webAuthnRepo.Create(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventPasskeyRegistered,
+		ActorID:   userID,
+		SubjectID: userID,
+		Details:   map[string]string{"credential_id": credential.ID, "attestation": credential.AttestationFormat},
+	})
+	return credential, nil
+}
+
+// ListWebAuthnCredentials returns the user's passkeys, oldest first
+func (s *Service) ListWebAuthnCredentials(ctx context.Context, userID string) ([]*user.WebAuthnCredential, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	return s.webAuthnRepo.ListByUser(ctx, userID)
+}
+
+// DeleteWebAuthnCredential removes one of the user's passkeys. Accounts
+// required to use MFA cannot remove their last second factor.
+func (s *Service) DeleteWebAuthnCredential(ctx context.Context, userID, credentialID string) error {
+	if s.webAuthn == nil {
+		return ErrWebAuthnDisabled
+	}
+
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	credentials, err := s.webAuthnRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, c := range credentials {
+		if c.ID == credentialID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrPasskeyNotFound
+	}
+	if u.MFARequired && !u.MFAEnabled() && len(credentials) == 1 {
+		return ErrMFARequired
+	}
+
+	if err := s.webAuthnRepo.Delete(ctx, credentialID); err != nil {
+		return err
+	}
+
+	s.audit(ctx, audit.Event{
+		Type:      audit.EventPasskeyRemoved,
+		ActorID:   userID,
+		SubjectID: userID,
+		Details:   map[string]string{"credential_id": credentialID},
+	})
+	return nil
+}
+
+// BeginWebAuthnLogin starts a passkey login. Without an MFA token it is a
+// passwordless login: the browser offers any passkey it holds for the site
+// and the authenticator must verify the user. With the MFA token returned
+// from Login, the user's passkeys serve as the second factor.
+func (s *Service) BeginWebAuthnLogin(ctx context.Context, mfaToken string) (*WebAuthnLogin, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	if mfaToken == "" {
+		token, err := s.jwtService.GeneratePurposeToken("", "", webAuthnLoginPurpose, webAuthnCeremonyTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &WebAuthnLogin{
+			Options:       s.webAuthn.RequestOptions(ceremonyChallenge(token), nil, webauthn.UserVerificationRequired),
+			CeremonyToken: token,
+		}, nil
+	}
+
+	claims, err := s.jwtService.ValidatePurposeToken(mfaToken, mfaTokenPurpose)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	credentials, err := s.webAuthnRepo.ListByUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, ErrPasskeyNotFound
+	}
+	// The MFA token is exchanged for the ceremony token
+	if err := s.jwtService.RevokeToken(claims); err != nil {
+		return nil, err
+	}
+
+	allow := make([][]byte, 0, len(credentials))
+	for _, c := range credentials {
+		allow = append(allow, c.CredentialID)
+	}
+
+	token, err := s.jwtService.GeneratePurposeToken(claims.UserID, claims.Email, webAuthnMFAPurpose, webAuthnCeremonyTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &WebAuthnLogin{
+		Options:       s.webAuthn.RequestOptions(ceremonyChallenge(token), allow, webauthn.UserVerificationDiscouraged),
+		CeremonyToken: token,
+	}, nil
+}
+
+// FinishWebAuthnLogin verifies the assertion for a ceremony started by
+// BeginWebAuthnLogin and signs the user in. A passwordless login still
+// goes through the verified email requirement; since the passkey already
+// verified the user, it counts as multi-factor and skips the MFA step.
+func (s *Service) FinishWebAuthnLogin(ctx context.Context, ceremonyToken string, resp *webauthn.AssertionResponse) (*LoginResult, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	if resp == nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	claims, err := s.jwtService.ValidatePurposeToken(ceremonyToken, webAuthnMFAPurpose)
+	secondFactor := err == nil
+	if !secondFactor {
+		claims, err = s.jwtService.ValidatePurposeToken(ceremonyToken, webAuthnLoginPurpose)
+		if err != nil {
+			return nil, ErrInvalidWebAuthnCeremony
+		}
+	}
+
+	credential, err := s.webAuthnRepo.FindByCredentialID(ctx, resp.RawID)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	// A second factor must belong to the user who entered the password; a
+	// discoverable credential must match the user handle it returned
+	if secondFactor && credential.UserID != claims.UserID {
+		return nil, ErrInvalidPasskey
+	}
+	if !secondFactor && string(resp.Response.UserHandle) != credential.UserID {
+		return nil, ErrInvalidPasskey
+	}
+
+	u, err := s.repo.FindByID(ctx, credential.UserID)
+	if err != nil || u.IsAnonymized() {
+		return nil, ErrInvalidPasskey
+	}
+
+	if err := s.checkLoginThrottle(ctx, u.Email); err != nil {
+		s.auditLoginFailure(ctx, u.Email, u.ID, audit.ReasonLockedOut)
+		return nil, err
+	}
+	if err := s.jwtService.RevokeToken(claims); err != nil {
+		return nil, err
+	}
+
+	signCount, err := s.webAuthn.VerifyAssertion(resp, ceremonyChallenge(ceremonyToken), &webauthn.Credential{
+		ID:        credential.CredentialID,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+	}, !secondFactor)
+	if err != nil {
+		reason := audit.ReasonInvalidPasskey
+		if errors.Is(err, webauthn.ErrSignCountRegression) {
+			reason = audit.ReasonPasskeyCloned
+		}
+		s.auditLoginFailure(ctx, u.Email, u.ID, reason)
+		s.recordLoginFailure(ctx, u.Email)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	// A concurrent login with the same counter means a replayed or cloned
+	// assertion
+	updated, err := s.webAuthnRepo.UpdateSignCount(ctx, credential.ID, credential.SignCount, signCount, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		s.auditLoginFailure(ctx, u.Email, u.ID, audit.ReasonPasskeyCloned)
+		return nil, ErrInvalidPasskey
+	}
+
+	if !secondFactor {
+		return s.completeLogin(ctx, u, "passkey", true)
+	}
+
+	tokens, err := s.startLogin(ctx, u, "mfa_passkey")
+	if err != nil {
+		return nil, err
+	}
+	s.resetLoginFailures(ctx, u.Email)
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// hasPasskeys reports whether the user has registered a passkey, which
+// makes it usable as a second factor
+func (s *Service) hasPasskeys(ctx context.Context, userID string) (bool, error) {
+	if s.webAuthn == nil {
+		return false, nil
+	}
+	credentials, err := s.webAuthnRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return len(credentials) > 0, nil
+}
@@ -0,0 +1,254 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/domain/audit"
+	"github.com/DiaaSaada/crypto-payment-gateway/internal/repository/user"
+	userUseCase "github.com/DiaaSaada/crypto-payment-gateway/internal/usecase/user"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn/webauthntest"
+)
+
+const testOrigin = "https://pay.example.com"
+
+func newWebAuthnService() (*userUseCase.Service, *captureRecorder) {
+	recorder := &captureRecorder{}
+	jwtService := jwt.NewService(jwt.NewHMACSigner("test-secret"), 15*time.Minute,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+	rp := webauthn.NewRelyingParty("pay.example.com", "Test", []string{testOrigin}, time.Minute)
+	service := userUseCase.NewService(user.NewInMemoryRepository(), jwtService,
+		userUseCase.WithWebAuthn(rp, user.NewInMemoryWebAuthnCredentialRepository()),
+		userUseCase.WithAuditLog(recorder),
+	)
+	return service, recorder
+}
+
+// registerPasskey registers test@example.com with a passkey held by the
+// returned authenticator
+func registerPasskey(t *testing.T, service *userUseCase.Service) (string, *webauthntest.Authenticator) {
+	t.Helper()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+
+	registration, err := service.BeginWebAuthnRegistration(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration() unexpected error = %v", err)
+	}
+	resp, err := authenticator.Create(registration.Options)
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if _, err := service.FinishWebAuthnRegistration(ctx, u.ID, registration.CeremonyToken, "Laptop", resp); err != nil {
+		t.Fatalf("FinishWebAuthnRegistration() unexpected error = %v", err)
+	}
+	return u.ID, authenticator
+}
+
+// passkeyLogin runs a login ceremony with the authenticator
+func passkeyLogin(t *testing.T, service *userUseCase.Service, authenticator *webauthntest.Authenticator, mfaToken string) (*userUseCase.LoginResult, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	login, err := service.BeginWebAuthnLogin(ctx, mfaToken)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnLogin() unexpected error = %v", err)
+	}
+	resp, err := authenticator.Get(login.Options)
+	if err != nil {
+		t.Fatalf("Get() unexpected error = %v", err)
+	}
+	return service.FinishWebAuthnLogin(ctx, login.CeremonyToken, resp)
+}
+
+func TestService_WebAuthn_Register(t *testing.T) {
+	service, recorder := newWebAuthnService()
+	ctx := context.Background()
+
+	userID, authenticator := registerPasskey(t, service)
+	if e := recorder.last(); e.Type != audit.EventPasskeyRegistered || e.SubjectID != userID {
+		t.Errorf("last event = %+v, want passkey registration", e)
+	}
+
+	credentials, err := service.ListWebAuthnCredentials(ctx, userID)
+	if err != nil || len(credentials) != 1 || credentials[0].Name != "Laptop" {
+		t.Fatalf("ListWebAuthnCredentials() = %+v, %v; want the new passkey", credentials, err)
+	}
+
+	// Registered passkeys are excluded from the next registration
+	registration, _ := service.BeginWebAuthnRegistration(ctx, userID)
+	if len(registration.Options.ExcludeCredentials) != 1 {
+		t.Errorf("ExcludeCredentials = %d, want 1", len(registration.Options.ExcludeCredentials))
+	}
+
+	// Ceremony tokens are single-use and bound to the user
+	resp, _ := authenticator.Create(registration.Options)
+	if _, err := service.FinishWebAuthnRegistration(ctx, "other-user", registration.CeremonyToken, "", resp); !errors.Is(err, userUseCase.ErrInvalidWebAuthnCeremony) {
+		t.Errorf("FinishWebAuthnRegistration() other user error = %v, want %v", err, userUseCase.ErrInvalidWebAuthnCeremony)
+	}
+	if _, err := service.FinishWebAuthnRegistration(ctx, userID, registration.CeremonyToken, "", resp); err != nil {
+		t.Fatalf("FinishWebAuthnRegistration() unexpected error = %v", err)
+	}
+	if _, err := service.FinishWebAuthnRegistration(ctx, userID, registration.CeremonyToken, "", resp); !errors.Is(err, userUseCase.ErrInvalidWebAuthnCeremony) {
+		t.Errorf("FinishWebAuthnRegistration() reused token error = %v, want %v", err, userUseCase.ErrInvalidWebAuthnCeremony)
+	}
+}
+
+func TestService_WebAuthn_RegisterInvalidResponse(t *testing.T) {
+	service, _ := newWebAuthnService()
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "testuser", "test@example.com", "password123")
+	registration, _ := service.BeginWebAuthnRegistration(ctx, u.ID)
+
+	// A credential created for a different origin is rejected
+	resp, _ := webauthntest.NewAuthenticator("https://evil.example.com").Create(registration.Options)
+	if _, err := service.FinishWebAuthnRegistration(ctx, u.ID, registration.CeremonyToken, "", resp); !errors.Is(err, userUseCase.ErrInvalidPasskey) {
+		t.Errorf("FinishWebAuthnRegistration() error = %v, want %v", err, userUseCase.ErrInvalidPasskey)
+	}
+}
+
+func TestService_WebAuthn_PasswordlessLogin(t *testing.T) {
+	service, recorder := newWebAuthnService()
+	ctx := context.Background()
+
+	userID, authenticator := registerPasskey(t, service)
+
+	result, err := passkeyLogin(t, service, authenticator, "")
+	if err != nil || result.Tokens == nil {
+		t.Fatalf("FinishWebAuthnLogin() = %+v, %v; want tokens", result, err)
+	}
+	e := recorder.last()
+	if e.Type != audit.EventLoginSucceeded || e.SubjectID != userID || e.Details["method"] != "passkey" {
+		t.Errorf("last event = %+v, want passkey login", e)
+	}
+
+	credentials, _ := service.ListWebAuthnCredentials(ctx, userID)
+	if credentials[0].SignCount != 1 || credentials[0].LastUsedAt == nil {
+		t.Errorf("credential = %+v, want sign count and last use recorded", credentials[0])
+	}
+
+	// Passwordless login requires user verification
+	authenticator.UserVerified = false
+	if _, err := passkeyLogin(t, service, authenticator, ""); !errors.Is(err, userUseCase.ErrInvalidPasskey) {
+		t.Errorf("FinishWebAuthnLogin() without UV error = %v, want %v", err, userUseCase.ErrInvalidPasskey)
+	}
+}
+
+func TestService_WebAuthn_LoginTokenIsSingleUse(t *testing.T) {
+	service, _ := newWebAuthnService()
+	ctx := context.Background()
+
+	_, authenticator := registerPasskey(t, service)
+
+	login, _ := service.BeginWebAuthnLogin(ctx, "")
+	resp, _ := authenticator.Get(login.Options)
+	if _, err := service.FinishWebAuthnLogin(ctx, login.CeremonyToken, resp); err != nil {
+		t.Fatalf("FinishWebAuthnLogin() unexpected error = %v", err)
+	}
+	if _, err := service.FinishWebAuthnLogin(ctx, login.CeremonyToken, resp); !errors.Is(err, userUseCase.ErrInvalidWebAuthnCeremony) {
+		t.Errorf("FinishWebAuthnLogin() replay error = %v, want %v", err, userUseCase.ErrInvalidWebAuthnCeremony)
+	}
+}
+
+func TestService_WebAuthn_SignCountRegression(t *testing.T) {
+	service, recorder := newWebAuthnService()
+	ctx := context.Background()
+
+	_, authenticator := registerPasskey(t, service)
+
+	first, _ := service.BeginWebAuthnLogin(ctx, "")
+	older, _ := authenticator.Get(first.Options)
+	second, _ := service.BeginWebAuthnLogin(ctx, "")
+	newer, _ := authenticator.Get(second.Options)
+
+	if _, err := service.FinishWebAuthnLogin(ctx, second.CeremonyToken, newer); err != nil {
+		t.Fatalf("FinishWebAuthnLogin() unexpected error = %v", err)
+	}
+	if _, err := service.FinishWebAuthnLogin(ctx, first.CeremonyToken, older); !errors.Is(err, webauthn.ErrSignCountRegression) {
+		t.Errorf("FinishWebAuthnLogin() older counter error = %v, want %v", err, webauthn.ErrSignCountRegression)
+	}
+	if e := recorder.last(); e.Type != audit.EventLoginFailed || e.Reason != audit.ReasonPasskeyCloned {
+		t.Errorf("last event = %+v, want cloned passkey failure", e)
+	}
+}
+
+func TestService_WebAuthn_SecondFactor(t *testing.T) {
+	service, _ := newWebAuthnService()
+	ctx := context.Background()
+
+	_, authenticator := registerPasskey(t, service)
+
+	// A registered passkey makes the password login two-step
+	result, err := service.Login(ctx, "test@example.com", "password123")
+	if err != nil || result.MFAToken == "" {
+		t.Fatalf("Login() = %+v, %v; want an MFA token", result, err)
+	}
+
+	// A second factor does not need user verification
+	authenticator.UserVerified = false
+	result, err = passkeyLogin(t, service, authenticator, result.MFAToken)
+	if err != nil || result.Tokens == nil {
+		t.Fatalf("FinishWebAuthnLogin() = %+v, %v; want tokens", result, err)
+	}
+}
+
+func TestService_WebAuthn_AssertionBoundToCeremony(t *testing.T) {
+	service, _ := newWebAuthnService()
+	ctx := context.Background()
+
+	_, authenticator := registerPasskey(t, service)
+
+	result, _ := service.Login(ctx, "test@example.com", "password123")
+	mfa, _ := service.BeginWebAuthnLogin(ctx, result.MFAToken)
+	resp, _ := authenticator.Get(mfa.Options)
+
+	// An assertion signs its ceremony's challenge and is useless for another
+	passwordless, _ := service.BeginWebAuthnLogin(ctx, "")
+	if _, err := service.FinishWebAuthnLogin(ctx, passwordless.CeremonyToken, resp); !errors.Is(err, userUseCase.ErrInvalidPasskey) {
+		t.Errorf("FinishWebAuthnLogin() error = %v, want %v", err, userUseCase.ErrInvalidPasskey)
+	}
+}
+
+func TestService_WebAuthn_MFARequired(t *testing.T) {
+	service, _ := newWebAuthnService()
+	ctx := context.Background()
+
+	userID, _ := registerPasskey(t, service)
+	service.SetMFARequired(ctx, userID, true)
+
+	// A passkey satisfies the MFA requirement
+	result, err := service.Login(ctx, "test@example.com", "password123")
+	if err != nil || result.MFAToken == "" {
+		t.Fatalf("Login() = %+v, %v; want an MFA token", result, err)
+	}
+
+	// and cannot be removed when it is the last second factor
+	credentials, _ := service.ListWebAuthnCredentials(ctx, userID)
+	if err := service.DeleteWebAuthnCredential(ctx, userID, credentials[0].ID); !errors.Is(err, userUseCase.ErrMFARequired) {
+		t.Errorf("DeleteWebAuthnCredential() error = %v, want %v", err, userUseCase.ErrMFARequired)
+	}
+
+	service.SetMFARequired(ctx, userID, false)
+	if err := service.DeleteWebAuthnCredential(ctx, userID, credentials[0].ID); err != nil {
+		t.Fatalf("DeleteWebAuthnCredential() unexpected error = %v", err)
+	}
+	if err := service.DeleteWebAuthnCredential(ctx, userID, credentials[0].ID); !errors.Is(err, userUseCase.ErrPasskeyNotFound) {
+		t.Errorf("DeleteWebAuthnCredential() twice error = %v, want %v", err, userUseCase.ErrPasskeyNotFound)
+	}
+}
+
+func TestService_WebAuthn_Disabled(t *testing.T) {
+	service, _ := newAuditedService()
+
+	if _, err := service.BeginWebAuthnLogin(context.Background(), ""); !errors.Is(err, userUseCase.ErrWebAuthnDisabled) {
+		t.Errorf("BeginWebAuthnLogin() error = %v, want %v", err, userUseCase.ErrWebAuthnDisabled)
+	}
+}
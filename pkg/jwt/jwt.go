@@ -5,56 +5,188 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID        string `json:"user_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified,omitempty"`
+	Role          string `json:"role,omitempty"`
+	// SessionID names the login session the token was issued for
+	SessionID string `json:"sid,omitempty"`
+	// Purpose restricts a token to a single flow such as an MFA challenge.
+	// Access tokens have no purpose.
+	Purpose string `json:"purpose,omitempty"`
+	// Actor is set on impersonation tokens and names the user really
+	// making the requests, like the act claim of RFC 8693
+	Actor *Actor `json:"act,omitempty"`
+	// IssuedAtNanos is the issue time in nanoseconds since the epoch. iat
+	// only has second precision, which cannot tell a token issued right
+	// after RevokeAllForUser from one issued just before it.
+	IssuedAtNanos int64 `json:"iat_ns,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor identifies who is acting on behalf of the token's user
+type Actor struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+}
+
+// IsImpersonation reports whether the token was issued to someone acting
+// as its user
+func (c *Claims) IsImpersonation() bool {
+	return c.Actor != nil
+}
+
+// RealUserID returns the user behind the token: the actor when
+// impersonating, the token's user otherwise
+func (c *Claims) RealUserID() string {
+	if c.Actor != nil {
+		return c.Actor.UserID
+	}
+	return c.UserID
+}
+
+// issuedAt returns when the token was issued, as precisely as it records
+func (c *Claims) issuedAt() time.Time {
+	if c.IssuedAtNanos != 0 {
+		return time.Unix(0, c.IssuedAtNanos)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
 // Service handles JWT operations
 type Service struct {
-	secretKey     string
+	keys          *KeyRing
 	tokenDuration time.Duration
+	revocations   RevocationStore
+}
+
+// Option configures optional behaviour of the Service
+type Option func(*Service)
+
+// WithRevocationStore makes the service consult the store when validating tokens
+func WithRevocationStore(store RevocationStore) Option {
+	return func(s *Service) {
+		s.revocations = store
+	}
 }
 
 // NewService creates a new JWT service
-func NewService(secretKey string, tokenDuration time.Duration) *Service {
-	return &Service{
-		secretKey:     secretKey,
+func NewService(signer Signer, tokenDuration time.Duration, opts ...Option) *Service {
+	s := &Service{
+		keys:          NewKeyRing(signer),
 		tokenDuration: tokenDuration,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// TokenDuration returns the lifetime of the access tokens issued by the service
+func (s *Service) TokenDuration() time.Duration {
+	return s.tokenDuration
+}
+
+// RotateKey makes signer the active signing key. Tokens signed with the
+// previous key remain valid until the grace period elapses.
+func (s *Service) RotateKey(signer Signer, grace time.Duration) {
+	s.keys.Rotate(signer, grace)
 }
 
 // GenerateToken creates a new JWT token for a user
 func (s *Service) GenerateToken(userID, email string) (string, error) {
-	claims := Claims{
+	return s.GenerateAccessToken(Claims{
 		UserID: userID,
 		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	})
+}
+
+// GenerateAccessToken creates an access token carrying the given claims.
+// Registered claims such as jti, iat and exp are always set by the service.
+func (s *Service) GenerateAccessToken(claims Claims) (string, error) {
+	claims.Purpose = ""
+	return s.sign(claims, s.tokenDuration)
+}
+
+// GenerateImpersonationToken creates an access token for claims.UserID
+// carrying actor as the real user. ttl is capped at the access token
+// duration, so revoking all tokens of the actor covers it.
+func (s *Service) GenerateImpersonationToken(claims Claims, actor Actor, ttl time.Duration) (string, error) {
+	if actor.UserID == "" || actor.UserID == claims.UserID {
+		return "", ErrInvalidToken
+	}
+	if ttl <= 0 || ttl > s.tokenDuration {
+		ttl = s.tokenDuration
 	}
+	claims.Purpose = ""
+	claims.Actor = &actor
+	return s.sign(claims, ttl)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+// GeneratePurposeToken creates a short-lived token that is only accepted by
+// ValidatePurposeToken with the same purpose, never as an access token
+func (s *Service) GeneratePurposeToken(userID, email, purpose string, ttl time.Duration) (string, error) {
+	return s.sign(Claims{
+		UserID:  userID,
+		Email:   email,
+		Purpose: purpose,
+	}, ttl)
 }
 
-// ValidateToken validates and parses a JWT token
+func (s *Service) sign(claims Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.IssuedAtNanos = now.UnixNano()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	signer := s.keys.Active()
+	token := jwt.NewWithClaims(signer.Method(), claims)
+	token.Header["kid"] = signer.KeyID()
+	return token.SignedString(signer.SigningKey())
+}
+
+// ValidateToken validates and parses a JWT access token
 func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
+	return s.validate(tokenString, "")
+}
+
+// ValidatePurposeToken validates a token created by GeneratePurposeToken
+func (s *Service) ValidatePurposeToken(tokenString, purpose string) (*Claims, error) {
+	if purpose == "" {
+		return nil, ErrInvalidToken
+	}
+	return s.validate(tokenString, purpose)
+}
+
+func (s *Service) validate(tokenString, purpose string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		signer, err := s.keys.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		// Only accept the key's own algorithm to rule out algorithm confusion
+		if token.Method.Alg() != signer.Method().Alg() {
 			return nil, ErrInvalidToken
 		}
-		return []byte(s.secretKey), nil
+		return signer.VerificationKey(), nil
 	})
 
 	if err != nil {
@@ -66,9 +198,53 @@ func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	if claims.Purpose != purpose {
+		return nil, ErrInvalidToken
+	}
+
 	if claims.ExpiresAt.Time.Before(time.Now()) {
 		return nil, ErrExpiredToken
 	}
 
+	if s.revocations != nil {
+		issuedAt := claims.issuedAt()
+		revoked, err := s.revocations.IsRevoked(claims.ID, claims.UserID, issuedAt)
+		if err != nil {
+			return nil, err
+		}
+		// Signing the actor out everywhere also ends their impersonations
+		if !revoked && claims.Actor != nil {
+			revoked, err = s.revocations.IsRevoked(claims.ID, claims.Actor.UserID, issuedAt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
 	return claims, nil
 }
+
+// RevokeToken invalidates a single token until it expires
+func (s *Service) RevokeToken(claims *Claims) error {
+	if s.revocations == nil {
+		return nil
+	}
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return ErrInvalidToken
+	}
+	return s.revocations.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeAllForUser invalidates every token issued to the user up to now.
+// Tokens without IssuedAtNanos only record the second they were issued
+// in, so those issued within the second of the revocation are revoked too.
+func (s *Service) RevokeAllForUser(userID string) error {
+	if s.revocations == nil {
+		return nil
+	}
+	now := time.Now()
+	return s.revocations.RevokeUser(userID, now, now.Add(s.tokenDuration))
+}
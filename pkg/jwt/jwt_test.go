@@ -8,7 +8,7 @@ import (
 )
 
 func TestGenerateToken(t *testing.T) {
-	service := jwt.NewService("test-secret-key", 24*time.Hour)
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour)
 
 	token, err := service.GenerateToken("user123", "test@example.com")
 	if err != nil {
@@ -21,7 +21,7 @@ func TestGenerateToken(t *testing.T) {
 }
 
 func TestValidateToken_ValidToken(t *testing.T) {
-	service := jwt.NewService("test-secret-key", 24*time.Hour)
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour)
 
 	token, _ := service.GenerateToken("user123", "test@example.com")
 
@@ -40,7 +40,7 @@ func TestValidateToken_ValidToken(t *testing.T) {
 }
 
 func TestValidateToken_InvalidToken(t *testing.T) {
-	service := jwt.NewService("test-secret-key", 24*time.Hour)
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour)
 
 	_, err := service.ValidateToken("invalid-token")
 	if err == nil {
@@ -49,7 +49,7 @@ func TestValidateToken_InvalidToken(t *testing.T) {
 }
 
 func TestValidateToken_ExpiredToken(t *testing.T) {
-	service := jwt.NewService("test-secret-key", -1*time.Hour)
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), -1*time.Hour)
 
 	token, _ := service.GenerateToken("user123", "test@example.com")
 
@@ -58,3 +58,150 @@ func TestValidateToken_ExpiredToken(t *testing.T) {
 		t.Error("ValidateToken() expected error for expired token")
 	}
 }
+
+func TestGenerateToken_SetsUniqueTokenID(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour)
+
+	first, _ := service.GenerateToken("user123", "test@example.com")
+	second, _ := service.GenerateToken("user123", "test@example.com")
+
+	firstClaims, _ := service.ValidateToken(first)
+	secondClaims, _ := service.ValidateToken(second)
+
+	if firstClaims.ID == "" {
+		t.Fatal("GenerateToken() should set a jti claim")
+	}
+
+	if firstClaims.ID == secondClaims.ID {
+		t.Error("GenerateToken() should set a unique jti per token")
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+
+	token, _ := service.GenerateToken("user123", "test@example.com")
+	other, _ := service.GenerateToken("user123", "test@example.com")
+	claims, _ := service.ValidateToken(token)
+
+	if err := service.RevokeToken(claims); err != nil {
+		t.Fatalf("RevokeToken() unexpected error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(token); err != jwt.ErrRevokedToken {
+		t.Errorf("ValidateToken() expected ErrRevokedToken, got %v", err)
+	}
+
+	if _, err := service.ValidateToken(other); err != nil {
+		t.Errorf("ValidateToken() other token unexpected error = %v", err)
+	}
+}
+
+func TestRevokeAllForUser(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+
+	token, _ := service.GenerateToken("user123", "test@example.com")
+	otherUser, _ := service.GenerateToken("user456", "other@example.com")
+
+	if err := service.RevokeAllForUser("user123"); err != nil {
+		t.Fatalf("RevokeAllForUser() unexpected error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(token); err != jwt.ErrRevokedToken {
+		t.Errorf("ValidateToken() expected ErrRevokedToken, got %v", err)
+	}
+
+	if _, err := service.ValidateToken(otherUser); err != nil {
+		t.Errorf("ValidateToken() other user's token unexpected error = %v", err)
+	}
+}
+
+func TestRevokeAllForUser_ReissueInSameSecond(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+
+	before, _ := service.GenerateToken("user123", "test@example.com")
+	if err := service.RevokeAllForUser("user123"); err != nil {
+		t.Fatalf("RevokeAllForUser() unexpected error = %v", err)
+	}
+	// Signing in again at once, most likely within the same second
+	after, _ := service.GenerateToken("user123", "test@example.com")
+
+	if _, err := service.ValidateToken(before); err != jwt.ErrRevokedToken {
+		t.Errorf("ValidateToken() token issued before error = %v, want %v", err, jwt.ErrRevokedToken)
+	}
+	if _, err := service.ValidateToken(after); err != nil {
+		t.Errorf("ValidateToken() token issued after unexpected error = %v", err)
+	}
+}
+
+func TestPurposeToken(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), 24*time.Hour)
+
+	token, err := service.GeneratePurposeToken("user123", "test@example.com", "mfa", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GeneratePurposeToken() unexpected error = %v", err)
+	}
+
+	claims, err := service.ValidatePurposeToken(token, "mfa")
+	if err != nil {
+		t.Fatalf("ValidatePurposeToken() unexpected error = %v", err)
+	}
+	if claims.UserID != "user123" || claims.Purpose != "mfa" {
+		t.Errorf("ValidatePurposeToken() claims = %+v", claims)
+	}
+
+	if _, err := service.ValidatePurposeToken(token, "other"); err != jwt.ErrInvalidToken {
+		t.Errorf("ValidatePurposeToken() with another purpose expected ErrInvalidToken, got %v", err)
+	}
+
+	if _, err := service.ValidateToken(token); err != jwt.ErrInvalidToken {
+		t.Errorf("ValidateToken() must reject purpose tokens, got %v", err)
+	}
+
+	access, _ := service.GenerateToken("user123", "test@example.com")
+	if _, err := service.ValidatePurposeToken(access, "mfa"); err != jwt.ErrInvalidToken {
+		t.Errorf("ValidatePurposeToken() must reject access tokens, got %v", err)
+	}
+}
+
+func TestImpersonationToken(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), time.Hour,
+		jwt.WithRevocationStore(jwt.NewInMemoryRevocationStore()),
+	)
+
+	token, err := service.GenerateImpersonationToken(
+		jwt.Claims{UserID: "merchant", Role: "merchant_owner"},
+		jwt.Actor{UserID: "admin", Email: "admin@example.com"},
+		24*time.Hour,
+	)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken() unexpected error = %v", err)
+	}
+
+	claims, err := service.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() unexpected error = %v", err)
+	}
+	if !claims.IsImpersonation() || claims.UserID != "merchant" || claims.RealUserID() != "admin" {
+		t.Errorf("ValidateToken() claims = %+v, actor %+v", claims, claims.Actor)
+	}
+	if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime > time.Hour {
+		t.Errorf("impersonation token lifetime = %v, should be capped at the access token duration", lifetime)
+	}
+
+	if _, err := service.GenerateImpersonationToken(jwt.Claims{UserID: "admin"}, jwt.Actor{UserID: "admin"}, time.Minute); err != jwt.ErrInvalidToken {
+		t.Errorf("GenerateImpersonationToken() of oneself expected ErrInvalidToken, got %v", err)
+	}
+
+	// Signing the actor out everywhere ends the impersonation
+	service.RevokeAllForUser("admin")
+	if _, err := service.ValidateToken(token); err != jwt.ErrRevokedToken {
+		t.Errorf("ValidateToken() after revoking the actor expected ErrRevokedToken, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package jwt
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+type retiredKey struct {
+	signer Signer
+	until  time.Time
+}
+
+// KeyRing holds the active signing key together with retired keys that keep
+// verifying already issued tokens until their grace deadline
+type KeyRing struct {
+	active  Signer
+	retired map[string]retiredKey
+	mu      sync.RWMutex
+}
+
+// NewKeyRing creates a key ring with the given active key
+func NewKeyRing(active Signer) *KeyRing {
+	return &KeyRing{
+		active:  active,
+		retired: make(map[string]retiredKey),
+	}
+}
+
+// Active returns the key used to sign new tokens
+func (k *KeyRing) Active() Signer {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// Rotate makes next the active key. The previous key keeps verifying tokens
+// for the grace period. Rotating to the key that is already active is a no-op.
+func (k *KeyRing) Rotate(next Signer, grace time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if next.KeyID() == k.active.KeyID() {
+		return
+	}
+
+	now := time.Now()
+	k.retired[k.active.KeyID()] = retiredKey{signer: k.active, until: now.Add(grace)}
+	delete(k.retired, next.KeyID())
+	k.active = next
+
+	for kid, key := range k.retired {
+		if !now.Before(key.until) {
+			delete(k.retired, kid)
+		}
+	}
+}
+
+// Lookup returns the key with the given ID if it may still verify tokens.
+// Tokens without a kid header predate key rotation and use the active key.
+func (k *KeyRing) Lookup(kid string) (Signer, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if kid == "" || kid == k.active.KeyID() {
+		return k.active, nil
+	}
+
+	key, ok := k.retired[kid]
+	if !ok || !time.Now().Before(key.until) {
+		return nil, ErrUnknownKey
+	}
+	return key.signer, nil
+}
+
+// VerificationKeys returns the active key followed by every retired key
+// still within its grace period
+func (k *KeyRing) VerificationKeys() []Signer {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	keys := []Signer{k.active}
+	for _, key := range k.retired {
+		if now.Before(key.until) {
+			keys = append(keys, key.signer)
+		}
+	}
+	return keys
+}
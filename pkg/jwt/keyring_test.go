@@ -0,0 +1,89 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func TestRotateKey_OldTokensValidDuringGracePeriod(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("old-secret"), time.Hour)
+	oldToken, _ := service.GenerateToken("user123", "test@example.com")
+
+	service.RotateKey(jwt.NewHMACSigner("new-secret"), time.Hour)
+	newToken, _ := service.GenerateToken("user123", "test@example.com")
+
+	if _, err := service.ValidateToken(oldToken); err != nil {
+		t.Errorf("ValidateToken() old token unexpected error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(newToken); err != nil {
+		t.Errorf("ValidateToken() new token unexpected error = %v", err)
+	}
+
+	// A service that only knows the new key proves what signed the new token
+	newOnly := jwt.NewService(jwt.NewHMACSigner("new-secret"), time.Hour)
+	if _, err := newOnly.ValidateToken(newToken); err != nil {
+		t.Errorf("new tokens should be signed with the new key, got %v", err)
+	}
+}
+
+func TestRotateKey_OldTokensRejectedAfterGracePeriod(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("old-secret"), time.Hour)
+	oldToken, _ := service.GenerateToken("user123", "test@example.com")
+
+	service.RotateKey(jwt.NewHMACSigner("new-secret"), 0)
+
+	_, err := service.ValidateToken(oldToken)
+	if !errors.Is(err, jwt.ErrUnknownKey) {
+		t.Errorf("ValidateToken() expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestRotateKey_SameKeyIsNoop(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("secret"), time.Hour)
+	token, _ := service.GenerateToken("user123", "test@example.com")
+
+	service.RotateKey(jwt.NewHMACSigner("secret"), 0)
+
+	if _, err := service.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken() unexpected error after no-op rotation = %v", err)
+	}
+}
+
+func TestRotateKey_JWKSIncludesRetiredKeys(t *testing.T) {
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	oldSigner, _ := jwt.NewECDSASigner(oldKey)
+	newSigner, _ := jwt.NewECDSASigner(newKey)
+
+	service := jwt.NewService(oldSigner, time.Hour)
+	service.RotateKey(newSigner, time.Hour)
+
+	kids := map[string]bool{}
+	for _, key := range service.JWKS().Keys {
+		kids[key.Kid] = true
+	}
+
+	if !kids[oldSigner.KeyID()] || !kids[newSigner.KeyID()] {
+		t.Errorf("JWKS() kids = %v, want both %s and %s", kids, oldSigner.KeyID(), newSigner.KeyID())
+	}
+}
+
+func TestKeyRing_LookupUnknownKey(t *testing.T) {
+	ring := jwt.NewKeyRing(jwt.NewHMACSigner("secret"))
+
+	if _, err := ring.Lookup("does-not-exist"); err != jwt.ErrUnknownKey {
+		t.Errorf("Lookup() expected ErrUnknownKey, got %v", err)
+	}
+
+	// Tokens minted before kid headers existed fall back to the active key
+	if signer, err := ring.Lookup(""); err != nil || signer != ring.Active() {
+		t.Errorf("Lookup(\"\") = %v, %v; want active key", signer, err)
+	}
+}
@@ -0,0 +1,109 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultSweepInterval is how often the in-memory store drops expired entries
+const defaultSweepInterval = time.Minute
+
+// RevocationStore keeps track of tokens that were invalidated before their expiry
+type RevocationStore interface {
+	// RevokeToken revokes a single token until its expiry
+	RevokeToken(tokenID string, expiresAt time.Time) error
+	// RevokeUser revokes every token issued to the user before issuedBefore.
+	// The entry is kept until expiresAt, when all such tokens have expired.
+	RevokeUser(userID string, issuedBefore, expiresAt time.Time) error
+	// IsRevoked reports whether a token has been revoked
+	IsRevoked(tokenID, userID string, issuedAt time.Time) (bool, error)
+}
+
+type userRevocation struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// InMemoryRevocationStore implements RevocationStore using in-memory storage.
+// Entries are garbage-collected once the tokens they cover have expired.
+type InMemoryRevocationStore struct {
+	tokens        map[string]time.Time
+	users         map[string]userRevocation
+	sweepInterval time.Duration
+	lastSweep     time.Time
+	mu            sync.RWMutex
+}
+
+// NewInMemoryRevocationStore creates a new in-memory revocation store
+func NewInMemoryRevocationStore() *InMemoryRevocationStore {
+	return &InMemoryRevocationStore{
+		tokens:        make(map[string]time.Time),
+		users:         make(map[string]userRevocation),
+		sweepInterval: defaultSweepInterval,
+		lastSweep:     time.Now(),
+	}
+}
+
+// RevokeToken revokes a single token until its expiry
+func (s *InMemoryRevocationStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[tokenID] = expiresAt
+	s.sweepLocked(time.Now())
+	return nil
+}
+
+// RevokeUser revokes every token issued to the user before issuedBefore
+func (s *InMemoryRevocationStore) RevokeUser(userID string, issuedBefore, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Never move an existing cutoff backwards
+	if existing, ok := s.users[userID]; ok {
+		if existing.issuedBefore.After(issuedBefore) {
+			issuedBefore = existing.issuedBefore
+		}
+		if existing.expiresAt.After(expiresAt) {
+			expiresAt = existing.expiresAt
+		}
+	}
+
+	s.users[userID] = userRevocation{issuedBefore: issuedBefore, expiresAt: expiresAt}
+	s.sweepLocked(time.Now())
+	return nil
+}
+
+// IsRevoked reports whether a token has been revoked
+func (s *InMemoryRevocationStore) IsRevoked(tokenID, userID string, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	if expiresAt, ok := s.tokens[tokenID]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+	if rev, ok := s.users[userID]; ok && now.Before(rev.expiresAt) && issuedAt.Before(rev.issuedBefore) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// sweepLocked drops expired entries; the caller must hold the write lock
+func (s *InMemoryRevocationStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < s.sweepInterval {
+		return
+	}
+
+	for id, expiresAt := range s.tokens {
+		if !now.Before(expiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	for id, rev := range s.users {
+		if !now.Before(rev.expiresAt) {
+			delete(s.users, id)
+		}
+	}
+	s.lastSweep = now
+}
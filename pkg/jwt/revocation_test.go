@@ -0,0 +1,61 @@
+package jwt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func TestInMemoryRevocationStore_RevokeToken(t *testing.T) {
+	store := jwt.NewInMemoryRevocationStore()
+
+	_ = store.RevokeToken("token123", time.Now().Add(time.Hour))
+
+	revoked, err := store.IsRevoked("token123", "user123", time.Now())
+	if err != nil || !revoked {
+		t.Errorf("IsRevoked() = %v, %v; want true, nil", revoked, err)
+	}
+
+	revoked, _ = store.IsRevoked("token456", "user123", time.Now())
+	if revoked {
+		t.Error("IsRevoked() should be false for other tokens")
+	}
+}
+
+func TestInMemoryRevocationStore_ExpiredEntriesAreIgnored(t *testing.T) {
+	store := jwt.NewInMemoryRevocationStore()
+
+	_ = store.RevokeToken("token123", time.Now().Add(-time.Second))
+	_ = store.RevokeUser("user123", time.Now(), time.Now().Add(-time.Second))
+
+	revoked, _ := store.IsRevoked("token123", "user123", time.Now().Add(-time.Hour))
+	if revoked {
+		t.Error("IsRevoked() should ignore expired entries")
+	}
+}
+
+func TestInMemoryRevocationStore_RevokeUser(t *testing.T) {
+	store := jwt.NewInMemoryRevocationStore()
+	cutoff := time.Now()
+
+	_ = store.RevokeUser("user123", cutoff, cutoff.Add(time.Hour))
+
+	revoked, _ := store.IsRevoked("token123", "user123", cutoff.Add(-time.Minute))
+	if !revoked {
+		t.Error("IsRevoked() should be true for tokens issued before the cutoff")
+	}
+
+	revoked, _ = store.IsRevoked("token123", "user123", cutoff.Add(time.Minute))
+	if revoked {
+		t.Error("IsRevoked() should be false for tokens issued after the cutoff")
+	}
+
+	// An earlier cutoff must not weaken an existing one
+	_ = store.RevokeUser("user123", cutoff.Add(-time.Hour), cutoff.Add(time.Hour))
+
+	revoked, _ = store.IsRevoked("token123", "user123", cutoff.Add(-time.Minute))
+	if !revoked {
+		t.Error("RevokeUser() should never move the cutoff backwards")
+	}
+}
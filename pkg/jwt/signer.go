@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrInvalidKey           = errors.New("invalid signing key")
+)
+
+// Signer holds the key material used to sign and verify tokens
+type Signer interface {
+	// Method returns the JWT signing method implemented by the key
+	Method() jwt.SigningMethod
+	// SigningKey returns the key passed to the signing method
+	SigningKey() interface{}
+	// VerificationKey returns the key used to verify signatures
+	VerificationKey() interface{}
+	// KeyID identifies the key in token headers and the JWKS
+	KeyID() string
+	// PublicKey returns the public half of asymmetric keys, or nil for shared secrets
+	PublicKey() crypto.PublicKey
+}
+
+type keySigner struct {
+	method     jwt.SigningMethod
+	signingKey interface{}
+	verifyKey  interface{}
+	publicKey  crypto.PublicKey
+	keyID      string
+}
+
+func (k *keySigner) Method() jwt.SigningMethod    { return k.method }
+func (k *keySigner) SigningKey() interface{}      { return k.signingKey }
+func (k *keySigner) VerificationKey() interface{} { return k.verifyKey }
+func (k *keySigner) KeyID() string                { return k.keyID }
+func (k *keySigner) PublicKey() crypto.PublicKey  { return k.publicKey }
+
+// NewHMACSigner creates an HS256 signer from a shared secret. Intended for
+// local development, since every verifier must also hold the secret.
+func NewHMACSigner(secret string) Signer {
+	return &keySigner{
+		method:     jwt.SigningMethodHS256,
+		signingKey: []byte(secret),
+		verifyKey:  []byte(secret),
+		keyID:      hmacKeyID(secret),
+	}
+}
+
+// hmacKeyID derives a stable key ID from a secret without revealing it
+func hmacKeyID(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("jwt-key-id"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// NewRSASigner creates an RS256 signer
+func NewRSASigner(key *rsa.PrivateKey) (Signer, error) {
+	return newAsymmetricSigner(jwt.SigningMethodRS256, key, &key.PublicKey)
+}
+
+// NewECDSASigner creates an ES256 signer; the key must be on the P-256 curve
+func NewECDSASigner(key *ecdsa.PrivateKey) (Signer, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: ES256 requires a P-256 key", ErrInvalidKey)
+	}
+	return newAsymmetricSigner(jwt.SigningMethodES256, key, &key.PublicKey)
+}
+
+// NewEdDSASigner creates an EdDSA signer using an Ed25519 key
+func NewEdDSASigner(key ed25519.PrivateKey) (Signer, error) {
+	return newAsymmetricSigner(jwt.SigningMethodEdDSA, key, key.Public())
+}
+
+func newAsymmetricSigner(method jwt.SigningMethod, private crypto.PrivateKey, public crypto.PublicKey) (Signer, error) {
+	jwk, err := publicJWK(public)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := jwk.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return &keySigner{
+		method:     method,
+		signingKey: private,
+		verifyKey:  public,
+		publicKey:  public,
+		keyID:      kid,
+	}, nil
+}
+
+// LoadSignerFromPEM reads a PEM encoded private key from path and creates a
+// signer for the given algorithm. PKCS#8, PKCS#1 (RSA) and SEC 1 (EC) keys
+// are accepted.
+func LoadSignerFromPEM(algorithm, path string) (Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case AlgRS256:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires an RSA key", ErrInvalidKey, algorithm)
+		}
+		return NewRSASigner(rsaKey)
+	case AlgES256:
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires an ECDSA key", ErrInvalidKey, algorithm)
+		}
+		return NewECDSASigner(ecKey)
+	case AlgEdDSA:
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires an Ed25519 key", ErrInvalidKey, algorithm)
+		}
+		return NewEdDSASigner(edKey)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrInvalidKey)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM block %q", ErrInvalidKey, block.Type)
+	}
+}
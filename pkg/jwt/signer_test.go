@@ -0,0 +1,158 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/jwt"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func pkcs8(t *testing.T, key interface{}) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return der
+}
+
+func TestLoadSignerFromPEM(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		blockType string
+		der       []byte
+		kty       string
+	}{
+		{name: "RS256 PKCS#8", algorithm: jwt.AlgRS256, blockType: "PRIVATE KEY", der: pkcs8(t, rsaKey), kty: "RSA"},
+		{name: "RS256 PKCS#1", algorithm: jwt.AlgRS256, blockType: "RSA PRIVATE KEY", der: x509.MarshalPKCS1PrivateKey(rsaKey), kty: "RSA"},
+		{name: "ES256 PKCS#8", algorithm: jwt.AlgES256, blockType: "PRIVATE KEY", der: pkcs8(t, ecKey), kty: "EC"},
+		{name: "ES256 SEC 1", algorithm: jwt.AlgES256, blockType: "EC PRIVATE KEY", der: ecDER, kty: "EC"},
+		{name: "EdDSA PKCS#8", algorithm: jwt.AlgEdDSA, blockType: "PRIVATE KEY", der: pkcs8(t, edKey), kty: "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := jwt.LoadSignerFromPEM(tt.algorithm, writePEM(t, tt.blockType, tt.der))
+			if err != nil {
+				t.Fatalf("LoadSignerFromPEM() unexpected error = %v", err)
+			}
+
+			service := jwt.NewService(signer, time.Hour)
+			token, err := service.GenerateToken("user123", "test@example.com")
+			if err != nil {
+				t.Fatalf("GenerateToken() unexpected error = %v", err)
+			}
+
+			claims, err := service.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken() unexpected error = %v", err)
+			}
+			if claims.UserID != "user123" {
+				t.Errorf("ValidateToken() UserID = %v, want user123", claims.UserID)
+			}
+
+			jwks := service.JWKS()
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("JWKS() returned %d keys, want 1", len(jwks.Keys))
+			}
+			key := jwks.Keys[0]
+			if key.Kty != tt.kty || key.Alg != tt.algorithm || key.Kid != signer.KeyID() || key.Kid == "" {
+				t.Errorf("JWKS() key = %+v, want kty %s, alg %s, kid %s", key, tt.kty, tt.algorithm, signer.KeyID())
+			}
+		})
+	}
+}
+
+func TestLoadSignerFromPEM_KeyTypeMismatch(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	_, err := jwt.LoadSignerFromPEM(jwt.AlgRS256, writePEM(t, "PRIVATE KEY", pkcs8(t, ecKey)))
+	if err == nil {
+		t.Error("LoadSignerFromPEM() expected error for an EC key used with RS256")
+	}
+}
+
+func TestLoadSignerFromPEM_UnsupportedAlgorithm(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	_, err := jwt.LoadSignerFromPEM("none", writePEM(t, "PRIVATE KEY", pkcs8(t, ecKey)))
+	if err == nil {
+		t.Error("LoadSignerFromPEM() expected error for an unsupported algorithm")
+	}
+}
+
+func TestNewECDSASigner_RejectsOtherCurves(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	if _, err := jwt.NewECDSASigner(key); err == nil {
+		t.Error("NewECDSASigner() expected error for a P-384 key")
+	}
+}
+
+func TestValidateToken_RejectsOtherAlgorithm(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := jwt.NewEdDSASigner(edKey)
+
+	hmacService := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), time.Hour)
+	edService := jwt.NewService(signer, time.Hour)
+
+	token, _ := hmacService.GenerateToken("user123", "test@example.com")
+
+	if _, err := edService.ValidateToken(token); err == nil {
+		t.Error("ValidateToken() expected error for a token signed with another algorithm")
+	}
+}
+
+func TestJWKS_EmptyForHMAC(t *testing.T) {
+	service := jwt.NewService(jwt.NewHMACSigner("test-secret-key"), time.Hour)
+
+	if keys := service.JWKS().Keys; len(keys) != 0 {
+		t.Errorf("JWKS() returned %d keys for a shared secret, want 0", len(keys))
+	}
+}
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// Example from RFC 7638, section 3.1
+	key := jwt.JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMs" +
+			"tn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91Cb" +
+			"OpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		t.Fatalf("Thumbprint() unexpected error = %v", err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Thumbprint() = %v, want NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+	}
+}
@@ -0,0 +1,148 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrLocked = errors.New("too many failed attempts")
+)
+
+// LockedError is returned while a key is backing off or locked out. It
+// matches ErrLocked with errors.Is.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", ErrLocked, e.RetryAfter.Round(time.Second))
+}
+
+// Is makes errors.Is(err, ErrLocked) report true
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Policy controls how failures are throttled. After each failure the key
+// must wait BaseDelay, doubling with every further failure up to MaxDelay.
+// Reaching MaxAttempts locks the key for LockoutDuration. Failures are
+// forgotten once Window passes without a new one.
+type Policy struct {
+	MaxAttempts     int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// DefaultPolicy returns the policy used for login attempts per account
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:     5,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		LockoutDuration: 15 * time.Minute,
+		Window:          15 * time.Minute,
+	}
+}
+
+// Entry is the throttling state of a single key
+type Entry struct {
+	Failures     int
+	BlockedUntil time.Time
+	Locked       bool
+}
+
+// Store persists throttling state. Implementations must drop entries once
+// their ttl has passed; a shared store lets several instances enforce the
+// same limits.
+type Store interface {
+	// Get returns the entry for key, or nil if there is none
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Update replaces the entry for key with the one fn derives from it,
+	// keeping it until the returned ttl has passed. fn gets a copy of the
+	// current entry, or nil if there is none. The read and the write must
+	// be atomic, so concurrent failures are all counted; a shared store may
+	// call fn again if it loses a race.
+	Update(ctx context.Context, key string, fn func(entry *Entry) (*Entry, time.Duration)) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Tracker counts failed attempts per key and enforces a Policy
+type Tracker struct {
+	store  Store
+	policy Policy
+}
+
+// NewTracker creates a tracker keeping its state in store
+func NewTracker(store Store, policy Policy) *Tracker {
+	return &Tracker{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Check returns a *LockedError if the key may not attempt again yet
+func (t *Tracker) Check(ctx context.Context, key string) error {
+	entry, err := t.store.Get(ctx, key)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	if wait := time.Until(entry.BlockedUntil); wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt for the key. It reports true when
+// this failure locked the key out.
+func (t *Tracker) RecordFailure(ctx context.Context, key string) (bool, error) {
+	locked := false
+	err := t.store.Update(ctx, key, func(entry *Entry) (*Entry, time.Duration) {
+		now := time.Now()
+		if entry == nil || (entry.Locked && !now.Before(entry.BlockedUntil)) {
+			// Start over once a lockout has been served
+			entry = &Entry{}
+		}
+
+		entry.Failures++
+		if entry.Failures >= t.policy.MaxAttempts {
+			entry.Locked = true
+			entry.BlockedUntil = now.Add(t.policy.LockoutDuration)
+		} else {
+			entry.BlockedUntil = now.Add(t.backoff(entry.Failures))
+		}
+		locked = entry.Locked
+
+		ttl := t.policy.Window
+		if wait := entry.BlockedUntil.Sub(now); wait > ttl {
+			ttl = wait
+		}
+		return entry, ttl
+	})
+	if err != nil {
+		return false, err
+	}
+	return locked, nil
+}
+
+// Reset forgets the failures of the key
+func (t *Tracker) Reset(ctx context.Context, key string) error {
+	return t.store.Delete(ctx, key)
+}
+
+// backoff returns the delay imposed after the given number of failures
+func (t *Tracker) backoff(failures int) time.Duration {
+	delay := t.policy.BaseDelay
+	for i := 1; i < failures && delay < t.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.policy.MaxDelay {
+		delay = t.policy.MaxDelay
+	}
+	return delay
+}
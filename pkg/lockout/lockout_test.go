@@ -0,0 +1,149 @@
+package lockout_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/lockout"
+)
+
+func newTracker() *lockout.Tracker {
+	return lockout.NewTracker(lockout.NewMemoryStore(), lockout.Policy{
+		MaxAttempts:     3,
+		BaseDelay:       time.Minute,
+		MaxDelay:        90 * time.Second,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	})
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Check() error = %v, want a *LockedError", err)
+	}
+	if !errors.Is(err, lockout.ErrLocked) {
+		t.Error("LockedError should match ErrLocked")
+	}
+	return locked.RetryAfter
+}
+
+func TestTracker_Backoff(t *testing.T) {
+	tracker := newTracker()
+	ctx := context.Background()
+
+	if err := tracker.Check(ctx, "key"); err != nil {
+		t.Fatalf("Check() unexpected error = %v", err)
+	}
+
+	tracker.RecordFailure(ctx, "key")
+	if wait := retryAfter(t, tracker.Check(ctx, "key")); wait <= 59*time.Second || wait > time.Minute {
+		t.Errorf("RetryAfter after 1 failure = %v, want about 1m", wait)
+	}
+
+	// The delay doubles but is capped by MaxDelay
+	tracker.RecordFailure(ctx, "key")
+	if wait := retryAfter(t, tracker.Check(ctx, "key")); wait <= 89*time.Second || wait > 90*time.Second {
+		t.Errorf("RetryAfter after 2 failures = %v, want about 90s", wait)
+	}
+
+	// Other keys are not affected
+	if err := tracker.Check(ctx, "other"); err != nil {
+		t.Errorf("Check() other key unexpected error = %v", err)
+	}
+}
+
+func TestTracker_Lockout(t *testing.T) {
+	tracker := newTracker()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		locked, err := tracker.RecordFailure(ctx, "key")
+		if err != nil {
+			t.Fatalf("RecordFailure() unexpected error = %v", err)
+		}
+		if locked != (i == 3) {
+			t.Errorf("RecordFailure() #%d locked = %v, want %v", i, locked, i == 3)
+		}
+	}
+
+	if wait := retryAfter(t, tracker.Check(ctx, "key")); wait <= 59*time.Minute {
+		t.Errorf("RetryAfter = %v, want about the lockout duration", wait)
+	}
+}
+
+func TestTracker_Reset(t *testing.T) {
+	tracker := newTracker()
+	ctx := context.Background()
+
+	tracker.RecordFailure(ctx, "key")
+	if err := tracker.Reset(ctx, "key"); err != nil {
+		t.Fatalf("Reset() unexpected error = %v", err)
+	}
+
+	if err := tracker.Check(ctx, "key"); err != nil {
+		t.Errorf("Check() after Reset() error = %v, want nil", err)
+	}
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	store := lockout.NewMemoryStore()
+	ctx := context.Background()
+
+	store.Update(ctx, "key", func(*lockout.Entry) (*lockout.Entry, time.Duration) {
+		return &lockout.Entry{Failures: 1}, 10 * time.Millisecond
+	})
+	if entry, _ := store.Get(ctx, "key"); entry == nil || entry.Failures != 1 {
+		t.Fatalf("Get() = %+v, want the stored entry", entry)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if entry, _ := store.Get(ctx, "key"); entry != nil {
+		t.Errorf("Get() after ttl = %+v, want nil", entry)
+	}
+	store.Update(ctx, "key", func(entry *lockout.Entry) (*lockout.Entry, time.Duration) {
+		if entry != nil {
+			t.Errorf("Update() after ttl got %+v, want nil", entry)
+		}
+		return &lockout.Entry{}, time.Minute
+	})
+}
+
+func TestTracker_ConcurrentFailures(t *testing.T) {
+	const attempts = 50
+	tracker := lockout.NewTracker(lockout.NewMemoryStore(), lockout.Policy{
+		MaxAttempts:     attempts,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	})
+	ctx := context.Background()
+
+	// Every failure must be counted, so exactly the last one locks the key
+	var wg sync.WaitGroup
+	var lockouts atomic.Int32
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if locked, err := tracker.RecordFailure(ctx, "key"); err != nil {
+				t.Errorf("RecordFailure() unexpected error = %v", err)
+			} else if locked {
+				lockouts.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := lockouts.Load(); n != 1 {
+		t.Errorf("RecordFailure() reported %d lockouts, want 1", n)
+	}
+	if retry := retryAfter(t, tracker.Check(ctx, "key")); retry <= 59*time.Minute {
+		t.Errorf("Check() RetryAfter = %v, want the lockout duration", retry)
+	}
+}
@@ -0,0 +1,87 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultSweepInterval is how often the in-memory store drops expired entries
+const defaultSweepInterval = time.Minute
+
+type memoryEntry struct {
+	entry     Entry
+	expiresAt time.Time
+}
+
+// MemoryStore implements Store using in-memory storage. State is local to
+// the process, so each instance enforces its own limits.
+type MemoryStore struct {
+	entries       map[string]memoryEntry
+	sweepInterval time.Duration
+	lastSweep     time.Time
+	mu            sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:       make(map[string]memoryEntry),
+		sweepInterval: defaultSweepInterval,
+		lastSweep:     time.Now(),
+	}
+}
+
+// Get returns a copy of the entry for key, or nil if there is none
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.getLocked(key, time.Now()), nil
+}
+
+// Update replaces the entry for key under the write lock
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(entry *Entry) (*Entry, time.Duration)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ttl := fn(s.getLocked(key, now))
+	s.entries[key] = memoryEntry{entry: *entry, expiresAt: now.Add(ttl)}
+	s.sweepLocked(now)
+	return nil
+}
+
+// Delete removes the entry for key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// getLocked returns a copy of the live entry for key, or nil; the caller
+// must hold the lock
+func (s *MemoryStore) getLocked(key string, now time.Time) *Entry {
+	stored, ok := s.entries[key]
+	if !ok || !now.Before(stored.expiresAt) {
+		return nil
+	}
+	entry := stored.entry
+	return &entry
+}
+
+// sweepLocked drops expired entries; the caller must hold the write lock
+func (s *MemoryStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < s.sweepInterval {
+		return
+	}
+
+	for key, stored := range s.entries {
+		if !now.Before(stored.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
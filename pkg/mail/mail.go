@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is an outgoing email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to a logger instead of sending them. Intended
+// for local development.
+type LogMailer struct {
+	logger *log.Logger
+	from   string
+}
+
+// NewLogMailer creates a mailer that logs every message
+func NewLogMailer(logger *log.Logger, from string) *LogMailer {
+	return &LogMailer{
+		logger: logger,
+		from:   from,
+	}
+}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Printf("mail from=%q to=%q subject=%q\n%s", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer appends messages to a file, one after another, so they can be
+// inspected or picked up by tests and local tooling
+type FileMailer struct {
+	path string
+	from string
+	mu   sync.Mutex
+}
+
+// NewFileMailer creates a mailer that appends messages to the file at path
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{
+		path: path,
+		from: from,
+	}
+}
+
+// Send appends the message to the file
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return writeMessage(f, m.from, msg)
+}
+
+func writeMessage(w io.Writer, from string, msg Message) error {
+	_, err := fmt.Fprintf(w, "Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), from, msg.To, msg.Subject, msg.Body)
+	return err
+}
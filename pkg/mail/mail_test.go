@@ -0,0 +1,45 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/mail"
+)
+
+func TestLogMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := mail.NewLogMailer(log.New(&buf, "", 0), "noreply@example.com")
+
+	err := mailer.Send(context.Background(), mail.Message{To: "john@example.com", Subject: "Hello", Body: "Body text"})
+	if err != nil {
+		t.Fatalf("Send() unexpected error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "john@example.com") || !strings.Contains(buf.String(), "Body text") {
+		t.Errorf("Send() logged %q, want recipient and body", buf.String())
+	}
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	mailer := mail.NewFileMailer(path, "noreply@example.com")
+
+	_ = mailer.Send(context.Background(), mail.Message{To: "john@example.com", Subject: "First", Body: "one"})
+	_ = mailer.Send(context.Background(), mail.Message{To: "jane@example.com", Subject: "Second", Body: "two"})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read mail file: %v", err)
+	}
+
+	content := string(data)
+	if !strings.Contains(content, "Subject: First") || !strings.Contains(content, "Subject: Second") {
+		t.Errorf("mail file = %q, want both messages", content)
+	}
+}
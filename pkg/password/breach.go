@@ -0,0 +1,91 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// hashPrefixLength is the length of the SHA-1 prefix used to bucket the
+// corpus, matching the Have I Been Pwned range API
+const hashPrefixLength = 5
+
+// BreachCorpus is a local set of breached password SHA-1 hashes, bucketed
+// by hash prefix like the k-anonymity range API it is usually exported
+// from. Passwords are never stored or compared in plain text.
+type BreachCorpus struct {
+	ranges map[string]map[string]struct{}
+	size   int
+}
+
+// LoadBreachCorpus reads a corpus file; see ParseBreachCorpus for the format
+func LoadBreachCorpus(path string) (*BreachCorpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseBreachCorpus(f)
+}
+
+// ParseBreachCorpus reads one uppercase or lowercase hex SHA-1 hash per
+// line, optionally followed by ":<count>" as in the Have I Been Pwned
+// downloads. Blank lines and lines starting with '#' are ignored.
+func ParseBreachCorpus(r io.Reader) (*BreachCorpus, error) {
+	c := &BreachCorpus{ranges: make(map[string]map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("breach corpus line %d: not a SHA-1 hash", line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("breach corpus line %d: not a SHA-1 hash", line)
+		}
+
+		c.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *BreachCorpus) add(hash string) {
+	prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+	suffixes, ok := c.ranges[prefix]
+	if !ok {
+		suffixes = make(map[string]struct{})
+		c.ranges[prefix] = suffixes
+	}
+	if _, ok := suffixes[suffix]; !ok {
+		suffixes[suffix] = struct{}{}
+		c.size++
+	}
+}
+
+// Len returns the number of distinct hashes in the corpus
+func (c *BreachCorpus) Len() int {
+	return c.size
+}
+
+// IsBreached reports whether the password's SHA-1 hash is in the corpus
+func (c *BreachCorpus) IsBreached(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, ok := c.ranges[hash[:hashPrefixLength]][hash[hashPrefixLength:]]
+	return ok
+}
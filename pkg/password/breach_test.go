@@ -0,0 +1,68 @@
+package password_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+)
+
+func TestParseBreachCorpus(t *testing.T) {
+	// SHA-1 of "password" with a count, and of "password123" in lowercase
+	corpus, err := password.ParseBreachCorpus(strings.NewReader(`# breached passwords
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824
+
+cbfdac6008f9cab4083784cbd1874f76618d2a97
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1
+`))
+	if err != nil {
+		t.Fatalf("ParseBreachCorpus() unexpected error = %v", err)
+	}
+
+	if corpus.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", corpus.Len())
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{password: "password", want: true},
+		{password: "password123", want: true},
+		{password: "Password", want: false},
+		{password: "correct horse battery staple", want: false},
+	}
+	for _, tt := range tests {
+		if got := corpus.IsBreached(tt.password); got != tt.want {
+			t.Errorf("IsBreached(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestParseBreachCorpus_InvalidLine(t *testing.T) {
+	_, err := password.ParseBreachCorpus(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nnot-a-hash\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ParseBreachCorpus() error = %v, want an error for line 2", err)
+	}
+}
+
+func TestLoadBreachCorpus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	corpus, err := password.LoadBreachCorpus(path)
+	if err != nil {
+		t.Fatalf("LoadBreachCorpus() unexpected error = %v", err)
+	}
+	if !corpus.IsBreached("password") {
+		t.Error("IsBreached() = false, want true")
+	}
+
+	if _, err := password.LoadBreachCorpus(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadBreachCorpus() expected error for missing file")
+	}
+}
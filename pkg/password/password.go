@@ -1,17 +1,147 @@
 package password
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Hash generates a bcrypt hash of the password
+var ErrInvalidParams = errors.New("invalid argon2id parameters")
+
+// Params are the Argon2id cost parameters. Raising them over time makes
+// existing hashes outdated; see NeedsRehash.
+type Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams returns the OWASP recommended minimum for Argon2id
+// (19 MiB, 2 iterations, 1 lane)
+func DefaultParams() Params {
+	return Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Validate reports whether the parameters are usable
+func (p Params) Validate() error {
+	if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 ||
+		p.SaltLength < 8 || p.KeyLength < 16 {
+		return ErrInvalidParams
+	}
+	return nil
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// Hash generates a PHC formatted Argon2id hash of the password using
+// DefaultParams
 func Hash(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	return HashWithParams(password, DefaultParams())
+}
+
+// HashWithParams generates a PHC formatted Argon2id hash of the password:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+func HashWithParams(password string, p Params) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
-// Verify checks if the provided password matches the hash
+// Verify checks if the provided password matches the hash. Both Argon2id
+// and legacy bcrypt hashes are accepted.
 func Verify(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	if isBcrypt(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	candidate := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return subtle.ConstantTimeCompare(key, candidate) == 1
+}
+
+// NeedsRehash reports whether a hash should be replaced by one produced
+// with the given parameters: legacy bcrypt hashes always do, Argon2id
+// hashes do when any of their parameters differ. Hashes that cannot be
+// parsed are left alone since they will not verify anyway.
+func NeedsRehash(hash string, p Params) bool {
+	if isBcrypt(hash) {
+		return true
+	}
+
+	current, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	return current != p
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+// decodeArgon2id parses a PHC formatted Argon2id hash. SaltLength and
+// KeyLength of the returned Params are taken from the encoded values.
+func decodeArgon2id(hash string) (Params, []byte, []byte, error) {
+	var p Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidParams
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidParams
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrInvalidParams
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidParams
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, ErrInvalidParams
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	if err := p.Validate(); err != nil {
+		return p, nil, nil, err
+	}
+	return p, salt, key, nil
 }
@@ -1,9 +1,12 @@
 package password_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHash(t *testing.T) {
@@ -40,3 +43,102 @@ func TestVerify_InvalidPassword(t *testing.T) {
 		t.Error("Verify() succeeded for invalid password")
 	}
 }
+
+func TestHash_Argon2idPHC(t *testing.T) {
+	hash, err := password.Hash("mysecretpassword")
+	if err != nil {
+		t.Fatalf("Hash() unexpected error = %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("Hash() = %q, want a PHC formatted argon2id hash", hash)
+	}
+
+	// Salts are random, so equal passwords hash differently
+	other, _ := password.Hash("mysecretpassword")
+	if hash == other {
+		t.Error("Hash() returned the same hash twice")
+	}
+}
+
+func TestHashWithParams_InvalidParams(t *testing.T) {
+	params := password.DefaultParams()
+	params.Iterations = 0
+
+	if _, err := password.HashWithParams("mysecretpassword", params); !errors.Is(err, password.ErrInvalidParams) {
+		t.Errorf("HashWithParams() error = %v, want %v", err, password.ErrInvalidParams)
+	}
+}
+
+func TestVerify_LongPassword(t *testing.T) {
+	// bcrypt ignores everything after 72 bytes; argon2id must not
+	prefix := strings.Repeat("a", 80)
+	hash, _ := password.Hash(prefix + "1")
+
+	if password.Verify(prefix+"2", hash) {
+		t.Error("Verify() succeeded for a password that differs after 72 bytes")
+	}
+	if !password.Verify(prefix+"1", hash) {
+		t.Error("Verify() failed for valid long password")
+	}
+}
+
+func TestVerify_LegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("mysecretpassword"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() unexpected error = %v", err)
+	}
+
+	if !password.Verify("mysecretpassword", string(legacy)) {
+		t.Error("Verify() failed for valid bcrypt hash")
+	}
+	if password.Verify("wrongpassword", string(legacy)) {
+		t.Error("Verify() succeeded for invalid password against bcrypt hash")
+	}
+}
+
+func TestVerify_MalformedHash(t *testing.T) {
+	hashes := []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=19456,t=2,p=1$not-base64!$abc",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+	}
+
+	for _, hash := range hashes {
+		if password.Verify("mysecretpassword", hash) {
+			t.Errorf("Verify() succeeded for malformed hash %q", hash)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	params := password.DefaultParams()
+	current, _ := password.HashWithParams("mysecretpassword", params)
+
+	stronger := params
+	stronger.Iterations++
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("mysecretpassword"), bcrypt.MinCost)
+
+	tests := []struct {
+		name   string
+		hash   string
+		params password.Params
+		want   bool
+	}{
+		{name: "current params", hash: current, params: params, want: false},
+		{name: "outdated params", hash: current, params: stronger, want: true},
+		{name: "legacy bcrypt", hash: string(legacy), params: params, want: true},
+		{name: "malformed", hash: "plaintext", params: params, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := password.NeedsRehash(tt.hash, tt.params); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrPolicyViolation = errors.New("password does not meet the policy")
+
+// Rule identifies a single password policy requirement
+type Rule string
+
+const (
+	RuleMinLength Rule = "min_length"
+	RuleMaxLength Rule = "max_length"
+	RuleUppercase Rule = "uppercase"
+	RuleLowercase Rule = "lowercase"
+	RuleDigit     Rule = "digit"
+	RuleSymbol    Rule = "symbol"
+	RuleIdentity  Rule = "not_identity"
+	RuleBreached  Rule = "not_breached"
+)
+
+// Violation describes a rule a password failed
+type Violation struct {
+	Rule    Rule   `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password failed. It matches
+// ErrPolicyViolation with errors.Is.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return ErrPolicyViolation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// BreachChecker reports whether a password appears in a known breach
+type BreachChecker interface {
+	IsBreached(password string) bool
+}
+
+// Policy describes the requirements for new passwords. Lengths are counted
+// in characters, not bytes. A zero MaxLength means no upper bound.
+type Policy struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	RejectIdentity bool
+	Breaches       BreachChecker
+}
+
+// DefaultPolicy follows NIST SP 800-63B: a length range and no composition
+// rules. Breached password checks are enabled by setting Breaches.
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:      8,
+		MaxLength:      128,
+		RejectIdentity: true,
+	}
+}
+
+// minIdentityLength keeps very short usernames from rejecting most passwords
+const minIdentityLength = 3
+
+// Check validates a password against the policy. identities are values the
+// password must not contain when RejectIdentity is set, typically the
+// username and email; for emails the local part is checked as well. The
+// returned error is a *PolicyError listing every failed rule.
+func (p Policy) Check(password string, identities ...string) error {
+	var violations []Violation
+	fail := func(rule Rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		fail(RuleMinLength, "must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		fail(RuleMaxLength, "must be at most %d characters", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		fail(RuleUppercase, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		fail(RuleLowercase, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		fail(RuleDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		fail(RuleSymbol, "must contain a symbol")
+	}
+
+	if p.RejectIdentity && containsIdentity(password, identities) {
+		fail(RuleIdentity, "must not contain your username or email")
+	}
+	if p.Breaches != nil && p.Breaches.IsBreached(password) {
+		fail(RuleBreached, "has appeared in a data breach")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsIdentity(password string, identities []string) bool {
+	lowered := strings.ToLower(password)
+	for _, identity := range identities {
+		identity = strings.ToLower(strings.TrimSpace(identity))
+		candidates := []string{identity}
+		if local, _, ok := strings.Cut(identity, "@"); ok {
+			candidates = append(candidates, local)
+		}
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) >= minIdentityLength && strings.Contains(lowered, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package password_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/password"
+)
+
+// breachList is a BreachChecker over a fixed set of passwords
+type breachList []string
+
+func (b breachList) IsBreached(pwd string) bool {
+	for _, breached := range b {
+		if pwd == breached {
+			return true
+		}
+	}
+	return false
+}
+
+func rules(err error) []password.Rule {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	var got []password.Rule
+	for _, v := range policyErr.Violations {
+		got = append(got, v.Rule)
+	}
+	return got
+}
+
+func TestPolicy_Check(t *testing.T) {
+	strict := password.Policy{
+		MinLength:      10,
+		MaxLength:      20,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		RejectIdentity: true,
+		Breaches:       breachList{"Tr0ub4dour&3x"},
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []password.Rule
+	}{
+		{name: "valid", password: "C0rrect-Horse", want: nil},
+		{name: "single character", password: "a", want: []password.Rule{
+			password.RuleMinLength, password.RuleUppercase, password.RuleDigit, password.RuleSymbol,
+		}},
+		{name: "too long", password: "C0rrect-Horse-Battery-Staple", want: []password.Rule{password.RuleMaxLength}},
+		{name: "no uppercase", password: "c0rrect-horse", want: []password.Rule{password.RuleUppercase}},
+		{name: "no lowercase", password: "C0RRECT-HORSE", want: []password.Rule{password.RuleLowercase}},
+		{name: "no digit", password: "Correct-Horse", want: []password.Rule{password.RuleDigit}},
+		{name: "no symbol", password: "C0rrectHorse", want: []password.Rule{password.RuleSymbol}},
+		{name: "contains username", password: "XjohnDoe!-9", want: []password.Rule{password.RuleIdentity}},
+		{name: "contains email local part", password: "Jdoe.Mail#42", want: []password.Rule{password.RuleIdentity}},
+		{name: "breached", password: "Tr0ub4dour&3x", want: []password.Rule{password.RuleBreached}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := strict.Check(tt.password, "johndoe", "jdoe.mail@example.com")
+			got := rules(err)
+
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Check() unexpected error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, password.ErrPolicyViolation) {
+				t.Fatalf("Check() error = %v, want %v", err, password.ErrPolicyViolation)
+			}
+			if strings.Join(ruleStrings(got), ",") != strings.Join(ruleStrings(tt.want), ",") {
+				t.Errorf("Check() violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ruleStrings(rules []password.Rule) []string {
+	s := make([]string, len(rules))
+	for i, r := range rules {
+		s[i] = string(r)
+	}
+	return s
+}
+
+func TestPolicy_Check_CountsCharacters(t *testing.T) {
+	policy := password.Policy{MinLength: 8}
+
+	// Eight characters, but many more bytes
+	if err := policy.Check("пароль日本"); err != nil {
+		t.Errorf("Check() unexpected error = %v", err)
+	}
+}
+
+func TestPolicy_Check_ShortIdentityIgnored(t *testing.T) {
+	policy := password.DefaultPolicy()
+
+	// A two letter username would otherwise reject most passwords
+	if err := policy.Check("longenough-passphrase", "ab", ""); err != nil {
+		t.Errorf("Check() unexpected error = %v", err)
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := password.DefaultPolicy()
+
+	if err := policy.Check("a"); !errors.Is(err, password.ErrPolicyViolation) {
+		t.Errorf("Check() error = %v, want %v", err, password.ErrPolicyViolation)
+	}
+	if err := policy.Check("correct horse battery staple"); err != nil {
+		t.Errorf("Check() unexpected error = %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package securetoken_test
+
+import (
+	"testing"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/securetoken"
+)
+
+func TestGenerate(t *testing.T) {
+	a, err := securetoken.Generate()
+	if err != nil {
+		t.Fatalf("Generate() unexpected error = %v", err)
+	}
+
+	b, _ := securetoken.Generate()
+	if a == "" || a == b {
+		t.Errorf("Generate() should return unique non-empty tokens, got %q and %q", a, b)
+	}
+}
+
+func TestHash(t *testing.T) {
+	token, _ := securetoken.Generate()
+
+	if securetoken.Hash(token) != securetoken.Hash(token) {
+		t.Error("Hash() should be deterministic")
+	}
+
+	if securetoken.Hash(token) == token {
+		t.Error("Hash() returned the plain token")
+	}
+}
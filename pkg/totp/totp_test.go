@@ -0,0 +1,76 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/totp"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 Appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := totp.Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code() unexpected error = %v", err)
+		}
+		if code != tt.want {
+			t.Errorf("Code(%d) = %v, want %v", tt.unix, code, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	now := time.Now()
+	code, _ := totp.Code(secret, now)
+
+	step, ok := totp.Validate(secret, code, now, 0)
+	if !ok {
+		t.Fatal("Validate() rejected a current code")
+	}
+
+	if _, ok := totp.Validate(secret, code, now, step); ok {
+		t.Error("Validate() accepted a replayed code")
+	}
+
+	if _, ok := totp.Validate(secret, code, now.Add(totp.Period), 0); !ok {
+		t.Error("Validate() should accept a code from the previous step")
+	}
+
+	if _, ok := totp.Validate(secret, code, now.Add(5*totp.Period), 0); ok {
+		t.Error("Validate() accepted a code outside the allowed skew")
+	}
+
+	if _, ok := totp.Validate(secret, "12345", now, 0); ok {
+		t.Error("Validate() accepted a code of the wrong length")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := totp.ProvisioningURI("Crypto Payment Gateway", "john@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Crypto%20Payment%20Gateway:john@example.com?") {
+		t.Errorf("ProvisioningURI() = %v, unexpected label", uri)
+	}
+
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Crypto+Payment+Gateway") {
+		t.Errorf("ProvisioningURI() = %v, missing secret or issuer", uri)
+	}
+}
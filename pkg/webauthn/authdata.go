@@ -0,0 +1,87 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagBackupEligible         = 0x08
+	flagBackedUp               = 0x10
+	flagAttestedCredentialData = 0x40
+	flagExtensionData          = 0x80
+)
+
+const (
+	rpIDHashLength      = 32
+	authDataMinLength   = rpIDHashLength + 1 + 4
+	aaguidLength        = 16
+	maxCredentialIDSize = 1023
+)
+
+// authenticatorData is the parsed authData structure signed by the
+// authenticator in both ceremonies
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+
+	// Only present in registrations
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (a *authenticatorData) userPresent() bool  { return a.flags&flagUserPresent != 0 }
+func (a *authenticatorData) userVerified() bool { return a.flags&flagUserVerified != 0 }
+
+// parseAuthenticatorData parses authData. Extension outputs are skipped.
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < authDataMinLength {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrInvalidResponse)
+	}
+
+	a := &authenticatorData{
+		rpIDHash:  data[:rpIDHashLength],
+		flags:     data[rpIDHashLength],
+		signCount: binary.BigEndian.Uint32(data[rpIDHashLength+1 : authDataMinLength]),
+	}
+	rest := data[authDataMinLength:]
+
+	if a.flags&flagAttestedCredentialData != 0 {
+		if len(rest) < aaguidLength+2 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidResponse)
+		}
+		a.aaguid = rest[:aaguidLength]
+		idLength := int(binary.BigEndian.Uint16(rest[aaguidLength : aaguidLength+2]))
+		rest = rest[aaguidLength+2:]
+		if idLength == 0 || idLength > maxCredentialIDSize || idLength > len(rest) {
+			return nil, fmt.Errorf("%w: invalid credential ID length", ErrInvalidResponse)
+		}
+		a.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		a.publicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if a.flags&flagExtensionData != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing authenticator data", ErrInvalidResponse)
+	}
+	return a, nil
+}
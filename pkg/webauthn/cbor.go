@@ -0,0 +1,160 @@
+package webauthn
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// maxCBORDepth bounds the nesting of decoded items. WebAuthn structures
+// are at most a few levels deep.
+const maxCBORDepth = 16
+
+// CBOR major types
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborSimple = 7
+)
+
+// decodeCBOR decodes the first CBOR item in data and returns it with the
+// number of bytes it took. Only the subset used by WebAuthn is supported:
+// integers, byte and text strings, arrays, maps with integer or text keys,
+// booleans and null. Indefinite lengths, tags and floats are rejected.
+// Integers decode to int64, maps to map[any]any.
+func decodeCBOR(data []byte) (any, int, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed CBOR: %v", ErrInvalidResponse, err)
+	}
+	return v, d.pos, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) remaining() uint64 {
+	return uint64(len(d.data) - d.pos)
+}
+
+// head reads the initial byte of an item and its argument
+func (d *cborDecoder) head() (major, info byte, arg uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, fmt.Errorf("unexpected end of data")
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info = b>>5, b&0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if d.remaining() < uint64(n) {
+			return 0, 0, 0, fmt.Errorf("unexpected end of data")
+		}
+		for _, c := range d.data[d.pos : d.pos+n] {
+			arg = arg<<8 | uint64(c)
+		}
+		d.pos += n
+		return major, info, arg, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("unsupported additional information %d", info)
+	}
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, fmt.Errorf("nesting too deep")
+	}
+
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("integer overflow")
+		}
+		return int64(arg), nil
+	case cborNegInt:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("integer overflow")
+		}
+		return -1 - int64(arg), nil
+	case cborBytes, cborText:
+		if arg > d.remaining() {
+			return nil, fmt.Errorf("string longer than data")
+		}
+		b := d.data[d.pos : d.pos+int(arg)]
+		d.pos += int(arg)
+		if major == cborText {
+			if !utf8.Valid(b) {
+				return nil, fmt.Errorf("invalid UTF-8 in text string")
+			}
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case cborArray:
+		// Every item takes at least one byte, which bounds the allocation
+		if arg > d.remaining() {
+			return nil, fmt.Errorf("array longer than data")
+		}
+		items := make([]any, 0, arg)
+		for range arg {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		if arg > d.remaining()/2 {
+			return nil, fmt.Errorf("map longer than data")
+		}
+		m := make(map[any]any, arg)
+		for range arg {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("unsupported map key type %T", key)
+			}
+			if _, dup := m[key]; dup {
+				return nil, fmt.Errorf("duplicate map key %v", key)
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case cborSimple:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unsupported simple value or float")
+	default:
+		return nil, fmt.Errorf("unsupported major type %d", major)
+	}
+}
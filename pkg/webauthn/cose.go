@@ -0,0 +1,147 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers of the supported credential keys
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms lists the accepted algorithms in order of preference
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters (RFC 9053)
+const (
+	coseKeyType  = 1
+	coseKeyAlg   = 3
+	coseKeyCurve = -1
+	coseKeyX     = -2
+	coseKeyY     = -3
+	coseKeyN     = -1
+	coseKeyE     = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	minRSAKeyBits = 2048
+)
+
+// publicKey is a parsed COSE credential public key
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey parses a COSE_Key holding an ES256, EdDSA or RS256 key
+func parsePublicKey(cose []byte) (*publicKey, error) {
+	v, n, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(cose) {
+		return nil, fmt.Errorf("%w: trailing data after COSE key", ErrInvalidResponse)
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: COSE key is not a map", ErrInvalidResponse)
+	}
+
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseKeyAlg)].(int64)
+	switch {
+	case alg == AlgES256 && kty == coseKeyTypeEC2:
+		return parseEC2Key(m)
+	case alg == AlgEdDSA && kty == coseKeyTypeOKP:
+		return parseOKPKey(m)
+	case alg == AlgRS256 && kty == coseKeyTypeRSA:
+		return parseRSAKey(m)
+	}
+	return nil, fmt.Errorf("%w: key type %d with algorithm %d", ErrUnsupportedAlgorithm, kty, alg)
+}
+
+func parseEC2Key(m map[any]any) (*publicKey, error) {
+	crv, _ := m[int64(coseKeyCurve)].(int64)
+	x, _ := m[int64(coseKeyX)].([]byte)
+	y, _ := m[int64(coseKeyY)].([]byte)
+	if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+		return nil, fmt.Errorf("%w: invalid P-256 key", ErrInvalidResponse)
+	}
+
+	// crypto/ecdh rejects points that are not on the curve
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("%w: invalid P-256 point", ErrInvalidResponse)
+	}
+	return &publicKey{alg: AlgES256, key: &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}}, nil
+}
+
+func parseOKPKey(m map[any]any) (*publicKey, error) {
+	crv, _ := m[int64(coseKeyCurve)].(int64)
+	x, _ := m[int64(coseKeyX)].([]byte)
+	if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid Ed25519 key", ErrInvalidResponse)
+	}
+	return &publicKey{alg: AlgEdDSA, key: ed25519.PublicKey(x)}, nil
+}
+
+func parseRSAKey(m map[any]any) (*publicKey, error) {
+	n, _ := m[int64(coseKeyN)].([]byte)
+	e, _ := m[int64(coseKeyE)].([]byte)
+	if len(n)*8 < minRSAKeyBits || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("%w: invalid RSA key", ErrInvalidResponse)
+	}
+	exponent := int(new(big.Int).SetBytes(e).Int64())
+	if exponent < 3 || exponent%2 == 0 {
+		return nil, fmt.Errorf("%w: invalid RSA exponent", ErrInvalidResponse)
+	}
+	return &publicKey{alg: AlgRS256, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}}, nil
+}
+
+// verify checks sig over data with the key
+func (k *publicKey) verify(data, sig []byte) error {
+	return verifySignature(k.alg, k.key, data, sig)
+}
+
+// verifySignature checks a signature made with the COSE algorithm alg
+func verifySignature(alg int64, key crypto.PublicKey, data, sig []byte) error {
+	var ok bool
+	switch alg {
+	case AlgES256:
+		k, isECDSA := key.(*ecdsa.PublicKey)
+		digest := sha256.Sum256(data)
+		ok = isECDSA && ecdsa.VerifyASN1(k, digest[:], sig)
+	case AlgEdDSA:
+		k, isEd25519 := key.(ed25519.PublicKey)
+		ok = isEd25519 && ed25519.Verify(k, data, sig)
+	case AlgRS256:
+		k, isRSA := key.(*rsa.PublicKey)
+		digest := sha256.Sum256(data)
+		ok = isRSA && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedAlgorithm, alg)
+	}
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies for passkeys and security
+// keys. Binary values cross the wire as unpadded base64url, matching the
+// JSON forms of the WebAuthn Level 3 browser API.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ChallengeSize is the length of generated challenges
+	ChallengeSize = 32
+	// minChallengeSize is the shortest challenge the spec allows
+	minChallengeSize = 16
+
+	credentialType = "public-key"
+
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+var (
+	ErrInvalidResponse        = errors.New("invalid WebAuthn response")
+	ErrChallengeMismatch      = errors.New("WebAuthn challenge does not match")
+	ErrOriginMismatch         = errors.New("WebAuthn origin is not allowed")
+	ErrRPIDMismatch           = errors.New("WebAuthn relying party ID does not match")
+	ErrUserNotPresent         = errors.New("user presence was not confirmed")
+	ErrUserNotVerified        = errors.New("user verification is required")
+	ErrUnsupportedAlgorithm   = errors.New("unsupported credential algorithm")
+	ErrUnsupportedAttestation = errors.New("unsupported attestation format")
+	ErrInvalidAttestation     = errors.New("invalid attestation statement")
+	ErrInvalidSignature       = errors.New("invalid WebAuthn signature")
+	ErrCredentialMismatch     = errors.New("response is for another credential")
+	ErrSignCountRegression    = errors.New("signature counter did not increase, the authenticator may be cloned")
+)
+
+// Bytes is binary data encoded as base64url in JSON. Padded input is
+// accepted too.
+type Bytes []byte
+
+// MarshalJSON encodes b as unpadded base64url
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes base64url, with or without padding
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("%w: invalid base64url", ErrInvalidResponse)
+	}
+	*b = decoded
+	return nil
+}
+
+// UserVerification states whether the authenticator must verify the user,
+// with a PIN or biometrics, on top of checking their presence
+type UserVerification string
+
+const (
+	UserVerificationRequired    UserVerification = "required"
+	UserVerificationPreferred   UserVerification = "preferred"
+	UserVerificationDiscouraged UserVerification = "discouraged"
+)
+
+// RelyingParty verifies ceremonies for one RP ID, the registrable domain
+// credentials are scoped to, reached from any of Origins
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+	// Timeout is the ceremony timeout suggested to browsers
+	Timeout time.Duration
+}
+
+// NewRelyingParty creates a relying party. Origins are full origins such as
+// "https://pay.example.com".
+func NewRelyingParty(id, name string, origins []string, timeout time.Duration) *RelyingParty {
+	return &RelyingParty{ID: id, Name: name, Origins: origins, Timeout: timeout}
+}
+
+// NewChallenge generates a random challenge
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// User is the account a credential is registered for. ID is the user
+// handle: opaque and free of personal data.
+type User struct {
+	ID          Bytes  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialDescriptor names an existing credential
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   Bytes  `json:"id"`
+}
+
+// RPEntity describes the relying party to the authenticator
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CredentialParameter is an acceptable credential key algorithm
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// AuthenticatorSelection states the requirements on the authenticator
+type AuthenticatorSelection struct {
+	ResidentKey      string           `json:"residentKey"`
+	UserVerification UserVerification `json:"userVerification"`
+}
+
+// CreationOptions are the publicKey options for navigator.credentials.create
+type CreationOptions struct {
+	RP                     RPEntity               `json:"rp"`
+	User                   User                   `json:"user"`
+	Challenge              Bytes                  `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the publicKey options for navigator.credentials.get.
+// Without AllowCredentials the browser offers the discoverable credentials
+// (passkeys) it holds for the RP ID.
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification UserVerification       `json:"userVerification"`
+}
+
+// RegistrationResponse is the JSON form of the credential returned by
+// navigator.credentials.create
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AttestationObject Bytes `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the credential returned by
+// navigator.credentials.get
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AuthenticatorData Bytes `json:"authenticatorData"`
+		Signature         Bytes `json:"signature"`
+		UserHandle        Bytes `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// Credential is a verified credential to store for later assertions
+type Credential struct {
+	ID                []byte
+	PublicKey         []byte
+	SignCount         uint32
+	AAGUID            []byte
+	AttestationFormat string
+	UserVerified      bool
+	BackupEligible    bool
+}
+
+// clientData is the collected client data signed over by the authenticator
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// CreationOptions returns the options for registering a credential for
+// user. Credentials in exclude are already registered and will not be
+// created again on the same authenticator.
+func (rp *RelyingParty) CreationOptions(user User, challenge []byte, exclude [][]byte, uv UserVerification) *CreationOptions {
+	params := make([]CredentialParameter, len(SupportedAlgorithms))
+	for i, alg := range SupportedAlgorithms {
+		params[i] = CredentialParameter{Type: credentialType, Alg: alg}
+	}
+	return &CreationOptions{
+		RP:                 RPEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: uv,
+		},
+		// Browsers then strip identifying attestation, leaving none or
+		// packed self attestation for most authenticators
+		Attestation: AttestationNone,
+	}
+}
+
+// RequestOptions returns the options for an assertion with one of allow,
+// or any discoverable credential when allow is empty
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow [][]byte, uv UserVerification) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: uv,
+	}
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		list[i] = CredentialDescriptor{Type: credentialType, ID: id}
+	}
+	return list
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// it was created for and returns the new credential. With requireUV the
+// authenticator must have verified the user.
+func (rp *RelyingParty) VerifyRegistration(resp *RegistrationResponse, challenge []byte, requireUV bool) (*Credential, error) {
+	if resp == nil || resp.Type != credentialType {
+		return nil, fmt.Errorf("%w: not a public key credential", ErrInvalidResponse)
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, clientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	obj, err := parseAttestationObject(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := parseAuthenticatorData(obj.authData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(auth, requireUV); err != nil {
+		return nil, err
+	}
+	if auth.credentialID == nil {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidResponse)
+	}
+	if len(resp.RawID) > 0 && !bytes.Equal(resp.RawID, auth.credentialID) {
+		return nil, ErrCredentialMismatch
+	}
+
+	key, err := parsePublicKey(auth.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	if err := verifyAttestation(obj, auth, key, clientDataHash[:]); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:                bytes.Clone(auth.credentialID),
+		PublicKey:         bytes.Clone(auth.publicKey),
+		SignCount:         auth.signCount,
+		AAGUID:            bytes.Clone(auth.aaguid),
+		AttestationFormat: obj.format,
+		UserVerified:      auth.userVerified(),
+		BackupEligible:    auth.flags&flagBackupEligible != 0,
+	}, nil
+}
+
+// VerifyAssertion checks an assertion made with the stored credential
+// against the challenge it was requested with, and returns the new
+// signature counter to store. A counter that fails to increase means the
+// credential may have been cloned; authenticators that do not count
+// always report zero.
+func (rp *RelyingParty) VerifyAssertion(resp *AssertionResponse, challenge []byte, credential *Credential, requireUV bool) (uint32, error) {
+	if resp == nil || resp.Type != credentialType {
+		return 0, fmt.Errorf("%w: not a public key credential", ErrInvalidResponse)
+	}
+	if !bytes.Equal(resp.RawID, credential.ID) {
+		return 0, ErrCredentialMismatch
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, clientDataTypeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	auth, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.verifyAuthenticatorData(auth, requireUV); err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := slices.Concat(resp.Response.AuthenticatorData, clientDataHash[:])
+	if err := key.verify(signed, resp.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	if (auth.signCount != 0 || credential.SignCount != 0) && auth.signCount <= credential.SignCount {
+		return 0, ErrSignCountRegression
+	}
+	return auth.signCount, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	if len(challenge) < minChallengeSize {
+		return ErrChallengeMismatch
+	}
+
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: malformed client data", ErrInvalidResponse)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: client data is for %q", ErrInvalidResponse, data.Type)
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return fmt.Errorf("%w: %q", ErrOriginMismatch, data.Origin)
+	}
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(auth *authenticatorData, requireUV bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(auth.rpIDHash, rpIDHash[:]) != 1 {
+		return ErrRPIDMismatch
+	}
+	if !auth.userPresent() {
+		return ErrUserNotPresent
+	}
+	if requireUV && !auth.userVerified() {
+		return ErrUserNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn/webauthntest"
+)
+
+const origin = "https://pay.example.com"
+
+func newRP() *webauthn.RelyingParty {
+	return webauthn.NewRelyingParty("pay.example.com", "Example Pay", []string{origin}, time.Minute)
+}
+
+func newChallenge(t *testing.T) []byte {
+	t.Helper()
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge() unexpected error = %v", err)
+	}
+	return challenge
+}
+
+// register creates a credential with the authenticator and verifies it
+func register(t *testing.T, rp *webauthn.RelyingParty, a *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	challenge := newChallenge(t)
+	user := webauthn.User{ID: []byte("user-123"), Name: "john@example.com", DisplayName: "john"}
+	resp, err := a.Create(rp.CreationOptions(user, challenge, nil, webauthn.UserVerificationPreferred))
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	cred, err := rp.VerifyRegistration(resp, challenge, false)
+	if err != nil {
+		t.Fatalf("VerifyRegistration() unexpected error = %v", err)
+	}
+	return cred
+}
+
+func TestVerifyRegistration_AttestationFormats(t *testing.T) {
+	rp := newRP()
+
+	for _, format := range []string{webauthntest.AttestationNone, webauthntest.AttestationPackedSelf, webauthntest.AttestationPackedX5C} {
+		t.Run(format, func(t *testing.T) {
+			a := webauthntest.NewAuthenticator(origin)
+			a.Attestation = format
+
+			cred := register(t, rp, a)
+			if len(cred.ID) == 0 || len(cred.PublicKey) == 0 || !cred.UserVerified {
+				t.Errorf("VerifyRegistration() credential = %+v", cred)
+			}
+			if string(cred.AAGUID) != string(a.AAGUID[:]) {
+				t.Errorf("VerifyRegistration() AAGUID = %x, want %x", cred.AAGUID, a.AAGUID)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistration_Rejects(t *testing.T) {
+	rp := newRP()
+
+	tests := []struct {
+		name    string
+		prepare func(a *webauthntest.Authenticator, rp *webauthn.RelyingParty) (*webauthn.RelyingParty, []byte)
+		uv      bool
+		wantErr error
+	}{
+		{
+			name: "other challenge",
+			prepare: func(a *webauthntest.Authenticator, rp *webauthn.RelyingParty) (*webauthn.RelyingParty, []byte) {
+				return rp, []byte("a-different-challenge-value")
+			},
+			wantErr: webauthn.ErrChallengeMismatch,
+		},
+		{
+			name: "phishing origin",
+			prepare: func(a *webauthntest.Authenticator, rp *webauthn.RelyingParty) (*webauthn.RelyingParty, []byte) {
+				a.Origin = "https://pay.example.com.evil.test"
+				return rp, nil
+			},
+			wantErr: webauthn.ErrOriginMismatch,
+		},
+		{
+			name: "other relying party",
+			prepare: func(a *webauthntest.Authenticator, rp *webauthn.RelyingParty) (*webauthn.RelyingParty, []byte) {
+				return webauthn.NewRelyingParty("other.example.com", "Other", []string{origin}, time.Minute), nil
+			},
+			wantErr: webauthn.ErrRPIDMismatch,
+		},
+		{
+			name: "user not verified",
+			prepare: func(a *webauthntest.Authenticator, rp *webauthn.RelyingParty) (*webauthn.RelyingParty, []byte) {
+				a.UserVerified = false
+				return rp, nil
+			},
+			uv:      true,
+			wantErr: webauthn.ErrUserNotVerified,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := webauthntest.NewAuthenticator(origin)
+			challenge := newChallenge(t)
+			verifier, expected := tt.prepare(a, rp)
+			if expected == nil {
+				expected = challenge
+			}
+
+			resp, err := a.Create(rp.CreationOptions(webauthn.User{ID: []byte("user-123")}, challenge, nil, webauthn.UserVerificationPreferred))
+			if err != nil {
+				t.Fatalf("Create() unexpected error = %v", err)
+			}
+			if _, err := verifier.VerifyRegistration(resp, expected, tt.uv); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyRegistration() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistration_MalformedAttestation(t *testing.T) {
+	rp := newRP()
+	a := webauthntest.NewAuthenticator(origin)
+	challenge := newChallenge(t)
+
+	resp, _ := a.Create(rp.CreationOptions(webauthn.User{ID: []byte("user-123")}, challenge, nil, webauthn.UserVerificationPreferred))
+	attestation := resp.Response.AttestationObject
+
+	for name, corrupt := range map[string][]byte{
+		"truncated":        attestation[:len(attestation)-10],
+		"trailing data":    append(append([]byte{}, attestation...), 0x00),
+		"indefinite map":   append([]byte{0xbf}, attestation[1:]...),
+		"huge byte string": {0xa1, 0x63, 'f', 'm', 't', 0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	} {
+		resp.Response.AttestationObject = corrupt
+		if _, err := rp.VerifyRegistration(resp, challenge, false); !errors.Is(err, webauthn.ErrInvalidResponse) {
+			t.Errorf("VerifyRegistration(%s) error = %v, want %v", name, err, webauthn.ErrInvalidResponse)
+		}
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	rp := newRP()
+	a := webauthntest.NewAuthenticator(origin)
+	cred := register(t, rp, a)
+
+	challenge := newChallenge(t)
+	resp, err := a.Get(rp.RequestOptions(challenge, [][]byte{cred.ID}, webauthn.UserVerificationRequired))
+	if err != nil {
+		t.Fatalf("Get() unexpected error = %v", err)
+	}
+	if string(resp.Response.UserHandle) != "user-123" {
+		t.Errorf("Get() user handle = %q, want user-123", resp.Response.UserHandle)
+	}
+
+	signCount, err := rp.VerifyAssertion(resp, challenge, cred, true)
+	if err != nil {
+		t.Fatalf("VerifyAssertion() unexpected error = %v", err)
+	}
+	if signCount != 1 {
+		t.Errorf("VerifyAssertion() sign count = %d, want 1", signCount)
+	}
+
+	// Replaying the assertion, or a cloned authenticator, does not advance
+	// the counter
+	cred.SignCount = signCount
+	if _, err := rp.VerifyAssertion(resp, challenge, cred, true); !errors.Is(err, webauthn.ErrSignCountRegression) {
+		t.Errorf("VerifyAssertion() replay error = %v, want %v", err, webauthn.ErrSignCountRegression)
+	}
+}
+
+func TestVerifyAssertion_Rejects(t *testing.T) {
+	rp := newRP()
+	a := webauthntest.NewAuthenticator(origin)
+	cred := register(t, rp, a)
+	other := register(t, rp, webauthntest.NewAuthenticator(origin))
+
+	challenge := newChallenge(t)
+	resp, _ := a.Get(rp.RequestOptions(challenge, nil, webauthn.UserVerificationPreferred))
+
+	if _, err := rp.VerifyAssertion(resp, newChallenge(t), cred, false); !errors.Is(err, webauthn.ErrChallengeMismatch) {
+		t.Errorf("VerifyAssertion() with another challenge error = %v, want %v", err, webauthn.ErrChallengeMismatch)
+	}
+	if _, err := rp.VerifyAssertion(resp, challenge, other, false); !errors.Is(err, webauthn.ErrCredentialMismatch) {
+		t.Errorf("VerifyAssertion() for another credential error = %v, want %v", err, webauthn.ErrCredentialMismatch)
+	}
+
+	resp.Response.Signature[len(resp.Response.Signature)-1] ^= 0xff
+	if _, err := rp.VerifyAssertion(resp, challenge, cred, false); !errors.Is(err, webauthn.ErrInvalidSignature) {
+		t.Errorf("VerifyAssertion() with a tampered signature error = %v, want %v", err, webauthn.ErrInvalidSignature)
+	}
+}
+
+func TestVerifyAssertion_ZeroCounter(t *testing.T) {
+	rp := newRP()
+	a := webauthntest.NewAuthenticator(origin)
+	a.CountSignatures = false
+	cred := register(t, rp, a)
+
+	// Authenticators without a counter always report zero
+	for range 2 {
+		challenge := newChallenge(t)
+		resp, _ := a.Get(rp.RequestOptions(challenge, nil, webauthn.UserVerificationPreferred))
+		if _, err := rp.VerifyAssertion(resp, challenge, cred, false); err != nil {
+			t.Fatalf("VerifyAssertion() unexpected error = %v", err)
+		}
+	}
+}
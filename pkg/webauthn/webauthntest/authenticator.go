@@ -0,0 +1,260 @@
+// Package webauthntest provides a software authenticator that answers
+// WebAuthn ceremonies the way a browser and security key would, for tests.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/DiaaSaada/crypto-payment-gateway/pkg/webauthn"
+)
+
+// Attestation formats the authenticator can produce
+const (
+	AttestationNone = webauthn.AttestationNone
+	// AttestationPackedSelf signs the attestation with the credential key
+	AttestationPackedSelf = "packed-self"
+	// AttestationPackedX5C signs it with a generated attestation certificate
+	AttestationPackedX5C = "packed-x5c"
+)
+
+var ErrNoCredential = errors.New("webauthntest: no matching credential")
+
+// Authenticator is a software authenticator holding ES256 credentials.
+// Credentials are discoverable. The zero value is not usable; create one
+// with NewAuthenticator.
+type Authenticator struct {
+	// Origin is reported in the client data, as the browser would
+	Origin string
+	// Attestation is the format used by Create
+	Attestation string
+	// UserVerified sets the UV flag, as after a PIN or biometric check
+	UserVerified bool
+	// CountSignatures increments the signature counter on every assertion;
+	// otherwise it stays zero like on many passkey providers
+	CountSignatures bool
+	AAGUID          [16]byte
+
+	credentials []*credential
+}
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// NewAuthenticator creates an authenticator used from origin, with user
+// verification and signature counting enabled
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{
+		Origin:          origin,
+		Attestation:     AttestationNone,
+		UserVerified:    true,
+		CountSignatures: true,
+		AAGUID:          [16]byte{0xa1, 0xb2, 0xc3, 0xd4},
+	}
+}
+
+// Create answers navigator.credentials.create with a new credential
+func (a *Authenticator) Create(opts *webauthn.CreationOptions) (*webauthn.RegistrationResponse, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, rpID: opts.RP.ID, userHandle: opts.User.ID, key: key}
+
+	clientDataJSON := a.clientData("webauthn.create", opts.Challenge)
+	authData := a.authenticatorData(cred, true)
+
+	stmt, format, err := a.attest(cred, authData, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &webauthn.RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(id),
+		RawID: id,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AttestationObject = encodeCBOR(map[any]any{
+		"fmt":      format,
+		"attStmt":  stmt,
+		"authData": authData,
+	})
+
+	a.credentials = append(a.credentials, cred)
+	return resp, nil
+}
+
+// Get answers navigator.credentials.get with the first credential allowed
+// by opts, or the first discoverable one for the RP ID
+func (a *Authenticator) Get(opts *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	cred := a.find(opts)
+	if cred == nil {
+		return nil, ErrNoCredential
+	}
+	if a.CountSignatures {
+		cred.signCount++
+	}
+
+	clientDataJSON := a.clientData("webauthn.get", opts.Challenge)
+	authData := a.authenticatorData(cred, false)
+	sig, err := sign(cred.key, authData, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &webauthn.AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(cred.id),
+		RawID: cred.id,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AuthenticatorData = authData
+	resp.Response.Signature = sig
+	resp.Response.UserHandle = cred.userHandle
+	return resp, nil
+}
+
+func (a *Authenticator) find(opts *webauthn.RequestOptions) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpID != opts.RPID {
+			continue
+		}
+		if len(opts.AllowCredentials) == 0 {
+			return cred
+		}
+		for _, allowed := range opts.AllowCredentials {
+			if slices.Equal(allowed.ID, cred.id) {
+				return cred
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return data
+}
+
+func (a *Authenticator) authenticatorData(cred *credential, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+	flags := byte(0x01)
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	if attested {
+		flags |= 0x40
+	}
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, cred.signCount)
+	if !attested {
+		return data
+	}
+
+	data = append(data, a.AAGUID[:]...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(cred.id)))
+	data = append(data, cred.id...)
+	return append(data, coseKey(&cred.key.PublicKey)...)
+}
+
+func (a *Authenticator) attest(cred *credential, authData, clientDataJSON []byte) (map[any]any, string, error) {
+	switch a.Attestation {
+	case AttestationNone:
+		return map[any]any{}, webauthn.AttestationNone, nil
+	case AttestationPackedSelf:
+		sig, err := sign(cred.key, authData, clientDataJSON)
+		if err != nil {
+			return nil, "", err
+		}
+		return map[any]any{"alg": webauthn.AlgES256, "sig": sig}, webauthn.AttestationPacked, nil
+	case AttestationPackedX5C:
+		attestationKey, cert, err := a.attestationCertificate()
+		if err != nil {
+			return nil, "", err
+		}
+		sig, err := sign(attestationKey, authData, clientDataJSON)
+		if err != nil {
+			return nil, "", err
+		}
+		return map[any]any{"alg": webauthn.AlgES256, "sig": sig, "x5c": []any{cert}}, webauthn.AttestationPacked, nil
+	default:
+		return nil, "", errors.New("webauthntest: unknown attestation format")
+	}
+}
+
+// attestationCertificate generates a self-signed batch certificate meeting
+// the packed attestation requirements
+func (a *Authenticator) attestationCertificate() (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	aaguid, err := asn1.Marshal(a.AAGUID[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"US"},
+			Organization:       []string{"webauthntest"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "webauthntest batch",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions: []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}, Value: aaguid},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	return key, der, err
+}
+
+func sign(key *ecdsa.PrivateKey, authData, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(slices.Concat(authData, clientDataHash[:]))
+	return ecdsa.SignASN1(rand.Reader, key, digest[:])
+}
+
+func coseKey(pub *ecdsa.PublicKey) []byte {
+	x := pub.X.FillBytes(make([]byte, 32))
+	y := pub.Y.FillBytes(make([]byte, 32))
+	return encodeCBOR(map[any]any{
+		1:  2,                 // kty: EC2
+		3:  webauthn.AlgES256, // alg
+		-1: 1,                 // crv: P-256
+		-2: x,
+		-3: y,
+	})
+}
@@ -0,0 +1,81 @@
+package webauthntest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// encodeCBOR encodes the subset of CBOR produced by authenticators:
+// integers, byte and text strings, arrays and maps. Map keys are sorted
+// in CTAP2 canonical order.
+func encodeCBOR(v any) []byte {
+	var buf bytes.Buffer
+	writeCBOR(&buf, v)
+	return buf.Bytes()
+}
+
+func writeHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= 0xff:
+		buf.Write([]byte{major<<5 | 24, byte(arg)})
+	case arg <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	case arg <= 0xffffffff:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, arg))
+	}
+}
+
+func writeCBOR(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case int:
+		writeCBOR(buf, int64(v))
+	case int64:
+		if v >= 0 {
+			writeHead(buf, 0, uint64(v))
+		} else {
+			writeHead(buf, 1, uint64(-1-v))
+		}
+	case []byte:
+		writeHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		writeHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			writeCBOR(buf, item)
+		}
+	case map[any]any:
+		// Canonical order: shorter encoded keys first, then bytewise
+		keys := make([][]byte, 0, len(v))
+		values := make(map[string]any, len(v))
+		for k, item := range v {
+			encoded := encodeCBOR(k)
+			keys = append(keys, encoded)
+			values[string(encoded)] = item
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return bytes.Compare(keys[i], keys[j]) < 0
+		})
+		writeHead(buf, 5, uint64(len(v)))
+		for _, k := range keys {
+			buf.Write(k)
+			writeCBOR(buf, values[string(k)])
+		}
+	default:
+		panic(fmt.Sprintf("webauthntest: cannot encode %T as CBOR", v))
+	}
+}